@@ -0,0 +1,225 @@
+package accounting
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/stat"
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// DAY is the length of a snapshot in miliseconds
+	DAY uint64 = 24 * 60 * 60 * 1000
+	// SNAPSHOT_INTERVAL is the time between two checks for a day to
+	// snapshot, it must be shorter than auth data is kept
+	SNAPSHOT_INTERVAL time.Duration = 10 * time.Minute
+	// BACKFILL_DAYS is the number of days searched back for the last
+	// stored snapshot, missing days after it are snapshot and incomplete
+	// snapshots among them are completed
+	BACKFILL_DAYS uint64 = 30
+)
+
+// Storage is the data storage accounting reads balances, prices and
+// exchange fills from and stores its daily snapshots to.
+type Storage interface {
+	CurrentAuthDataVersion(timepoint uint64) (common.Version, error)
+	GetAuthData(version common.Version) (common.AuthDataSnapshot, error)
+	CurrentPriceVersion(timepoint uint64) (common.Version, error)
+	GetAllPrices(version common.Version) (common.AllPriceEntry, error)
+	GetTradeHistory(fromTime, toTime uint64, exchangeID common.ExchangeID, pairID common.TokenPairID) (common.AllTradeHistory, error)
+
+	StoreAccountingSnapshot(snapshot Snapshot) error
+	GetAccountingSnapshots(fromTime, toTime uint64) ([]Snapshot, error)
+}
+
+// StatStorage is the stat storage accounting reads trades of the reserve
+// from.
+type StatStorage interface {
+	GetTradeLogs(fromTime uint64, toTime uint64) ([]common.TradeLog, error)
+}
+
+type Accounting struct {
+	storage     Storage
+	statStorage StatStorage
+	ethRate     stat.EthUSDRate
+	reserve     ethereum.Address
+	ticker      *time.Ticker
+}
+
+func NewAccounting(storage Storage, statStorage StatStorage, ethRate stat.EthUSDRate, reserve ethereum.Address) *Accounting {
+	return &Accounting{
+		storage:     storage,
+		statStorage: statStorage,
+		ethRate:     ethRate,
+		reserve:     reserve,
+	}
+}
+
+func dayOf(timepoint uint64) uint64 {
+	return timepoint - timepoint%DAY
+}
+
+// value sets rates and values of inventory and their totals
+func (self *Accounting) value(snapshot *Snapshot, rates map[string]float64) {
+	for tokenID, tokenInventory := range snapshot.Inventory {
+		tokenInventory.Rate = rates[tokenID]
+		tokenInventory.ETHValue = tokenInventory.Total * tokenInventory.Rate
+		tokenInventory.USDValue = tokenInventory.ETHValue * snapshot.ETHUSDRate
+		snapshot.Inventory[tokenID] = tokenInventory
+		snapshot.TotalETH += tokenInventory.ETHValue
+		snapshot.TotalUSD += tokenInventory.USDValue
+	}
+}
+
+// pnl sets realized PnL of the day of the snapshot and its totals. It is
+// incomplete if no trade log after the day is stored.
+func (self *Accounting) pnl(snapshot *Snapshot) error {
+	day := snapshot.Timestamp
+	end := day + DAY - 1
+	history, err := self.storage.GetTradeHistory(day, end, "", "")
+	if err != nil {
+		return err
+	}
+	logs, err := self.statStorage.GetTradeLogs(day, end)
+	if err != nil {
+		return err
+	}
+	after, err := self.statStorage.GetTradeLogs(end+1, end+DAY)
+	if err != nil {
+		return err
+	}
+	snapshot.PnL = ComputePnL(history, logs, self.reserve)
+	snapshot.Incomplete = len(after) == 0
+	snapshot.RealizedPnLETH, snapshot.RealizedPnLUSD = 0, 0
+	for tokenID, tokenPnL := range snapshot.PnL {
+		tokenPnL.RealizedUSD = tokenPnL.RealizedETH * snapshot.ETHUSDRate
+		snapshot.PnL[tokenID] = tokenPnL
+		snapshot.RealizedPnLETH += tokenPnL.RealizedETH
+		snapshot.RealizedPnLUSD += tokenPnL.RealizedUSD
+	}
+	return nil
+}
+
+// inventory returns the not valued inventory of the latest auth data
+// before timepoint and rates of the latest prices before it.
+func (self *Accounting) inventory(timepoint uint64) (Snapshot, map[string]float64, error) {
+	snapshot := Snapshot{Timestamp: timepoint, PnL: map[string]TokenPnL{}}
+	rates := map[string]float64{"ETH": 1}
+	version, err := self.storage.CurrentAuthDataVersion(timepoint)
+	if err != nil {
+		return snapshot, rates, err
+	}
+	authData, err := self.storage.GetAuthData(version)
+	if err != nil {
+		return snapshot, rates, err
+	}
+	snapshot.InventoryTimestamp = uint64(version)
+	snapshot.Inventory = ComputeInventory(authData)
+	if version, err := self.storage.CurrentPriceVersion(timepoint); err == nil {
+		if prices, err := self.storage.GetAllPrices(version); err == nil {
+			rates = GetRates(prices)
+		}
+	}
+	snapshot.ETHUSDRate = self.ethRate.GetUSDRate(timepoint)
+	return snapshot, rates, nil
+}
+
+// GetInventory returns inventory of the latest auth data before timepoint,
+// valued with the latest prices before it. It has no PnL.
+func (self *Accounting) GetInventory(timepoint uint64) (Snapshot, error) {
+	snapshot, rates, err := self.inventory(timepoint)
+	if err != nil {
+		return snapshot, err
+	}
+	self.value(&snapshot, rates)
+	return snapshot, nil
+}
+
+// ComputeSnapshot returns the snapshot of the day starting at day, the day
+// must be over and its last auth data must still be stored.
+func (self *Accounting) ComputeSnapshot(day uint64) (Snapshot, error) {
+	end := day + DAY - 1
+	snapshot, rates, err := self.inventory(end)
+	if err != nil {
+		return snapshot, err
+	}
+	if snapshot.InventoryTimestamp < day {
+		return snapshot, errors.New(fmt.Sprintf("There is no auth data in day %d", day))
+	}
+	snapshot.Timestamp = day
+	self.value(&snapshot, rates)
+	err = self.pnl(&snapshot)
+	return snapshot, err
+}
+
+// GetSnapshots returns daily snapshots of days starting between fromTime
+// and toTime.
+func (self *Accounting) GetSnapshots(fromTime, toTime uint64) ([]Snapshot, error) {
+	return self.storage.GetAccountingSnapshots(fromTime, toTime)
+}
+
+// snapshotMissingDays stores snapshots of the days after the last stored
+// one until the day before timepoint and completes PnL of incomplete
+// snapshots among them.
+func (self *Accounting) snapshotMissingDays(timepoint uint64) {
+	lastDay := dayOf(timepoint) - DAY
+	stored, err := self.storage.GetAccountingSnapshots(lastDay-(BACKFILL_DAYS-1)*DAY, lastDay)
+	if err != nil {
+		log.Printf("Accounting: getting snapshots before %d failed: %s", lastDay, err)
+		return
+	}
+	for _, snapshot := range stored {
+		if !snapshot.Incomplete {
+			continue
+		}
+		if err = self.pnl(&snapshot); err != nil {
+			log.Printf("Accounting: computing PnL of %d failed: %s", snapshot.Timestamp, err)
+			continue
+		}
+		if snapshot.Incomplete {
+			continue
+		}
+		if err = self.storage.StoreAccountingSnapshot(snapshot); err != nil {
+			log.Printf("Accounting: storing snapshot of %d failed: %s", snapshot.Timestamp, err)
+			continue
+		}
+		log.Printf("Accounting: completed snapshot of %d, realized PnL %f ETH", snapshot.Timestamp, snapshot.RealizedPnLETH)
+	}
+	day := lastDay
+	if len(stored) > 0 {
+		day = stored[len(stored)-1].Timestamp + DAY
+	}
+	for ; day <= lastDay; day += DAY {
+		snapshot, err := self.ComputeSnapshot(day)
+		if err != nil {
+			log.Printf("Accounting: computing snapshot of %d failed: %s", day, err)
+			continue
+		}
+		if err = self.storage.StoreAccountingSnapshot(snapshot); err != nil {
+			log.Printf("Accounting: storing snapshot of %d failed: %s", day, err)
+			continue
+		}
+		log.Printf("Accounting: stored snapshot of %d, inventory %f ETH, realized PnL %f ETH, incomplete: %t", day, snapshot.TotalETH, snapshot.RealizedPnLETH, snapshot.Incomplete)
+	}
+}
+
+func (self *Accounting) Run() error {
+	self.ticker = time.NewTicker(SNAPSHOT_INTERVAL)
+	go func() {
+		for t := range self.ticker.C {
+			self.snapshotMissingDays(common.TimeToTimepoint(t))
+		}
+	}()
+	return nil
+}
+
+func (self *Accounting) Stop() error {
+	if self.ticker != nil {
+		self.ticker.Stop()
+	}
+	return nil
+}
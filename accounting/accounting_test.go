@@ -0,0 +1,181 @@
+package accounting
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+func setupTokens() (common.Token, common.Token) {
+	eth := common.Token{ID: "ETH", Address: "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", Decimal: 18}
+	omg := common.Token{ID: "OMG", Address: "0x1111111111111111111111111111111111111111", Decimal: 18}
+	common.SupportedTokens = map[string]common.Token{"ETH": eth, "OMG": omg}
+	return eth, omg
+}
+
+func TestComputeInventory(t *testing.T) {
+	setupTokens()
+	reserveBalance := common.RawBalance(*big.NewInt(0).Mul(big.NewInt(5), big.NewInt(1000000000000000000)))
+	snapshot := common.AuthDataSnapshot{
+		ReserveBalances: map[string]common.BalanceEntry{
+			"OMG": {Valid: true, Balance: reserveBalance},
+		},
+		ExchangeBalances: map[common.ExchangeID]common.EBalanceEntry{
+			"binance": {
+				Valid:            true,
+				AvailableBalance: map[string]float64{"OMG": 10},
+				LockedBalance:    map[string]float64{"OMG": 2},
+				DepositBalance:   map[string]float64{"OMG": 3},
+			},
+		},
+		PendingActivities: []common.ActivityRecord{
+			// mined deposit not credited yet is in flight
+			{Action: "deposit", Params: map[string]interface{}{"token": "OMG", "amount": "3"}, MiningStatus: "mined"},
+			// deposit not mined yet is still in the reserve balance
+			{Action: "deposit", Params: map[string]interface{}{"token": "OMG", "amount": "100"}, MiningStatus: "submitted"},
+			{Action: "withdraw", Params: map[string]interface{}{"token": "OMG", "amount": "4"}, ExchangeStatus: "done"},
+		},
+	}
+	omg := ComputeInventory(snapshot)["OMG"]
+	if omg.Reserve != 5 || omg.Exchanges["binance"] != 12 || omg.InFlightDeposits != 3 || omg.InFlightWithdrawals != 4 || omg.Total != 24 {
+		t.Fatalf("Unexpected inventory %+v", omg)
+	}
+}
+
+func TestComputePnL(t *testing.T) {
+	eth, omg := setupTokens()
+	reserve := ethereum.HexToAddress("0x2222222222222222222222222222222222222222")
+	history := common.AllTradeHistory{
+		Data: map[common.ExchangeID]common.ExchangeTradeHistory{
+			"binance": {
+				"OMG-ETH": {
+					{ID: "1", Price: 0.01, Qty: 10, Type: "buy"},
+					{ID: "2", Price: 0.02, Qty: 10, Type: "buy"},
+				},
+			},
+		},
+	}
+	ether := big.NewInt(1000000000000000000)
+	logs := []common.TradeLog{
+		// user buys 10 OMG with 0.2 ETH, the reserve sells them
+		{
+			ReserveAddress: reserve,
+			SrcAddress:     ethereum.HexToAddress(eth.Address),
+			DestAddress:    ethereum.HexToAddress(omg.Address),
+			SrcAmount:      big.NewInt(0).Div(ether, big.NewInt(5)),
+			DestAmount:     big.NewInt(0).Mul(ether, big.NewInt(10)),
+		},
+		// trades of other reserves are not ours
+		{
+			ReserveAddress: ethereum.HexToAddress("0x3333333333333333333333333333333333333333"),
+			SrcAddress:     ethereum.HexToAddress(eth.Address),
+			DestAddress:    ethereum.HexToAddress(omg.Address),
+			SrcAmount:      ether,
+			DestAmount:     ether,
+		},
+	}
+	pnl := ComputePnL(history, logs, reserve)["OMG"]
+	// 10 of 20 bought at 0.015 are sold at 0.02
+	if pnl.BuyQty != 20 || pnl.SellQty != 10 || pnl.SellPrice != 0.02 {
+		t.Fatalf("Unexpected trades %+v", pnl)
+	}
+	if diff := pnl.RealizedETH - 0.05; diff > 1e-9 || diff < -1e-9 {
+		t.Fatalf("Expected realized PnL 0.05 ETH, got %f", pnl.RealizedETH)
+	}
+}
+
+type testStorage struct {
+	logs      []common.TradeLog
+	snapshots map[uint64]Snapshot
+}
+
+func (self *testStorage) CurrentAuthDataVersion(timepoint uint64) (common.Version, error) {
+	return common.Version(timepoint), nil
+}
+
+func (self *testStorage) GetAuthData(version common.Version) (common.AuthDataSnapshot, error) {
+	return common.AuthDataSnapshot{}, nil
+}
+
+func (self *testStorage) CurrentPriceVersion(timepoint uint64) (common.Version, error) {
+	return 0, errors.New("There is no price")
+}
+
+func (self *testStorage) GetAllPrices(version common.Version) (common.AllPriceEntry, error) {
+	return common.AllPriceEntry{}, nil
+}
+
+func (self *testStorage) GetTradeHistory(fromTime, toTime uint64, exchangeID common.ExchangeID, pairID common.TokenPairID) (common.AllTradeHistory, error) {
+	return common.AllTradeHistory{}, nil
+}
+
+func (self *testStorage) StoreAccountingSnapshot(snapshot Snapshot) error {
+	self.snapshots[snapshot.Timestamp] = snapshot
+	return nil
+}
+
+func (self *testStorage) GetAccountingSnapshots(fromTime, toTime uint64) ([]Snapshot, error) {
+	result := []Snapshot{}
+	for day := fromTime - fromTime%DAY; day <= toTime; day += DAY {
+		if snapshot, found := self.snapshots[day]; found && day >= fromTime {
+			result = append(result, snapshot)
+		}
+	}
+	return result, nil
+}
+
+func (self *testStorage) GetTradeLogs(fromTime uint64, toTime uint64) ([]common.TradeLog, error) {
+	result := []common.TradeLog{}
+	for _, l := range self.logs {
+		if l.Timestamp >= fromTime && l.Timestamp <= toTime {
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
+
+type testETHRate struct{}
+
+func (self testETHRate) GetUSDRate(timepoint uint64) float64 {
+	return 1000
+}
+
+func TestSnapshotMissingDays(t *testing.T) {
+	eth, omg := setupTokens()
+	reserve := ethereum.HexToAddress("0x2222222222222222222222222222222222222222")
+	day := 100 * DAY
+	storage := &testStorage{snapshots: map[uint64]Snapshot{day: {Timestamp: day}}}
+	// the reserve sells 10 OMG on the third day, no trade log after it is
+	// fetched yet
+	ether := big.NewInt(1000000000000000000)
+	storage.logs = []common.TradeLog{{
+		Timestamp:      day + 2*DAY + 1000,
+		ReserveAddress: reserve,
+		SrcAddress:     ethereum.HexToAddress(eth.Address),
+		DestAddress:    ethereum.HexToAddress(omg.Address),
+		SrcAmount:      big.NewInt(0).Div(ether, big.NewInt(5)),
+		DestAmount:     big.NewInt(0).Mul(ether, big.NewInt(10)),
+	}}
+	accounting := NewAccounting(storage, storage, testETHRate{}, reserve)
+
+	// both days after the last stored snapshot are snapshot
+	accounting.snapshotMissingDays(day + 3*DAY + 1000)
+	second, found := storage.snapshots[day+DAY]
+	if !found || second.Incomplete {
+		t.Fatalf("Expected complete snapshot of the second day, got %+v", storage.snapshots)
+	}
+	third, found := storage.snapshots[day+2*DAY]
+	if !found || !third.Incomplete || third.PnL["OMG"].SellQty != 10 {
+		t.Fatalf("Expected incomplete snapshot of the third day, got %+v", storage.snapshots)
+	}
+
+	// the third day is completed once a trade log after it is fetched
+	storage.logs = append(storage.logs, common.TradeLog{Timestamp: day + 3*DAY + 2000})
+	accounting.snapshotMissingDays(day + 3*DAY + 3000)
+	if third = storage.snapshots[day+2*DAY]; third.Incomplete || third.PnL["OMG"].SellQty != 10 || len(storage.snapshots) != 3 {
+		t.Fatalf("Expected completed snapshot of the third day, got %+v", storage.snapshots)
+	}
+}
@@ -0,0 +1,118 @@
+package accounting
+
+import (
+	"strconv"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+func activityAmount(activity common.ActivityRecord) float64 {
+	switch amount := activity.Params["amount"].(type) {
+	case string:
+		result, _ := strconv.ParseFloat(amount, 64)
+		return result
+	case float64:
+		return amount
+	}
+	return 0
+}
+
+// activityToken returns token id of a deposit or a withdraw, the token is
+// stored by its id.
+func activityToken(activity common.ActivityRecord) string {
+	switch token := activity.Params["token"].(type) {
+	case string:
+		return token
+	case common.Token:
+		return token.ID
+	}
+	return ""
+}
+
+func getInventory(inventory map[string]TokenInventory, tokenID string) TokenInventory {
+	result, found := inventory[tokenID]
+	if !found {
+		result = TokenInventory{Exchanges: map[common.ExchangeID]float64{}}
+	}
+	return result
+}
+
+// ComputeInventory totals balances of the reserve and exchanges of an auth
+// data snapshot with funds moving between them. A deposit is in flight
+// once its transaction is mined until the exchange credits it, a withdraw
+// is in flight from its request until its transaction is mined.
+func ComputeInventory(snapshot common.AuthDataSnapshot) map[string]TokenInventory {
+	inventory := map[string]TokenInventory{}
+	for tokenID, balance := range snapshot.ReserveBalances {
+		token, err := common.GetToken(tokenID)
+		if err != nil || !balance.Valid {
+			continue
+		}
+		tokenInventory := getInventory(inventory, tokenID)
+		tokenInventory.Reserve = balance.Balance.ToFloat(token.Decimal)
+		inventory[tokenID] = tokenInventory
+	}
+	for exchangeID, balances := range snapshot.ExchangeBalances {
+		if !balances.Valid {
+			continue
+		}
+		for tokenID, available := range balances.AvailableBalance {
+			tokenInventory := getInventory(inventory, tokenID)
+			tokenInventory.Exchanges[exchangeID] = available + balances.LockedBalance[tokenID]
+			inventory[tokenID] = tokenInventory
+		}
+	}
+	for _, activity := range snapshot.PendingActivities {
+		tokenID := activityToken(activity)
+		if tokenID == "" {
+			continue
+		}
+		switch activity.Action {
+		case "deposit":
+			if activity.MiningStatus != string(common.ActivityStateMined) ||
+				activity.ExchangeStatus == string(common.ActivityStateDone) ||
+				activity.ExchangeStatus == string(common.ActivityStateFailed) {
+				continue
+			}
+			tokenInventory := getInventory(inventory, tokenID)
+			tokenInventory.InFlightDeposits += activityAmount(activity)
+			inventory[tokenID] = tokenInventory
+		case "withdraw":
+			if activity.ExchangeStatus == string(common.ActivityStateFailed) ||
+				activity.MiningStatus == string(common.ActivityStateMined) ||
+				activity.MiningStatus == string(common.ActivityStateFailed) {
+				continue
+			}
+			tokenInventory := getInventory(inventory, tokenID)
+			tokenInventory.InFlightWithdrawals += activityAmount(activity)
+			inventory[tokenID] = tokenInventory
+		}
+	}
+	for tokenID, tokenInventory := range inventory {
+		tokenInventory.Total = tokenInventory.Reserve + tokenInventory.InFlightDeposits + tokenInventory.InFlightWithdrawals
+		for _, balance := range tokenInventory.Exchanges {
+			tokenInventory.Total += balance
+		}
+		inventory[tokenID] = tokenInventory
+	}
+	return inventory
+}
+
+// GetRates returns the value in ETH of one token from mid of its books
+// with ETH consolidated across exchanges.
+func GetRates(prices common.AllPriceEntry) map[string]float64 {
+	rates := map[string]float64{"ETH": 1}
+	for _, token := range common.GetSupportedTokens() {
+		if token.IsETH() {
+			continue
+		}
+		price, found := prices.Data[common.NewTokenPairID(token.ID, "ETH")]
+		if !found {
+			continue
+		}
+		if top, found := common.GetBookTops(price)[common.CONSOLIDATED_EXCHANGE]; found {
+			rates[token.ID] = (top.Bid + top.Ask) / 2
+		}
+	}
+	return rates
+}
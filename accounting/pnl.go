@@ -0,0 +1,97 @@
+package accounting
+
+import (
+	"strings"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+// trades accumulates buys and sells of one token
+type trades struct {
+	buyQty, buyCost, sellQty, sellCost float64
+}
+
+func (self *trades) add(tradeType string, qty, price float64) {
+	if tradeType == "buy" {
+		self.buyQty += qty
+		self.buyCost += qty * price
+	} else {
+		self.sellQty += qty
+		self.sellCost += qty * price
+	}
+}
+
+func (self trades) pnl() TokenPnL {
+	result := TokenPnL{BuyQty: self.buyQty, SellQty: self.sellQty}
+	if self.buyQty > 0 {
+		result.BuyPrice = self.buyCost / self.buyQty
+	}
+	if self.sellQty > 0 {
+		result.SellPrice = self.sellCost / self.sellQty
+	}
+	matched := self.buyQty
+	if self.sellQty < matched {
+		matched = self.sellQty
+	}
+	result.RealizedETH = matched * (result.SellPrice - result.BuyPrice)
+	return result
+}
+
+func getSupportedToken(address ethereum.Address) (common.Token, bool) {
+	for _, token := range common.GetSupportedTokens() {
+		if strings.ToLower(token.Address) == strings.ToLower(address.Hex()) {
+			return token, true
+		}
+	}
+	return common.Token{}, false
+}
+
+// ComputePnL returns realized PnL in ETH of each token traded against ETH
+// by exchange fills of history and by trades of the reserve in logs.
+func ComputePnL(history common.AllTradeHistory, logs []common.TradeLog, reserve ethereum.Address) map[string]TokenPnL {
+	tokenTrades := map[string]*trades{}
+	add := func(tokenID, tradeType string, qty, price float64) {
+		if _, found := tokenTrades[tokenID]; !found {
+			tokenTrades[tokenID] = &trades{}
+		}
+		tokenTrades[tokenID].add(tradeType, qty, price)
+	}
+	for _, exchangeHistory := range history.Data {
+		for pairID, fills := range exchangeHistory {
+			// pairs quoted in other tokens are not valued in ETH
+			parts := strings.Split(string(pairID), "-")
+			if len(parts) != 2 || parts[1] != "ETH" {
+				continue
+			}
+			for _, fill := range fills {
+				add(parts[0], fill.Type, fill.Qty, fill.Price)
+			}
+		}
+	}
+	for _, l := range logs {
+		if l.ReserveAddress != reserve || l.SrcAmount == nil || l.DestAmount == nil {
+			continue
+		}
+		src, srcFound := getSupportedToken(l.SrcAddress)
+		dest, destFound := getSupportedToken(l.DestAddress)
+		if !srcFound || !destFound {
+			continue
+		}
+		srcAmount := common.BigToFloat(l.SrcAmount, src.Decimal)
+		destAmount := common.BigToFloat(l.DestAmount, dest.Decimal)
+		// the reserve receives source token and pays destination token,
+		// token to token trades are not valued
+		switch {
+		case src.IsETH() && !dest.IsETH() && destAmount > 0:
+			add(dest.ID, "sell", destAmount, srcAmount/destAmount)
+		case dest.IsETH() && !src.IsETH() && srcAmount > 0:
+			add(src.ID, "buy", srcAmount, destAmount/srcAmount)
+		}
+	}
+	result := map[string]TokenPnL{}
+	for tokenID, t := range tokenTrades {
+		result[tokenID] = t.pnl()
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package accounting
+
+import (
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// TokenInventory is the balance of one token in every place it can be,
+// in token unit. Exchanges are available plus locked balances, deposit
+// balances of exchanges are not counted as they are the same funds as
+// InFlightDeposits. Rate is the value of one token in ETH.
+type TokenInventory struct {
+	Reserve             float64
+	Exchanges           map[common.ExchangeID]float64
+	InFlightDeposits    float64
+	InFlightWithdrawals float64
+	Total               float64
+	Rate                float64
+	ETHValue            float64
+	USDValue            float64
+}
+
+// TokenPnL is realized PnL of one token against ETH over a period. Buys
+// and sells are exchange fills and reserve trades, prices are volume
+// weighted in ETH per token. Realized PnL is made by the quantity both
+// bought and sold during the period, inventory carried over periods is
+// not realized.
+type TokenPnL struct {
+	BuyQty      float64
+	BuyPrice    float64
+	SellQty     float64
+	SellPrice   float64
+	RealizedETH float64
+	RealizedUSD float64
+}
+
+// Snapshot is inventory and PnL of every token. Daily snapshots have
+// Timestamp at the start of their day (UTC, miliseconds), inventory is
+// taken from the last auth data of the day and PnL is realized during
+// the day. InventoryTimestamp is the timestamp of the auth data. PnL is
+// Incomplete while no trade log after the day is stored as trade logs of
+// the day may not all be fetched yet, it is recomputed once they are.
+type Snapshot struct {
+	Timestamp          uint64
+	InventoryTimestamp uint64
+	Incomplete         bool
+	ETHUSDRate         float64
+	Inventory          map[string]TokenInventory
+	PnL                map[string]TokenPnL
+	TotalETH           float64
+	TotalUSD           float64
+	RealizedPnLETH     float64
+	RealizedPnLUSD     float64
+}
@@ -9,6 +9,7 @@ import (
 	"math/big"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/KyberNetwork/reserve-data/common"
@@ -21,6 +22,15 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// VOLATILITY_WINDOW is the time, in miliseconds, rate history is read
+// over to plan compact rates
+const VOLATILITY_WINDOW uint64 = 60 * 60 * 1000
+
+// RateHistory is the storage of rates fetched from the pricing contract.
+type RateHistory interface {
+	GetRates(fromTime, toTime uint64) ([]common.AllRateEntry, error)
+}
+
 type tbindex struct {
 	BulkIndex   uint64
 	IndexInBulk uint64
@@ -54,12 +64,17 @@ type Blockchain struct {
 	oldBurners    []ethereum.Address
 	signer        Signer
 	depositSigner Signer
+	alerterSigner Signer
+	mu            sync.RWMutex
 	tokens        []common.Token
 	tokenIndices  map[string]tbindex
 	nonce         NonceCorpus
 	nonceDeposit  NonceCorpus
+	nonceAlerter  NonceCorpus
 	broadcaster   *Broadcaster
 	chainType     string
+	rateTolerance float64
+	rateHistory   RateHistory
 }
 
 func (self *Blockchain) AddOldNetwork(addr ethereum.Address) {
@@ -70,17 +85,52 @@ func (self *Blockchain) AddOldBurners(addr ethereum.Address) {
 	self.oldBurners = append(self.oldBurners, addr)
 }
 
+// SetAlerterSigner sets the key trading of the reserve and of its tokens
+// is disabled or enabled with, it must be an alerter of the contracts to
+// disable and their admin to enable.
+func (self *Blockchain) SetAlerterSigner(signer Signer, nonce NonceCorpus) {
+	self.alerterSigner = signer
+	self.nonceAlerter = nonce
+}
+
+// SetRateTolerance makes SetRates skip tokens whose buy and sell rates
+// change by at most tolerance, relatively to their rates on chain. It is
+// disabled when tolerance is 0.
+func (self *Blockchain) SetRateTolerance(tolerance float64) {
+	self.rateTolerance = tolerance
+}
+
+// SetRateHistory sets the rates compact rates are planned from, tokens
+// are only rebased before they overflow by a minimal margin without it.
+func (self *Blockchain) SetRateHistory(history RateHistory) {
+	self.rateHistory = history
+}
+
 func (self *Blockchain) AddToken(t common.Token) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
 	self.tokens = append(self.tokens, t)
 }
 
+func (self *Blockchain) getTokens() []common.Token {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.tokens
+}
+
+func (self *Blockchain) getTokenIndices() map[string]tbindex {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.tokenIndices
+}
+
 func (self *Blockchain) GetAddresses() *common.Addresses {
 	exs := map[common.ExchangeID]common.TokenAddresses{}
 	for _, ex := range common.SupportedExchanges {
 		exs[ex.ID()] = ex.TokenAddresses()
 	}
 	tokens := map[string]common.TokenInfo{}
-	for _, t := range self.tokens {
+	for _, t := range self.getTokens() {
 		tokens[t.ID] = common.TokenInfo{
 			Address:  ethereum.HexToAddress(t.Address),
 			Decimals: t.Decimal,
@@ -99,36 +149,70 @@ func (self *Blockchain) GetAddresses() *common.Addresses {
 	}
 }
 
-func (self *Blockchain) LoadAndSetTokenIndices() error {
-	tokens := []ethereum.Address{}
-	self.tokenIndices = map[string]tbindex{}
-
-	for _, tok := range self.tokens {
+// loadTokenIndices returns indices of tokens in compact data of the
+// pricing contract.
+func (self *Blockchain) loadTokenIndices(tokens []common.Token) (map[string]tbindex, error) {
+	result := map[string]tbindex{}
+	addresses := []ethereum.Address{}
+	for _, tok := range tokens {
 		if tok.ID != "ETH" {
-			tokens = append(tokens, ethereum.HexToAddress(tok.Address))
+			addresses = append(addresses, ethereum.HexToAddress(tok.Address))
 		} else {
 			// this is not really needed. Just a safe guard
-			self.tokenIndices[ethereum.HexToAddress(tok.Address).Hex()] = tbindex{1000000, 1000000}
+			result[ethereum.HexToAddress(tok.Address).Hex()] = tbindex{1000000, 1000000}
 		}
 	}
 	bulkIndices, indicesInBulk, err := self.wrapper.GetTokenIndicies(
 		nil, nil,
 		self.pricingAddr,
-		tokens,
+		addresses,
 	)
 	if err != nil {
-		return err
+		return result, err
 	}
-	for i, tok := range tokens {
-		self.tokenIndices[tok.Hex()] = tbindex{
+	for i, tok := range addresses {
+		result[tok.Hex()] = tbindex{
 			bulkIndices[i].Uint64(),
 			indicesInBulk[i].Uint64(),
 		}
 	}
+	return result, nil
+}
+
+func (self *Blockchain) LoadAndSetTokenIndices() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	tokenIndices, err := self.loadTokenIndices(self.tokens)
+	if err != nil {
+		return err
+	}
+	self.tokenIndices = tokenIndices
 	log.Printf("Token indices: %+v", self.tokenIndices)
 	return nil
 }
 
+// RegisterToken adds a token listed in the pricing contract while the
+// blockchain is used, its compact data index is loaded with the others.
+func (self *Blockchain) RegisterToken(t common.Token) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	tokens := []common.Token{}
+	for _, tok := range self.tokens {
+		if tok.ID != t.ID {
+			tokens = append(tokens, tok)
+		}
+	}
+	tokens = append(tokens, t)
+	tokenIndices, err := self.loadTokenIndices(tokens)
+	if err != nil {
+		return err
+	}
+	self.tokens = tokens
+	self.tokenIndices = tokenIndices
+	log.Printf("Registered token %s, token indices: %+v", t.ID, self.tokenIndices)
+	return nil
+}
+
 func getNextNonce(n NonceCorpus) (*big.Int, error) {
 	var nonce *big.Int
 	var err error
@@ -193,6 +277,34 @@ func (self *Blockchain) getDepositTransactOpts(nonce, gasPrice *big.Int) (*bind.
 	return &result, cancel, nil
 }
 
+func (self *Blockchain) getAlerterTransactOpts(nonce, gasPrice *big.Int) (*bind.TransactOpts, context.CancelFunc, error) {
+	if self.alerterSigner == nil {
+		return nil, donothing, errors.New("No alerter key is configured")
+	}
+	shared := self.alerterSigner.GetTransactOpts()
+	var err error
+	if nonce == nil {
+		nonce, err = getNextNonce(self.nonceAlerter)
+	}
+	if err != nil {
+		return nil, donothing, err
+	}
+	if gasPrice == nil {
+		gasPrice = big.NewInt(50100000000)
+	}
+	timeout, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	result := bind.TransactOpts{
+		From:     shared.From,
+		Nonce:    nonce,
+		Signer:   shared.Signer,
+		Value:    shared.Value,
+		GasPrice: gasPrice,
+		GasLimit: shared.GasLimit,
+		Context:  timeout,
+	}
+	return &result, cancel, nil
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -265,31 +377,55 @@ func (self *Blockchain) SetRates(
 	sells []*big.Int,
 	block *big.Int,
 	nonce *big.Int,
-	gasPrice *big.Int) (*types.Transaction, error) {
+	gasPrice *big.Int) (*types.Transaction, []ethereum.Address, error) {
+
+	block.Add(block, big.NewInt(1))
+	tokenIndices := self.getTokenIndices()
+	baseBuys, baseSells, compactBuys, compactSells, updateBlocks, err := self.wrapper.GetTokenRates(
+		nil, nil, self.pricingAddr, tokens,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	kept, unchangedTokens, err := self.filterUnchangedRates(
+		tokens, buys, sells, baseBuys, baseSells, compactBuys, compactSells, updateBlocks, block, tokenIndices,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	skipped := []ethereum.Address{}
+	if len(unchangedTokens) > 0 {
+		if len(kept) == 0 && nonce == nil {
+			log.Printf("Rates of all tokens are unchanged, no set rates tx is sent")
+			return nil, unchangedTokens, nil
+		}
+		// a pending set rates tx is replaced even when no rate changed so
+		// its outdated rates are not mined
+		if len(kept) > 0 {
+			skipped = unchangedTokens
+			tokens = keptTokens(tokens, kept)
+			buys, sells = keptRates(buys, kept), keptRates(sells, kept)
+			baseBuys, baseSells = keptRates(baseBuys, kept), keptRates(baseSells, kept)
+		}
+	}
 
 	opts, cancel, err := self.getTransactOpts(nonce, gasPrice)
 
 	defer cancel()
-	block.Add(block, big.NewInt(1))
 	if err != nil {
 		log.Printf("Getting transaction opts failed, err: %s", err)
-		return nil, err
+		return nil, nil, err
 	} else {
-		baseBuys, baseSells, _, _, _, err := self.wrapper.GetTokenRates(
-			nil, nil, self.pricingAddr, tokens,
-		)
-		if err != nil {
-			return nil, err
-		}
 		baseTokens := []ethereum.Address{}
 		newBSells := []*big.Int{}
 		newBBuys := []*big.Int{}
 		newCSells := map[ethereum.Address]byte{}
 		newCBuys := map[ethereum.Address]byte{}
+		plan := self.planCompactRates(tokens, buys, sells, baseBuys, baseSells)
 		for i, token := range tokens {
-			compactSell, overflow1 := BigIntToCompactRate(sells[i], baseSells[i])
-			compactBuy, overflow2 := BigIntToCompactRate(buys[i], baseBuys[i])
-			if overflow1 || overflow2 {
+			compactSell, _ := BigIntToCompactRate(sells[i], baseSells[i])
+			compactBuy, _ := BigIntToCompactRate(buys[i], baseBuys[i])
+			if plan.Tokens[i].Base {
 				baseTokens = append(baseTokens, token)
 				newBSells = append(newBSells, sells[i])
 				newBBuys = append(newBBuys, buys[i])
@@ -303,7 +439,7 @@ func (self *Blockchain) SetRates(
 		bbuys, bsells, indices := BuildCompactBulk(
 			newCBuys,
 			newCSells,
-			self.tokenIndices,
+			tokenIndices,
 		)
 		var tx *types.Transaction
 		if len(baseTokens) > 0 {
@@ -341,11 +477,157 @@ func (self *Blockchain) SetRates(
 			// )
 		}
 		if err != nil {
-			return nil, err
-		} else {
-			return self.signAndBroadcast(tx, self.signer)
+			return nil, nil, err
+		}
+		tx, err = self.signAndBroadcast(tx, self.signer)
+		return tx, skipped, err
+	}
+}
+
+// rateVolatility returns the largest recent move of rates of each token,
+// by token id.
+func (self *Blockchain) rateVolatility() map[string]float64 {
+	if self.rateHistory == nil {
+		return map[string]float64{}
+	}
+	toTime := common.GetTimepoint()
+	history, err := self.rateHistory.GetRates(toTime-VOLATILITY_WINDOW, toTime)
+	if err != nil {
+		log.Printf("Getting rate history failed, compact rates are planned without volatility: %s", err)
+		return map[string]float64{}
+	}
+	return common.RateVolatility(history)
+}
+
+// tokenIDs returns ids of tokens, or their address when they are unknown.
+func (self *Blockchain) tokenIDs(tokens []ethereum.Address) []string {
+	result := []string{}
+	for _, token := range tokens {
+		id := token.Hex()
+		for _, t := range self.getTokens() {
+			if ethereum.HexToAddress(t.Address) == token {
+				id = t.ID
+				break
+			}
 		}
+		result = append(result, id)
+	}
+	return result
+}
+
+// planCompactRates plans compact rates of tokens set in a set rates tx.
+func (self *Blockchain) planCompactRates(tokens []ethereum.Address, buys, sells, baseBuys, baseSells []*big.Int) common.CompactRatePlan {
+	plan := PlanCompactRates(self.tokenIDs(tokens), buys, sells, baseBuys, baseSells, self.rateVolatility())
+	for _, decision := range plan.Tokens {
+		if decision.Base {
+			log.Printf("Setting base rates of %s: %s", decision.Token, decision.Reason)
+		}
+	}
+	return plan
+}
+
+// PlanCompactRates returns how target buys and sells of tokens would be
+// set against their current rates on chain, without setting them. Tokens
+// skipped by rate tolerance as in SetRates are not planned.
+func (self *Blockchain) PlanCompactRates(tokens []ethereum.Address, buys, sells []*big.Int) (common.CompactRatePlan, error) {
+	tokenIndices := self.getTokenIndices()
+	baseBuys, baseSells, compactBuys, compactSells, updateBlocks, err := self.wrapper.GetTokenRates(
+		nil, nil, self.pricingAddr, tokens,
+	)
+	if err != nil {
+		return common.CompactRatePlan{}, err
+	}
+	current, err := self.CurrentBlock()
+	if err != nil {
+		return common.CompactRatePlan{}, err
+	}
+	// rates would be set from the next block
+	block := big.NewInt(0).SetUint64(current + 1)
+	kept, skipped, err := self.filterUnchangedRates(
+		tokens, buys, sells, baseBuys, baseSells, compactBuys, compactSells, updateBlocks, block, tokenIndices,
+	)
+	if err != nil {
+		return common.CompactRatePlan{}, err
+	}
+	tokens = keptTokens(tokens, kept)
+	buys, sells = keptRates(buys, kept), keptRates(sells, kept)
+	baseBuys, baseSells = keptRates(baseBuys, kept), keptRates(baseSells, kept)
+	plan := PlanCompactRates(self.tokenIDs(tokens), buys, sells, baseBuys, baseSells, self.rateVolatility())
+	plan.Skipped = self.tokenIDs(skipped)
+	return plan, nil
+}
+
+// filterUnchangedRates returns positions of tokens whose rates are set at
+// block and the tokens skipped as their bulks are unchanged within rate
+// tolerance. All tokens are kept when rate tolerance is disabled.
+func (self *Blockchain) filterUnchangedRates(
+	tokens []ethereum.Address,
+	buys, sells, baseBuys, baseSells []*big.Int,
+	compactBuys, compactSells []int8,
+	updateBlocks []*big.Int,
+	block *big.Int,
+	tokenIndices map[string]tbindex) ([]int, []ethereum.Address, error) {
+	if self.rateTolerance <= 0 {
+		kept := []int{}
+		for i := range tokens {
+			kept = append(kept, i)
+		}
+		return kept, []ethereum.Address{}, nil
+	}
+	unchanged, err := unchangedRates(self.pricing, self.rateTolerance, buys, sells, baseBuys, baseSells, compactBuys, compactSells, updateBlocks, block)
+	if err != nil {
+		return nil, nil, err
+	}
+	kept, skipped := FilterUnchangedBulks(tokens, unchanged, tokenIndices)
+	return kept, skipped, nil
+}
+
+func keptTokens(tokens []ethereum.Address, kept []int) []ethereum.Address {
+	result := []ethereum.Address{}
+	for _, i := range kept {
+		result = append(result, tokens[i])
 	}
+	return result
+}
+
+func keptRates(rates []*big.Int, kept []int) []*big.Int {
+	result := []*big.Int{}
+	for _, i := range kept {
+		result = append(result, rates[i])
+	}
+	return result
+}
+
+// validDurationReader reads how many blocks rates stay valid in the
+// pricing contract.
+type validDurationReader interface {
+	ValidRateDurationInBlocks(opts *bind.CallOpts, atBlock *big.Int) (*big.Int, error)
+}
+
+// unchangedRates returns whether each token's buy and sell are within
+// tolerance of its rates on chain, and its rates don't need to be set
+// again before they expire, past half of their valid duration.
+func unchangedRates(
+	pricing validDurationReader,
+	tolerance float64,
+	buys, sells, baseBuys, baseSells []*big.Int,
+	compactBuys, compactSells []int8,
+	updateBlocks []*big.Int,
+	block *big.Int) ([]bool, error) {
+	validDuration, err := pricing.ValidRateDurationInBlocks(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := []bool{}
+	for i := range buys {
+		refreshBlock := big.NewInt(0).Div(validDuration, big.NewInt(2))
+		refreshBlock.Add(refreshBlock, updateBlocks[i])
+		result = append(result,
+			block.Cmp(refreshBlock) < 0 &&
+				RateWithinTolerance(buys[i], CurrentRate(baseBuys[i], compactBuys[i]), tolerance) &&
+				RateWithinTolerance(sells[i], CurrentRate(baseSells[i], compactSells[i]), tolerance))
+	}
+	return result, nil
 }
 
 func (self *Blockchain) Send(
@@ -400,6 +682,80 @@ func (self *Blockchain) SetQtyStepFunction(token ethereum.Address, xBuy []*big.I
 	}
 }
 
+// SetTradeEnabled enables or disables trading of the reserve with the
+// alerter key.
+func (self *Blockchain) SetTradeEnabled(enable bool) (*types.Transaction, error) {
+	opts, cancel, err := self.getAlerterTransactOpts(nil, nil)
+	defer cancel()
+	if err != nil {
+		log.Printf("Getting transaction opts failed, err: %s", err)
+		return nil, err
+	}
+	var tx *types.Transaction
+	if enable {
+		tx, err = self.reserve.EnableTrade(opts)
+	} else {
+		tx, err = self.reserve.DisableTrade(opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return self.signAndBroadcast(tx, self.alerterSigner)
+}
+
+// SetTokenTradeEnabled enables or disables trading of token in the pricing
+// contract with the alerter key.
+func (self *Blockchain) SetTokenTradeEnabled(token ethereum.Address, enable bool) (*types.Transaction, error) {
+	opts, cancel, err := self.getAlerterTransactOpts(nil, nil)
+	defer cancel()
+	if err != nil {
+		log.Printf("Getting transaction opts failed, err: %s", err)
+		return nil, err
+	}
+	var tx *types.Transaction
+	if enable {
+		tx, err = self.pricing.EnableTokenTrade(opts, token)
+	} else {
+		tx, err = self.pricing.DisableTokenTrade(opts, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return self.signAndBroadcast(tx, self.alerterSigner)
+}
+
+// AddPricingToken lists token in the pricing contract with the alerter
+// key, it must be the admin of the contract.
+func (self *Blockchain) AddPricingToken(token ethereum.Address) (*types.Transaction, error) {
+	opts, cancel, err := self.getAlerterTransactOpts(nil, nil)
+	defer cancel()
+	if err != nil {
+		log.Printf("Getting transaction opts failed, err: %s", err)
+		return nil, err
+	}
+	tx, err := self.pricing.AddToken(opts, token)
+	if err != nil {
+		return nil, err
+	}
+	return self.signAndBroadcast(tx, self.alerterSigner)
+}
+
+// SetTokenControlInfo sets imbalance limits of token, in token wei, with
+// the alerter key, it must be the admin of the pricing contract.
+func (self *Blockchain) SetTokenControlInfo(token ethereum.Address, minimalRecordResolution, maxPerBlockImbalance, maxTotalImbalance *big.Int) (*types.Transaction, error) {
+	opts, cancel, err := self.getAlerterTransactOpts(nil, nil)
+	defer cancel()
+	if err != nil {
+		log.Printf("Getting transaction opts failed, err: %s", err)
+		return nil, err
+	}
+	tx, err := self.pricing.SetTokenControlInfo(opts, token, minimalRecordResolution, maxPerBlockImbalance, maxTotalImbalance)
+	if err != nil {
+		return nil, err
+	}
+	return self.signAndBroadcast(tx, self.alerterSigner)
+}
+
 //====================== Readonly calls ============================
 func (self *Blockchain) CurrentBlock() (uint64, error) {
 	var blockno string
@@ -480,8 +836,9 @@ func (self *Blockchain) TxStatus(hash ethereum.Hash) (string, uint64, error) {
 
 func (self *Blockchain) FetchBalanceData(reserve ethereum.Address, atBlock *big.Int, timepoint uint64) (map[string]common.BalanceEntry, error) {
 	result := map[string]common.BalanceEntry{}
+	allTokens := self.getTokens()
 	tokens := []ethereum.Address{}
-	for _, tok := range self.tokens {
+	for _, tok := range allTokens {
 		tokens = append(tokens, ethereum.HexToAddress(tok.Address))
 	}
 	timestamp := common.GetTimestamp()
@@ -489,7 +846,7 @@ func (self *Blockchain) FetchBalanceData(reserve ethereum.Address, atBlock *big.
 	returnTime := common.GetTimestamp()
 	log.Printf("Fetcher ------> balances: %v, err: %s", balances, err)
 	if err != nil {
-		for tokenID, _ := range common.SupportedTokens {
+		for tokenID, _ := range common.GetSupportedTokens() {
 			result[tokenID] = common.BalanceEntry{
 				Valid:      false,
 				Error:      err.Error(),
@@ -498,7 +855,7 @@ func (self *Blockchain) FetchBalanceData(reserve ethereum.Address, atBlock *big.
 			}
 		}
 	} else {
-		for i, tok := range self.tokens {
+		for i, tok := range allTokens {
 			if balances[i].Cmp(Big0) == 0 || balances[i].Cmp(BigMax) > 0 {
 				log.Printf("Fetcher ------> balances of token %s is invalid", tok.ID)
 				result[tok.ID] = common.BalanceEntry{
@@ -525,7 +882,7 @@ func (self *Blockchain) FetchRates(timepoint uint64, atBlock uint64, currentBloc
 	result := common.AllRateEntry{}
 	tokenAddrs := []ethereum.Address{}
 	validTokens := []common.Token{}
-	for _, s := range self.tokens {
+	for _, s := range self.getTokens() {
 		if s.ID != "ETH" {
 			tokenAddrs = append(tokenAddrs, ethereum.HexToAddress(s.Address))
 			validTokens = append(validTokens, s)
@@ -559,6 +916,119 @@ func (self *Blockchain) FetchRates(timepoint uint64, atBlock uint64, currentBloc
 	}
 }
 
+// FetchRateStates returns the number of blocks rates are valid for and the
+// state of each token other than ETH in the pricing contract at atBlock.
+func (self *Blockchain) FetchRateStates(atBlock uint64) (uint64, map[string]common.TokenRateState, error) {
+	result := map[string]common.TokenRateState{}
+	block := big.NewInt(int64(atBlock))
+	duration, err := self.pricing.ValidRateDurationInBlocks(nil, block)
+	if err != nil {
+		return 0, result, err
+	}
+	for _, token := range self.getTokens() {
+		if token.IsETH() {
+			continue
+		}
+		addr := ethereum.HexToAddress(token.Address)
+		state := common.TokenRateState{}
+		state.Listed, state.Enabled, err = self.pricing.GetTokenBasicData(nil, block, addr)
+		if err != nil {
+			return 0, result, err
+		}
+		updateBlock, err := self.pricing.GetRateUpdateBlock(nil, block, addr)
+		if err != nil {
+			return 0, result, err
+		}
+		state.UpdateBlock = updateBlock.Uint64()
+		resolution, maxPerBlock, maxTotal, err := self.pricing.GetTokenControlInfo(nil, block, addr)
+		if err != nil {
+			return 0, result, err
+		}
+		state.MinimalRecordResolution = common.BigToFloat(resolution, token.Decimal)
+		state.MaxPerBlockImbalance = common.BigToFloat(maxPerBlock, token.Decimal)
+		state.MaxTotalImbalance = common.BigToFloat(maxTotal, token.Decimal)
+		result[token.ID] = state
+	}
+	return duration.Uint64(), result, nil
+}
+
+// GetTradeStatus returns whether trading of the reserve is enabled and
+// whether trading of each token other than ETH is enabled in the pricing
+// contract.
+func (self *Blockchain) GetTradeStatus() (common.TradeStatus, error) {
+	result := common.TradeStatus{Tokens: map[string]bool{}}
+	var err error
+	result.TradeEnabled, err = self.reserve.TradeEnabled(nil, nil)
+	if err != nil {
+		return result, err
+	}
+	for _, token := range self.getTokens() {
+		if token.IsETH() {
+			continue
+		}
+		_, enabled, err := self.pricing.GetTokenBasicData(nil, nil, ethereum.HexToAddress(token.Address))
+		if err != nil {
+			return result, err
+		}
+		result.Tokens[token.ID] = enabled
+	}
+	return result, nil
+}
+
+// getStepFunction reads a step function of token from the pricing
+// contract, command is the getStepFunctionData command returning its
+// length of x, the next ones return x at an index, length of y and y at
+// an index.
+func (self *Blockchain) getStepFunction(token common.Token, command int64) (common.StepFunction, error) {
+	result := common.StepFunction{X: []float64{}, Y: []int64{}}
+	addr := ethereum.HexToAddress(token.Address)
+	get := func(command int64, param int64) (*big.Int, error) {
+		return self.pricing.GetStepFunctionData(nil, nil, addr, big.NewInt(command), big.NewInt(param))
+	}
+	length, err := get(command, 0)
+	if err != nil {
+		return result, err
+	}
+	for i := int64(0); i < length.Int64(); i++ {
+		x, err := get(command+1, i)
+		if err != nil {
+			return result, err
+		}
+		result.X = append(result.X, common.BigToFloat(x, token.Decimal))
+	}
+	length, err = get(command+2, 0)
+	if err != nil {
+		return result, err
+	}
+	for i := int64(0); i < length.Int64(); i++ {
+		y, err := get(command+3, i)
+		if err != nil {
+			return result, err
+		}
+		result.Y = append(result.Y, y.Int64())
+	}
+	return result, nil
+}
+
+// GetStepFunctions returns the quantity and imbalance step functions of
+// token in the pricing contract, steps are in token and adjustments in
+// basis points.
+func (self *Blockchain) GetStepFunctions(token common.Token) (common.TokenStepFunctions, error) {
+	result := common.TokenStepFunctions{}
+	var err error
+	if result.QtyBuy, err = self.getStepFunction(token, 0); err != nil {
+		return result, err
+	}
+	if result.QtySell, err = self.getStepFunction(token, 4); err != nil {
+		return result, err
+	}
+	if result.ImbalanceBuy, err = self.getStepFunction(token, 8); err != nil {
+		return result, err
+	}
+	result.ImbalanceSell, err = self.getStepFunction(token, 12)
+	return result, err
+}
+
 func (self *Blockchain) GetPrice(token ethereum.Address, block *big.Int, priceType string, qty *big.Int, atBlock *big.Int) (*big.Int, error) {
 	if priceType == "buy" {
 		return self.pricing.GetRate(nil, atBlock, token, block, true, qty)
@@ -668,7 +1138,7 @@ func (self *Blockchain) GetLogs(fromBlock uint64, toBlock uint64, timepoint uint
 
 					if ethRate != 0 {
 						// fiatAmount = amount * ethRate
-						eth := common.SupportedTokens["ETH"]
+						eth := common.GetSupportedTokens()["ETH"]
 						f := new(big.Float)
 						if strings.ToLower(eth.Address) == strings.ToLower(srcAddr.String()) {
 							f.SetInt(tradeLog.SrcAmount)
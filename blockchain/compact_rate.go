@@ -1,12 +1,29 @@
 package blockchain
 
 import (
+	"fmt"
+	"math"
 	"math/big"
 	"strconv"
 
+	"github.com/KyberNetwork/reserve-data/common"
 	ethereum "github.com/ethereum/go-ethereum/common"
 )
 
+const (
+	// MAX_COMPACT is the largest compact rate, in 0.1% of the base rate
+	MAX_COMPACT int64 = 127
+	// MIN_REBASE_MARGIN is the headroom below which a token is rebased
+	// even when its rates are stable
+	MIN_REBASE_MARGIN int64 = 10
+	// VOLATILITY_MARGIN is the number of its largest recent rate moves a
+	// token keeps headroom for before it is rebased
+	VOLATILITY_MARGIN float64 = 3
+	// PIGGYBACK_COMPACT is the compact rate above which a token is rebased
+	// when base rates are set in the tx anyway
+	PIGGYBACK_COMPACT int64 = 64
+)
+
 type CompactRate struct {
 	Base    *big.Int
 	Compact byte
@@ -17,11 +34,26 @@ type CompactRate struct {
 // 8bits, the base is changed to the rate, Compact is set to 0 and
 // return overflow = true
 func BigIntToCompactRate(rate *big.Int, base *big.Int) (compactrate *CompactRate, overflow bool) {
-	if base.Cmp(big.NewInt(0)) == 0 {
+	intComp, ok := compactValue(rate, base)
+	if ok && -128 <= intComp && intComp <= 127 {
+		// capable to change compact
+		return &CompactRate{
+			base, byte(intComp),
+		}, false
+	} else {
+		// incapable to change compact, need to change base
 		return &CompactRate{
 			rate, 0,
 		}, true
 	}
+}
+
+// compactValue returns the compact rate of rate on base, it may not fit
+// 8 bits. It returns false when base is 0.
+func compactValue(rate *big.Int, base *big.Int) (int64, bool) {
+	if base.Cmp(big.NewInt(0)) == 0 {
+		return 0, false
+	}
 	// rate = base * (1 + compact/1000)
 	// compact = (rate / base - 1) * 1000
 	fRate := big.NewFloat(0).SetInt(rate)
@@ -32,17 +64,7 @@ func BigIntToCompactRate(rate *big.Int, base *big.Int) (compactrate *CompactRate
 	// using text to round float
 	str := compact.Text('f', 0)
 	intComp, _ := strconv.ParseInt(str, 10, 64)
-	if -128 <= intComp && intComp <= 127 {
-		// capable to change compact
-		return &CompactRate{
-			base, byte(intComp),
-		}, false
-	} else {
-		// incapable to change compact, need to change base
-		return &CompactRate{
-			rate, 0,
-		}, true
-	}
+	return intComp, true
 }
 
 type bulk struct {
@@ -74,3 +96,96 @@ func BuildCompactBulk(newBuys, newSells map[ethereum.Address]byte, indices map[s
 	}
 	return buyResults, sellResults, indexResults
 }
+
+// CurrentRate returns the rate of a token from its base rate and compact
+// rate in the pricing contract.
+func CurrentRate(base *big.Int, compact int8) *big.Int {
+	// rate = base * (1000 + compact) / 1000
+	result := big.NewInt(0).Mul(base, big.NewInt(1000+int64(compact)))
+	return result.Div(result, big.NewInt(1000))
+}
+
+// RateWithinTolerance returns true when target differs from current by at
+// most tolerance, relatively to current.
+func RateWithinTolerance(target, current *big.Int, tolerance float64) bool {
+	if current.Sign() == 0 {
+		return target.Sign() == 0
+	}
+	diff := big.NewFloat(0).SetInt(big.NewInt(0).Sub(target, current))
+	diff.Quo(diff.Abs(diff), big.NewFloat(0).SetInt(current))
+	change, _ := diff.Float64()
+	return change <= tolerance
+}
+
+// FilterUnchangedBulks returns positions of tokens to set and the tokens
+// skipped, given which tokens are unchanged. A bulk is only skipped when
+// all its tokens are unchanged so it is never partly written.
+func FilterUnchangedBulks(tokens []ethereum.Address, unchanged []bool, indices map[string]tbindex) ([]int, []ethereum.Address) {
+	changedBulks := map[uint64]bool{}
+	for i, token := range tokens {
+		if !unchanged[i] {
+			changedBulks[indices[token.Hex()].BulkIndex] = true
+		}
+	}
+	kept := []int{}
+	skipped := []ethereum.Address{}
+	for i, token := range tokens {
+		if changedBulks[indices[token.Hex()].BulkIndex] {
+			kept = append(kept, i)
+		} else {
+			skipped = append(skipped, token)
+		}
+	}
+	return kept, skipped
+}
+
+// PlanCompactRates decides for each token whether its target buy and sell
+// are set as compact rates on its current base rates or with new base
+// rates. Besides tokens whose compact rates overflow, tokens are rebased
+// before they overflow when their headroom is within VOLATILITY_MARGIN of
+// their largest recent moves, at least MIN_REBASE_MARGIN, so it happens in
+// a tx of our choice rather than when it is forced. Once base rates are
+// set in the tx, tokens past PIGGYBACK_COMPACT are rebased along.
+func PlanCompactRates(tokens []string, buys, sells, baseBuys, baseSells []*big.Int, volatility map[string]float64) common.CompactRatePlan {
+	result := common.CompactRatePlan{Tokens: []common.CompactRateDecision{}, Skipped: []string{}}
+	for i, token := range tokens {
+		decision := common.CompactRateDecision{
+			Token:      token,
+			Volatility: volatility[token],
+		}
+		compactBuy, okBuy := compactValue(buys[i], baseBuys[i])
+		compactSell, okSell := compactValue(sells[i], baseSells[i])
+		decision.CompactBuy, decision.CompactSell = compactBuy, compactSell
+		decision.Headroom = MAX_COMPACT - int64(math.Max(math.Abs(float64(compactBuy)), math.Abs(float64(compactSell))))
+		decision.Margin = int64(math.Ceil(VOLATILITY_MARGIN * decision.Volatility * 1000))
+		if decision.Margin < MIN_REBASE_MARGIN {
+			decision.Margin = MIN_REBASE_MARGIN
+		}
+		_, overflowBuy := BigIntToCompactRate(buys[i], baseBuys[i])
+		_, overflowSell := BigIntToCompactRate(sells[i], baseSells[i])
+		switch {
+		case !okBuy || !okSell:
+			decision.Base = true
+			decision.Reason = "no base rate is set"
+		case overflowBuy || overflowSell:
+			decision.Base = true
+			decision.Reason = fmt.Sprintf("compact rates %d/%d overflow", compactBuy, compactSell)
+		case decision.Headroom < decision.Margin:
+			decision.Base = true
+			decision.Reason = fmt.Sprintf("headroom %d is below margin %d, largest recent move is %.3f%%", decision.Headroom, decision.Margin, decision.Volatility*100)
+		default:
+			decision.Reason = fmt.Sprintf("headroom %d is above margin %d", decision.Headroom, decision.Margin)
+		}
+		result.BaseTx = result.BaseTx || decision.Base
+		result.Tokens = append(result.Tokens, decision)
+	}
+	if result.BaseTx {
+		for i, decision := range result.Tokens {
+			if !decision.Base && MAX_COMPACT-decision.Headroom > PIGGYBACK_COMPACT {
+				result.Tokens[i].Base = true
+				result.Tokens[i].Reason = fmt.Sprintf("compact rates %d/%d are past %d and base rates are set anyway", decision.CompactBuy, decision.CompactSell, PIGGYBACK_COMPACT)
+			}
+		}
+	}
+	return result
+}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethereum "github.com/ethereum/go-ethereum/common"
 )
 
@@ -159,3 +160,85 @@ func TestBuildCompactBulk(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterUnchangedBulks(t *testing.T) {
+	current := CurrentRate(big.NewInt(1000000), -5)
+	if current.Int64() != 995000 {
+		t.Fatalf("Expected current rate 995000, got %s", current)
+	}
+	if !RateWithinTolerance(big.NewInt(995500), current, 0.001) || RateWithinTolerance(big.NewInt(997000), current, 0.001) {
+		t.Fatalf("Unexpected tolerance check against %s", current)
+	}
+	tokens := []ethereum.Address{
+		ethereum.HexToAddress("0x1111111111111111111111111111111111111111"),
+		ethereum.HexToAddress("0x2222222222222222222222222222222222222222"),
+		ethereum.HexToAddress("0x3333333333333333333333333333333333333333"),
+	}
+	indices := map[string]tbindex{
+		tokens[0].Hex(): {BulkIndex: 0, IndexInBulk: 0},
+		tokens[1].Hex(): {BulkIndex: 0, IndexInBulk: 1},
+		tokens[2].Hex(): {BulkIndex: 1, IndexInBulk: 0},
+	}
+	// the first token is unchanged but shares its bulk with a changed one
+	kept, skipped := FilterUnchangedBulks(tokens, []bool{true, false, true}, indices)
+	if !reflect.DeepEqual(kept, []int{0, 1}) || !reflect.DeepEqual(skipped, []ethereum.Address{tokens[2]}) {
+		t.Fatalf("Unexpected kept %v and skipped %v", kept, skipped)
+	}
+}
+
+type testDurationReader struct {
+	duration *big.Int
+}
+
+func (self testDurationReader) ValidRateDurationInBlocks(opts *bind.CallOpts, atBlock *big.Int) (*big.Int, error) {
+	return self.duration, nil
+}
+
+func TestUnchangedRates(t *testing.T) {
+	base := big.NewInt(1000000)
+	bases := []*big.Int{base, base, base}
+	// rates on chain are 995000, the last token was updated long ago
+	compacts := []int8{-5, -5, -5}
+	updateBlocks := []*big.Int{big.NewInt(100), big.NewInt(100), big.NewInt(50)}
+	targets := []*big.Int{big.NewInt(995500), big.NewInt(997000), big.NewInt(995000)}
+	unchanged, err := unchangedRates(
+		testDurationReader{big.NewInt(100)}, 0.001,
+		targets, targets, bases, bases, compacts, compacts, updateBlocks, big.NewInt(120),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unchanged, []bool{true, false, false}) {
+		t.Fatalf("Unexpected unchanged rates %v", unchanged)
+	}
+}
+
+func TestPlanCompactRates(t *testing.T) {
+	base := big.NewInt(1000000)
+	rates := func(compacts ...int64) []*big.Int {
+		result := []*big.Int{}
+		for _, compact := range compacts {
+			result = append(result, big.NewInt(1000000+compact*1000))
+		}
+		return result
+	}
+	bases := []*big.Int{base, base, base, base}
+	tokens := []string{"KNC", "OMG", "EOS", "SNT"}
+	// KNC is near overflow, OMG is volatile, EOS and SNT have room
+	volatility := map[string]float64{"OMG": 0.01}
+	plan := PlanCompactRates(tokens, rates(120, 100, 80, 20), rates(120, 100, 80, 20), bases, bases, volatility)
+	expected := []bool{true, true, true, false}
+	for i, decision := range plan.Tokens {
+		if decision.Base != expected[i] {
+			t.Fatalf("Expected base of %s to be %t, got %+v", decision.Token, expected[i], decision)
+		}
+	}
+	if !plan.BaseTx || plan.Tokens[1].Margin != 30 {
+		t.Fatalf("Unexpected plan %+v", plan)
+	}
+	// EOS is only rebased along with other tokens
+	plan = PlanCompactRates(tokens[2:], rates(80, 20), rates(80, 20), bases[2:], bases[2:], volatility)
+	if plan.BaseTx {
+		t.Fatalf("Expected no base rate to be set, got %+v", plan)
+	}
+}
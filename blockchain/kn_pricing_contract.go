@@ -7,6 +7,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethereum "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
@@ -31,12 +32,84 @@ func (self *KNPricingContract) SetQtyStepFunction(opts *bind.TransactOpts, token
 	return self.KNContractBase.BuildTx(opts, "setQtyStepFunction", token, xBuy, yBuy, xSell, ySell)
 }
 
+func (self *KNPricingContract) AddToken(opts *bind.TransactOpts, token ethereum.Address) (*types.Transaction, error) {
+	return self.KNContractBase.BuildTx(opts, "addToken", token)
+}
+
+func (self *KNPricingContract) SetTokenControlInfo(opts *bind.TransactOpts, token ethereum.Address, minimalRecordResolution *big.Int, maxPerBlockImbalance *big.Int, maxTotalImbalance *big.Int) (*types.Transaction, error) {
+	return self.KNContractBase.BuildTx(opts, "setTokenControlInfo", token, minimalRecordResolution, maxPerBlockImbalance, maxTotalImbalance)
+}
+
+func (self *KNPricingContract) EnableTokenTrade(opts *bind.TransactOpts, token ethereum.Address) (*types.Transaction, error) {
+	return self.KNContractBase.BuildTx(opts, "enableTokenTrade", token)
+}
+
+func (self *KNPricingContract) DisableTokenTrade(opts *bind.TransactOpts, token ethereum.Address) (*types.Transaction, error) {
+	return self.KNContractBase.BuildTx(opts, "disableTokenTrade", token)
+}
+
 func (self *KNPricingContract) GetRate(opts *bind.CallOpts, atBlock *big.Int, token ethereum.Address, currentBlockNumber *big.Int, buy bool, qty *big.Int) (*big.Int, error) {
 	out := big.NewInt(0)
 	err := self.KNContractBase.Call(opts, atBlock, out, "getRate", token, currentBlockNumber, buy, qty)
 	return out, err
 }
 
+func (self *KNPricingContract) ValidRateDurationInBlocks(opts *bind.CallOpts, atBlock *big.Int) (*big.Int, error) {
+	out := new(*big.Int)
+	err := self.KNContractBase.Call(opts, atBlock, out, "validRateDurationInBlocks")
+	return *out, err
+}
+
+func (self *KNPricingContract) GetRateUpdateBlock(opts *bind.CallOpts, atBlock *big.Int, token ethereum.Address) (*big.Int, error) {
+	out := new(*big.Int)
+	err := self.KNContractBase.Call(opts, atBlock, out, "getRateUpdateBlock", token)
+	return *out, err
+}
+
+// GetTokenBasicData returns whether token is listed and whether its trade
+// is enabled.
+func (self *KNPricingContract) GetTokenBasicData(opts *bind.CallOpts, atBlock *big.Int, token ethereum.Address) (bool, bool, error) {
+	var (
+		ret0 = new(bool)
+		ret1 = new(bool)
+	)
+	out := &[]interface{}{
+		ret0,
+		ret1,
+	}
+	err := self.KNContractBase.Call(opts, atBlock, out, "getTokenBasicData", token)
+	return *ret0, *ret1, err
+}
+
+// GetTokenControlInfo returns minimal record resolution, max per block
+// imbalance and max total imbalance of token in its wei.
+func (self *KNPricingContract) GetTokenControlInfo(opts *bind.CallOpts, atBlock *big.Int, token ethereum.Address) (*big.Int, *big.Int, *big.Int, error) {
+	var (
+		ret0 = new(*big.Int)
+		ret1 = new(*big.Int)
+		ret2 = new(*big.Int)
+	)
+	out := &[]interface{}{
+		ret0,
+		ret1,
+		ret2,
+	}
+	err := self.KNContractBase.Call(opts, atBlock, out, "getTokenControlInfo", token)
+	return *ret0, *ret1, *ret2, err
+}
+
+// GetStepFunctionData returns one value of the step functions of token,
+// command selects the function and whether its length, one of its x or
+// one of its y at index param is returned.
+func (self *KNPricingContract) GetStepFunctionData(opts *bind.CallOpts, atBlock *big.Int, token ethereum.Address, command *big.Int, param *big.Int) (*big.Int, error) {
+	out := new(*big.Int)
+	if err := self.KNContractBase.Call(opts, atBlock, out, "getStepFunctionData", token, command, param); err != nil {
+		return nil, err
+	}
+	// the abi decodes int256 as unsigned, restore its sign
+	return math.S256(*out), nil
+}
+
 func NewKNPricingContract(address ethereum.Address, client *ethclient.Client) (*KNPricingContract, error) {
 	file, err := os.Open(
 		"/go/src/github.com/KyberNetwork/reserve-data/blockchain/pricing.abi")
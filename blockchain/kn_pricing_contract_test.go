@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethereum "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TestEthAPI answers every eth_call with the same ABI encoded output.
+type TestEthAPI struct {
+	output []byte
+}
+
+func (self *TestEthAPI) Call(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+	return self.output, nil
+}
+
+func newTestPricingContract(t *testing.T, output []byte) *KNPricingContract {
+	file, err := os.Open("pricing.abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	parsed, err := abi.JSON(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &TestEthAPI{output}); err != nil {
+		t.Fatal(err)
+	}
+	client := ethclient.NewClient(rpc.DialInProc(server))
+	return &KNPricingContract{NewKNContractBase(ethereum.Address{}, parsed, client)}
+}
+
+func TestPricingContractUintOutputs(t *testing.T) {
+	pricing := newTestPricingContract(t, math.PaddedBigBytes(big.NewInt(30), 32))
+	duration, err := pricing.ValidRateDurationInBlocks(nil, nil)
+	if err != nil {
+		t.Fatalf("Expected valid rate duration to be decoded, got %s", err)
+	}
+	if duration.Int64() != 30 {
+		t.Fatalf("Expected valid rate duration 30, got %s", duration.Text(10))
+	}
+	block, err := pricing.GetRateUpdateBlock(nil, nil, ethereum.Address{})
+	if err != nil {
+		t.Fatalf("Expected rate update block to be decoded, got %s", err)
+	}
+	if block.Int64() != 30 {
+		t.Fatalf("Expected rate update block 30, got %s", block.Text(10))
+	}
+}
+
+func TestPricingContractStepFunctionData(t *testing.T) {
+	for _, value := range []int64{-2000, 0, 4000} {
+		word := math.PaddedBigBytes(math.U256(big.NewInt(value)), 32)
+		pricing := newTestPricingContract(t, word)
+		result, err := pricing.GetStepFunctionData(nil, nil, ethereum.Address{}, big.NewInt(3), big.NewInt(0))
+		if err != nil {
+			t.Fatalf("Expected step function data to be decoded, got %s", err)
+		}
+		if result.Int64() != value {
+			t.Fatalf("Expected step function data %d, got %s", value, result.Text(10))
+		}
+	}
+}
@@ -19,6 +19,20 @@ func (self *KNReserveContract) Withdraw(opts *bind.TransactOpts, token ethereum.
 	return self.KNContractBase.BuildTx(opts, "withdraw", token, amount, destination)
 }
 
+func (self *KNReserveContract) EnableTrade(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return self.KNContractBase.BuildTx(opts, "enableTrade")
+}
+
+func (self *KNReserveContract) DisableTrade(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return self.KNContractBase.BuildTx(opts, "disableTrade")
+}
+
+func (self *KNReserveContract) TradeEnabled(opts *bind.CallOpts, atBlock *big.Int) (bool, error) {
+	out := new(bool)
+	err := self.KNContractBase.Call(opts, atBlock, out, "tradeEnabled")
+	return *out, err
+}
+
 func NewKNReserveContract(address ethereum.Address, client *ethclient.Client) (*KNReserveContract, error) {
 	file, err := os.Open(
 		"/go/src/github.com/KyberNetwork/reserve-data/blockchain/reserve.abi")
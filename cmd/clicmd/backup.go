@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/cmd/configuration"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	statstorage "github.com/KyberNetwork/reserve-data/stat/storage"
+	"github.com/boltdb/bolt"
+	"github.com/spf13/cobra"
+)
+
+type backupFunc func(w io.Writer) (int64, error)
+
+// validators check bucket layout of a database file before it is
+// accepted as a backup or restored
+var validators = map[string]func(path string) error{
+	"data": storage.ValidateBoltFile,
+	"stat": statstorage.ValidateBoltFile,
+}
+
+var backupURL string
+var backupDB string
+var backupOut string
+var restoreFrom string
+
+func getValidator(db string) func(path string) error {
+	validate, found := validators[db]
+	if !found {
+		log.Fatalf("Unknown database %s, it must be data or stat", db)
+	}
+	return validate
+}
+
+// writeSyncedFile writes a snapshot to path and flushes it to disk, path
+// is removed if the snapshot is incomplete
+func writeSyncedFile(path string, backup backupFunc) (int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := backup(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return n, err
+}
+
+// writeBackupFile writes a snapshot to a temporary file and moves it to
+// path only when the snapshot is complete
+func writeBackupFile(path string, backup backupFunc) (int64, error) {
+	tmp := path + ".tmp"
+	n, err := writeSyncedFile(tmp, backup)
+	if err != nil {
+		return n, err
+	}
+	return n, os.Rename(tmp, path)
+}
+
+// rotateBackups keeps only the newest keep backups of db in dir
+func rotateBackups(dir string, db string, keep int) error {
+	files, err := filepath.Glob(filepath.Join(dir, db+"-*.db"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for i := 0; i < len(files)-keep; i++ {
+		log.Printf("Removing old backup %s", files[i])
+		if err := os.Remove(files[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runScheduledBackup backs up all given databases to dir every interval,
+// keeping the newest keep backups of each database
+func runScheduledBackup(dir string, interval time.Duration, keep int, backups map[string]backupFunc) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Printf("Couldn't create backup directory %s, scheduled backup is disabled: %s", dir, err)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	for {
+		timepoint := common.GetTimepoint()
+		for db, backup := range backups {
+			path := filepath.Join(dir, fmt.Sprintf("%s-%d.db", db, timepoint))
+			n, err := writeBackupFile(path, backup)
+			if err != nil {
+				log.Printf("Scheduled backup of %s database failed: %s", db, err)
+				continue
+			}
+			log.Printf("Scheduled backup of %s database: %s (%d bytes)", db, path, n)
+			if err := rotateBackups(dir, db, keep); err != nil {
+				log.Printf("Rotating backups of %s database failed: %s", db, err)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+func downloadBackup(db string, out string, sign func(message string) string) (int64, error) {
+	params := url.Values{}
+	params.Set("nonce", strconv.FormatUint(common.GetTimepoint(), 10))
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/backup/%s?%s", backupURL, db, params.Encode()), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Add("signed", sign(params.Encode()))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return 0, errors.New(fmt.Sprintf("Server refused to back up %s database: %s", db, string(body)))
+	}
+	return writeBackupFile(out, func(w io.Writer) (int64, error) {
+		return io.Copy(w, resp.Body)
+	})
+}
+
+func backupStart(cmd *cobra.Command, args []string) {
+	kyberENV := os.Getenv("KYBER_ENV")
+	if kyberENV == "" {
+		kyberENV = "dev"
+	}
+	validate := getValidator(backupDB)
+	out := backupOut
+	if out == "" {
+		out = fmt.Sprintf("%s-%d.db", backupDB, common.GetTimepoint())
+	}
+	auth := configuration.GetAuthEngine(kyberENV)
+	n, err := downloadBackup(backupDB, out, auth.KNConfigurationSign)
+	if err != nil {
+		log.Fatalf("Backing up %s database failed: %s", backupDB, err)
+	}
+	if err = validate(out); err != nil {
+		log.Fatalf("Backup %s is invalid: %s", out, err)
+	}
+	log.Printf("Backed up %s database to %s (%d bytes)", backupDB, out, n)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = writeSyncedFile(dst, func(w io.Writer) (int64, error) {
+		return io.Copy(w, in)
+	})
+	return err
+}
+
+// restoreStart copies the backup next to the database, validates the copy
+// and only then swaps it in place of the database. The current database
+// is moved back if the swap fails.
+func restoreStart(cmd *cobra.Command, args []string) {
+	kyberENV := os.Getenv("KYBER_ENV")
+	if kyberENV == "" {
+		kyberENV = "dev"
+	}
+	validate := getValidator(backupDB)
+	dataPath, statPath := configuration.GetStoragePaths(kyberENV)
+	target := dataPath
+	if backupDB == "stat" {
+		target = statPath
+	}
+	// the server holds an exclusive lock on its databases, refuse to
+	// swap a database which is still in use
+	_, err := os.Stat(target)
+	exists := err == nil
+	if exists {
+		db, err := bolt.Open(target, 0600, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+		if err != nil {
+			log.Fatalf("Couldn't lock %s, make sure the server is stopped: %s", target, err)
+		}
+		db.Close()
+	}
+	tmp := target + ".tmp"
+	if err := copyFile(restoreFrom, tmp); err != nil {
+		log.Fatalf("Copying %s to %s failed, nothing is changed: %s", restoreFrom, tmp, err)
+	}
+	if err := validate(tmp); err != nil {
+		os.Remove(tmp)
+		log.Fatalf("Backup %s is not a valid %s database, nothing is changed: %s", restoreFrom, backupDB, err)
+	}
+	old := fmt.Sprintf("%s.before-restore-%d", target, common.GetTimepoint())
+	if exists {
+		if err := os.Rename(target, old); err != nil {
+			os.Remove(tmp)
+			log.Fatalf("Couldn't move current database away, nothing is changed: %s", err)
+		}
+		log.Printf("Current %s database is moved to %s", backupDB, old)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		if exists {
+			if rerr := os.Rename(old, target); rerr != nil {
+				log.Fatalf("Restoring %s to %s failed: %s, moving current database back from %s failed too: %s", restoreFrom, target, err, old, rerr)
+			}
+		}
+		log.Fatalf("Restoring %s to %s failed, current database is kept: %s", restoreFrom, target, err)
+	}
+	log.Printf("Restored %s database from %s to %s", backupDB, restoreFrom, target)
+}
+
+var backupCmd = &cobra.Command{
+	Use:     "backup",
+	Short:   "stream a consistent snapshot of a database from a running server",
+	Long:    `download a snapshot of data or stat database from a running server and validate its bucket layout`,
+	Example: "KYBER_ENV=dev ./cmd backup --db data --out data.db",
+	Run:     backupStart,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:     "restore",
+	Short:   "restore a database from a backup, the server must be stopped",
+	Long:    `validate bucket layout of a backup and swap it in place of the data or stat database of KYBER_ENV, the current database is kept next to it`,
+	Example: "KYBER_ENV=dev ./cmd restore --db data --from data.db",
+	Run:     restoreStart,
+}
+
+func init() {
+	backupCmd.Flags().StringVar(&backupURL, "url", "http://127.0.0.1:8000", "base URL of the running server")
+	backupCmd.Flags().StringVar(&backupDB, "db", "data", "database to back up, data or stat")
+	backupCmd.Flags().StringVar(&backupOut, "out", "", "output file, default to <db>-<timepoint>.db")
+	RootCmd.AddCommand(backupCmd)
+
+	restoreCmd.Flags().StringVar(&backupDB, "db", "data", "database to restore, data or stat")
+	restoreCmd.Flags().StringVar(&restoreFrom, "from", "", "backup file to restore from")
+	restoreCmd.MarkFlagRequired("from")
+	RootCmd.AddCommand(restoreCmd)
+}
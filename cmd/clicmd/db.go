@@ -0,0 +1,401 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/accounting"
+	"github.com/KyberNetwork/reserve-data/cmd/configuration"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/KyberNetwork/reserve-data/migration"
+	statstorage "github.com/KyberNetwork/reserve-data/stat/storage"
+	"github.com/boltdb/bolt"
+	"github.com/spf13/cobra"
+)
+
+// decoders create a value of the real type stored in each known bucket.
+// Nested buckets are named by their path joined with "/".
+var decoders = map[string]func() interface{}{
+	storage.PRICE_BUCKET:            func() interface{} { return &common.AllPriceEntry{} },
+	storage.RATE_BUCKET:             func() interface{} { return &common.AllRateEntry{} },
+	storage.ACTIVITY_BUCKET:         func() interface{} { return &common.ActivityRecord{} },
+	storage.PENDING_ACTIVITY_BUCKET: func() interface{} { return &common.ActivityRecord{} },
+	storage.EXPIRED_ACTIVITY_BUCKET: func() interface{} { return &common.ActivityRecord{} },
+	storage.AUTH_DATA_BUCKET:        func() interface{} { return &common.AuthDataSnapshot{} },
+	storage.METRIC_BUCKET:           func() interface{} { return &metric.MetricEntry{} },
+	storage.METRIC_TARGET_QUANTITY:  func() interface{} { return &metric.TokenTargetQty{} },
+	storage.PENDING_TARGET_QUANTITY: func() interface{} { return &metric.TokenTargetQty{} },
+	storage.ENABLE_REBALANCE:        func() interface{} { return &metric.RebalanceControl{} },
+	storage.SETRATE_CONTROL:         func() interface{} { return &metric.SetrateControl{} },
+	storage.PENDING_PWI_EQUATION:    func() interface{} { return &metric.PWIEquation{} },
+	storage.PWI_EQUATION:            func() interface{} { return &metric.PWIEquation{} },
+	storage.RECONCILIATION_BUCKET:   func() interface{} { return &common.ReconciliationReport{} },
+	storage.ACCOUNTING_BUCKET:       func() interface{} { return &accounting.Snapshot{} },
+	storage.RATE_HEALTH_BUCKET:      func() interface{} { return &common.RateHealth{} },
+	storage.PENDING_TRADE_SWITCH:    func() interface{} { return &metric.TradeSwitch{} },
+	storage.PENDING_STEP_FUNCTIONS:  func() interface{} { return &metric.PendingStepFunctions{} },
+	storage.TOKEN_LISTING:           func() interface{} { return &metric.TokenListing{} },
+	statstorage.LOG_BUCKET:          func() interface{} { return &common.TradeLog{} },
+}
+
+// migrations are the schema migrations of each database
+var migrations = map[string][]migration.Migration{
+	"data": storage.Migrations,
+	"stat": statstorage.Migrations,
+}
+
+var dbName string
+var dbFile string
+var dbBucket string
+var dbLimit int
+var dbActivityIDs []string
+var dbApply bool
+
+func dbPath() string {
+	if dbFile != "" {
+		return dbFile
+	}
+	kyberENV := os.Getenv("KYBER_ENV")
+	if kyberENV == "" {
+		kyberENV = "dev"
+	}
+	dataPath, statPath := configuration.GetStoragePaths(kyberENV)
+	if dbName == "stat" {
+		return statPath
+	}
+	return dataPath
+}
+
+// openDB opens the database file directly, it fails instead of waiting
+// when the file is locked by a running server
+func openDB(readOnly bool) *bolt.DB {
+	path := dbPath()
+	if _, err := os.Stat(path); err != nil {
+		log.Fatalf("Couldn't open %s: %s", path, err)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: readOnly, Timeout: time.Second})
+	if err != nil {
+		log.Fatalf("Couldn't open %s, stop the server or inspect a backup instead: %s", path, err)
+	}
+	return db
+}
+
+// findBucket returns the bucket of a "/" separated path
+func findBucket(tx *bolt.Tx, path string) *bolt.Bucket {
+	names := strings.Split(path, "/")
+	b := tx.Bucket([]byte(names[0]))
+	for _, name := range names[1:] {
+		if b == nil {
+			return nil
+		}
+		b = b.Bucket([]byte(name))
+	}
+	return b
+}
+
+// decodeKey shows timepoint keys and activity keys as numbers, other keys
+// as text
+func decodeKey(k []byte) string {
+	if len(k) == 8 || len(k) == 64 {
+		return strconv.FormatUint(binary.BigEndian.Uint64(k[:8]), 10)
+	}
+	return string(k)
+}
+
+func decodeValue(bucket string, v []byte) interface{} {
+	newValue, known := decoders[bucket]
+	if !known && strings.HasPrefix(bucket, statstorage.TRADE_STATS_BUCKET+"/") {
+		newValue, known = func() interface{} { return &common.TradeStats{} }, true
+	}
+	if !known && strings.HasPrefix(bucket, storage.TRADE_HISTORY+"/") {
+		newValue, known = func() interface{} { return &common.TradeHistory{} }, true
+	}
+	if !known && strings.HasPrefix(bucket, storage.CANDLE_BUCKET+"/") {
+		newValue, known = func() interface{} { return &common.Candle{} }, true
+	}
+	if !known {
+		return string(v)
+	}
+	value := newValue()
+	if err := json.Unmarshal(v, value); err != nil {
+		return fmt.Sprintf("undecodable value (%s): %s", err, string(v))
+	}
+	return value
+}
+
+func printBuckets(prefix string, name []byte, b *bolt.Bucket) {
+	path := prefix + string(name)
+	stats := b.Stats()
+	fmt.Printf("%-60s keys: %-10d size: %d bytes\n", path, stats.KeyN, stats.BranchInuse+stats.LeafInuse+stats.InlineBucketInuse)
+	b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			printBuckets(path+"/", k, b.Bucket(k))
+		}
+		return nil
+	})
+}
+
+func dbBucketsStart(cmd *cobra.Command, args []string) {
+	db := openDB(true)
+	defer db.Close()
+	db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			printBuckets("", name, b)
+			return nil
+		})
+	})
+}
+
+func dbDumpStart(cmd *cobra.Command, args []string) {
+	db := openDB(true)
+	defer db.Close()
+	err := db.View(func(tx *bolt.Tx) error {
+		b := findBucket(tx, dbBucket)
+		if b == nil {
+			return errors.New(fmt.Sprintf("Bucket %s doesn't exist", dbBucket))
+		}
+		// newest records first
+		c := b.Cursor()
+		count := 0
+		for k, v := c.Last(); k != nil && (dbLimit <= 0 || count < dbLimit); k, v = c.Prev() {
+			if v == nil {
+				continue
+			}
+			data, err := json.MarshalIndent(map[string]interface{}{
+				"key":   decodeKey(k),
+				"value": decodeValue(dbBucket, v),
+			}, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Dumping %s failed: %s", dbBucket, err)
+	}
+}
+
+// backupBeforeWrite keeps a snapshot of the database next to it before
+// any repair is applied
+func backupBeforeWrite(db *bolt.DB) {
+	path := fmt.Sprintf("%s.before-db-%d", db.Path(), common.GetTimepoint())
+	_, err := writeBackupFile(path, func(w io.Writer) (int64, error) {
+		var n int64
+		err := db.View(func(tx *bolt.Tx) error {
+			var err error
+			n, err = tx.WriteTo(w)
+			return err
+		})
+		return n, err
+	})
+	if err != nil {
+		log.Fatalf("Couldn't back up database before writing, nothing is changed: %s", err)
+	}
+	log.Printf("Database is backed up to %s", path)
+}
+
+// changePendingActivities applies change to each given pending activity,
+// activities prepare fails on are reported and skipped. Without --apply it
+// only prints what would be changed.
+func changePendingActivities(action string, prepare func(record *common.ActivityRecord) error, change func(tx *bolt.Tx, key []byte, record common.ActivityRecord) error) {
+	if len(dbActivityIDs) == 0 {
+		log.Fatalf("At least one --id is required")
+	}
+	dbName = "data"
+	db := openDB(!dbApply)
+	defer db.Close()
+	if dbApply {
+		backupBeforeWrite(db)
+	}
+	apply := func(tx *bolt.Tx) error {
+		pb := tx.Bucket([]byte(storage.PENDING_ACTIVITY_BUCKET))
+		for _, rawID := range dbActivityIDs {
+			id, err := common.StringToActivityID(rawID)
+			if err != nil {
+				return err
+			}
+			key := id.ToBytes()
+			v := pb.Get(key[:])
+			if v == nil {
+				return errors.New(fmt.Sprintf("Activity %s is not pending", rawID))
+			}
+			record := common.ActivityRecord{}
+			if err = json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			if record.ID != id {
+				return errors.New(fmt.Sprintf("Pending activity at %s is %s, not %s", decodeKey(key[:]), record.ID, rawID))
+			}
+			estatus, mstatus := record.ExchangeStatus, record.MiningStatus
+			if prepare != nil {
+				if err = prepare(&record); err != nil {
+					fmt.Printf("skip %s activity %s on %s: %s\n", record.Action, record.ID, record.Destination, err)
+					continue
+				}
+			}
+			fmt.Printf("%s %s activity %s on %s (exchange status: %q, mining status: %q)\n",
+				action, record.Action, record.ID, record.Destination, estatus, mstatus)
+			if dbApply {
+				if err = change(tx, key[:], record); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	var err error
+	if dbApply {
+		err = db.Update(apply)
+	} else {
+		err = db.View(apply)
+		fmt.Println("Dry run, nothing is changed. Use --apply to write.")
+	}
+	if err != nil {
+		log.Fatalf("Couldn't %s pending activities, nothing is changed: %s", action, err)
+	}
+}
+
+func dbDeletePendingStart(cmd *cobra.Command, args []string) {
+	changePendingActivities("delete", nil, func(tx *bolt.Tx, key []byte, record common.ActivityRecord) error {
+		// the record stays in activities bucket for audit
+		return tx.Bucket([]byte(storage.PENDING_ACTIVITY_BUCKET)).Delete(key)
+	})
+}
+
+func dbMovePendingStart(cmd *cobra.Command, args []string) {
+	expire := func(record *common.ActivityRecord) error {
+		return record.Expire(common.OperatorSource, common.GetTimestamp())
+	}
+	changePendingActivities("move", expire, func(tx *bolt.Tx, key []byte, record common.ActivityRecord) error {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if err = tx.Bucket([]byte(storage.ACTIVITY_BUCKET)).Put(key, data); err != nil {
+			return err
+		}
+		if err = tx.Bucket([]byte(storage.EXPIRED_ACTIVITY_BUCKET)).Put(key, data); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(storage.PENDING_ACTIVITY_BUCKET)).Delete(key)
+	})
+}
+
+func dbMigrateStart(cmd *cobra.Command, args []string) {
+	dbMigrations, found := migrations[dbName]
+	if !found {
+		log.Fatalf("Unknown database %s, it must be data or stat", dbName)
+	}
+	db := openDB(!dbApply)
+	defer db.Close()
+	var pending []migration.Migration
+	var err error
+	db.View(func(tx *bolt.Tx) error {
+		fmt.Printf("%s: schema version %d, latest version %d\n",
+			db.Path(), migration.SchemaVersion(tx), migration.Latest(dbMigrations))
+		pending, err = migration.Pending(tx, dbMigrations)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Couldn't migrate %s: %s", db.Path(), err)
+	}
+	for _, m := range pending {
+		fmt.Printf("pending migration %d: %s\n", m.Version, m.Description)
+	}
+	if len(pending) == 0 {
+		fmt.Println("Schema is up to date.")
+		return
+	}
+	if !dbApply {
+		fmt.Println("Dry run, nothing is changed. Use --apply to migrate.")
+		return
+	}
+	backupBeforeWrite(db)
+	version, err := migration.Run(db, dbMigrations)
+	if err != nil {
+		log.Fatalf("Couldn't migrate %s: %s", db.Path(), err)
+	}
+	fmt.Printf("Migrated %s to schema version %d\n", db.Path(), version)
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "inspect and repair bolt databases",
+	Long: `inspect data or stat database of KYBER_ENV (or any bolt file given by --file) and repair pending activities or migrate the schema.
+The server holds a lock on its databases so it must be stopped, or a backup can be inspected instead.`,
+}
+
+var dbBucketsCmd = &cobra.Command{
+	Use:     "buckets",
+	Short:   "list buckets with their key counts and sizes",
+	Example: "KYBER_ENV=dev ./cmd db buckets --db stat",
+	Run:     dbBucketsStart,
+}
+
+var dbDumpCmd = &cobra.Command{
+	Use:     "dump",
+	Short:   "print records of a bucket as JSON, newest first",
+	Example: "KYBER_ENV=dev ./cmd db dump --bucket pending_activities --limit 10",
+	Run:     dbDumpStart,
+}
+
+var dbPendingCmd = &cobra.Command{
+	Use:   "pending",
+	Short: "delete or move pending activities, dry run unless --apply is given",
+}
+
+var dbDeletePendingCmd = &cobra.Command{
+	Use:     "delete",
+	Short:   "remove activities from pending activities, their records are kept",
+	Example: "KYBER_ENV=dev ./cmd db pending delete --id '1512189195897392628|1872552297_OMGETH' --apply",
+	Run:     dbDeletePendingStart,
+}
+
+var dbMovePendingCmd = &cobra.Command{
+	Use:     "move",
+	Short:   "expire activities and move them from pending to expired activities, activities which can't be expired are skipped",
+	Example: "KYBER_ENV=dev ./cmd db pending move --id '1512189195897392628|1872552297_OMGETH' --apply",
+	Run:     dbMovePendingStart,
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "show schema version and apply pending migrations, dry run unless --apply is given",
+	Long: `show schema version of the database and its pending migrations. With --apply the database is backed up next to it
+and the migrations are applied in order. The server also applies pending migrations when it starts.`,
+	Example: "KYBER_ENV=dev ./cmd db migrate --db data --apply",
+	Run:     dbMigrateStart,
+}
+
+func init() {
+	dbCmd.PersistentFlags().StringVar(&dbName, "db", "data", "database to inspect, data or stat")
+	dbCmd.PersistentFlags().StringVar(&dbFile, "file", "", "bolt file to inspect, default to the database of KYBER_ENV")
+	dbDumpCmd.Flags().StringVar(&dbBucket, "bucket", "", "bucket to dump, nested buckets are joined by /")
+	dbDumpCmd.MarkFlagRequired("bucket")
+	dbDumpCmd.Flags().IntVar(&dbLimit, "limit", 20, "max number of records to print, 0 means all")
+	dbPendingCmd.PersistentFlags().StringSliceVar(&dbActivityIDs, "id", []string{}, "activity id, can be repeated")
+	dbPendingCmd.PersistentFlags().BoolVar(&dbApply, "apply", false, "write the changes, a backup is taken first")
+
+	dbMigrateCmd.Flags().BoolVar(&dbApply, "apply", false, "apply the migrations, a backup is taken first")
+
+	dbPendingCmd.AddCommand(dbDeletePendingCmd)
+	dbPendingCmd.AddCommand(dbMovePendingCmd)
+	dbCmd.AddCommand(dbBucketsCmd)
+	dbCmd.AddCommand(dbDumpCmd)
+	dbCmd.AddCommand(dbPendingCmd)
+	dbCmd.AddCommand(dbMigrateCmd)
+	RootCmd.AddCommand(dbCmd)
+}
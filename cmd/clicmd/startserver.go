@@ -8,8 +8,10 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/KyberNetwork/reserve-data"
+	"github.com/KyberNetwork/reserve-data/accounting"
 	"github.com/KyberNetwork/reserve-data/blockchain"
 	"github.com/KyberNetwork/reserve-data/blockchain/nonce"
 	"github.com/KyberNetwork/reserve-data/cmd/configuration"
@@ -17,7 +19,10 @@ import (
 	"github.com/KyberNetwork/reserve-data/core"
 	"github.com/KyberNetwork/reserve-data/data"
 	"github.com/KyberNetwork/reserve-data/data/fetcher"
+	"github.com/KyberNetwork/reserve-data/execution"
+	"github.com/KyberNetwork/reserve-data/hedging"
 	"github.com/KyberNetwork/reserve-data/http"
+	"github.com/KyberNetwork/reserve-data/router"
 	"github.com/KyberNetwork/reserve-data/stat"
 	ethereum "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -33,6 +38,9 @@ var endpointOW string
 var base_url, auth_url string
 var enableStat bool
 var noCore bool
+var backupDir string
+var backupInterval time.Duration
+var backupKeep int
 
 func loadTimestamp(path string) []uint64 {
 	raw, err := ioutil.ReadFile(path)
@@ -101,6 +109,10 @@ func serverStart(cmd *cobra.Command, args []string) {
 	var rData reserve.ReserveData
 	var rCore reserve.ReserveCore
 	var rStat reserve.ReserveStats
+	var rAccounting reserve.ReserveAccounting
+	var rExecution reserve.ReserveExecution
+	var rRouter reserve.ReserveRouter
+	var rHedging reserve.ReserveHedging
 
 	//set static field supportExchange from common...
 	for _, ex := range config.Exchanges {
@@ -119,6 +131,14 @@ func serverStart(cmd *cobra.Command, args []string) {
 		for _, ex := range config.FetcherExchanges {
 			dataFetcher.AddExchange(ex)
 		}
+		dataFetcher.SetActivityTTLs(config.ActivityTTLs)
+		dataFetcher.SetMetricDepths(config.MetricDepths)
+	}
+
+	// eth usd rate is shared by stat and accounting of core
+	var ethUSDRate stat.EthUSDRate
+	if enableStat || !noCore {
+		ethUSDRate = stat.NewCMCEthUSDRate()
 	}
 
 	if enableStat {
@@ -128,7 +148,7 @@ func serverStart(cmd *cobra.Command, args []string) {
 		}
 		statFetcher = stat.NewFetcher(
 			config.StatFetcherStorage,
-			stat.NewCMCEthUSDRate(),
+			ethUSDRate,
 			config.StatFetcherRunner,
 			deployBlock,
 		)
@@ -179,9 +199,33 @@ func serverStart(cmd *cobra.Command, args []string) {
 		bc.AddOldBurners(ethereum.HexToAddress("0x4E89bc8484B2c454f2F7B25b612b648c45e14A8e"))
 	}
 
+	bc.SetRateTolerance(config.SetRateTolerance)
+	bc.SetRateHistory(config.DataStorage)
+	if config.AlerterSigner != nil {
+		bc.SetAlerterSigner(config.AlerterSigner, nonce.NewTimeWindow(infura, config.AlerterSigner))
+	}
+
 	for _, token := range config.SupportedTokens {
 		bc.AddToken(token)
 	}
+	// tokens listed while running are not in the setting file
+	if listings, err := config.MetricStorage.GetListedTokens(); err != nil {
+		log.Printf("Can't load listed tokens: %s", err)
+	} else {
+		configTokens := config.MapTokens()
+		for _, listing := range listings {
+			token := listing.Data.Token()
+			if _, found := configTokens[token.ID]; found {
+				continue
+			}
+			bc.AddToken(token)
+			if err := core.RegisterListingOnExchanges(listing.Data); err != nil {
+				log.Printf("Can't register listed token %s on exchanges: %s", token.ID, err)
+			}
+			common.AddSupportedToken(token)
+			log.Printf("Loaded listed token %s", token.ID)
+		}
+	}
 	err = bc.LoadAndSetTokenIndices()
 	if err != nil {
 		fmt.Printf("Can't load and set token indices: %s\n", err)
@@ -194,6 +238,46 @@ func serverStart(cmd *cobra.Command, args []string) {
 			)
 			rData.Run()
 			rCore = core.NewReserveCore(bc, config.ActivityStorage, config.ReserveAddress)
+			rAccounting = accounting.NewAccounting(
+				config.AccountingStorage,
+				config.StatStorage,
+				ethUSDRate,
+				config.ReserveAddress,
+			)
+			rAccounting.Run()
+			rExecution = execution.NewExecution(
+				rCore,
+				config.ExecutionStorage,
+				config.MetricStorage,
+				config.ExecutionExchanges,
+			)
+			rExecution.Run()
+			rRouter = router.NewRouter(
+				config.RouterStorage,
+				rCore,
+				config.Exchanges,
+			)
+			if config.Hedging.Exchange != "" {
+				if exchange, found := common.SupportedExchanges[common.ExchangeID(config.Hedging.Exchange)]; found {
+					h, err := hedging.NewHedging(
+						config.Hedging,
+						exchange,
+						rCore,
+						config.DataStorage,
+						config.StatStorage,
+						config.MetricStorage,
+						config.ReserveAddress,
+					)
+					if err != nil {
+						log.Printf("Hedging is disabled: %s", err)
+					} else {
+						h.Run()
+						rHedging = h
+					}
+				} else {
+					log.Printf("Hedging exchange %s is not supported, hedging is disabled", config.Hedging.Exchange)
+				}
+			}
 		}
 		if enableStat {
 			statFetcher.SetBlockchain(bc)
@@ -203,9 +287,19 @@ func serverStart(cmd *cobra.Command, args []string) {
 			)
 			rStat.Run()
 		}
+		if backupInterval > 0 {
+			backups := map[string]backupFunc{}
+			if rData != nil {
+				backups["data"] = rData.Backup
+			}
+			if rStat != nil {
+				backups["stat"] = rStat.Backup
+			}
+			go runScheduledBackup(backupDir, backupInterval, backupKeep, backups)
+		}
 		servPortStr := fmt.Sprintf(":%d", servPort)
 		server := http.NewHTTPServer(
-			rData, rCore, rStat,
+			rData, rCore, rStat, rAccounting, rExecution, rRouter, rHedging,
 			config.MetricStorage,
 			servPortStr,
 			config.EnableAuthentication,
@@ -234,5 +328,8 @@ func init() {
 	startServer.PersistentFlags().StringVar(&base_url, "base_url", "http://127.0.0.1", "base_url for authenticated enpoint")
 	startServer.Flags().BoolVarP(&enableStat, "enable-stat", "", false, "enable stat related fetcher and api, event logs will not be fetched")
 	startServer.Flags().BoolVarP(&noCore, "no-core", "", false, "disable core related fetcher and api, this should be used only when we want to run an independent stat server")
+	startServer.Flags().StringVar(&backupDir, "backup-dir", "/go/src/github.com/KyberNetwork/reserve-data/cmd/backup", "directory of scheduled backups")
+	startServer.Flags().DurationVar(&backupInterval, "backup-interval", 0, "interval of scheduled backups, disabled if it is 0")
+	startServer.Flags().IntVar(&backupKeep, "backup-keep", 7, "number of scheduled backups to keep for each database")
 	RootCmd.AddCommand(startServer)
 }
@@ -1,6 +1,9 @@
 package configuration
 
 import (
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/accounting"
 	"github.com/KyberNetwork/reserve-data/blockchain"
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/KyberNetwork/reserve-data/core"
@@ -9,8 +12,10 @@ import (
 	"github.com/KyberNetwork/reserve-data/exchange/binance"
 	"github.com/KyberNetwork/reserve-data/exchange/bittrex"
 	"github.com/KyberNetwork/reserve-data/exchange/huobi"
+	"github.com/KyberNetwork/reserve-data/execution"
 	"github.com/KyberNetwork/reserve-data/http"
 	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/KyberNetwork/reserve-data/router"
 	"github.com/KyberNetwork/reserve-data/stat"
 	ethereum "github.com/ethereum/go-ethereum/common"
 )
@@ -32,13 +37,19 @@ type Config struct {
 	FetcherStorage     fetcher.Storage
 	StatFetcherStorage stat.Storage
 	MetricStorage      metric.MetricStorage
-
-	FetcherRunner     fetcher.FetcherRunner
-	StatFetcherRunner stat.FetcherRunner
-	FetcherExchanges  []fetcher.Exchange
-	Exchanges         []common.Exchange
-	BlockchainSigner  blockchain.Signer
-	DepositSigner     blockchain.Signer
+	AccountingStorage  accounting.Storage
+	ExecutionStorage   execution.Storage
+	RouterStorage      router.Storage
+
+	FetcherRunner      fetcher.FetcherRunner
+	StatFetcherRunner  stat.FetcherRunner
+	FetcherExchanges   []fetcher.Exchange
+	ExecutionExchanges []execution.Exchange
+	Exchanges          []common.Exchange
+	BlockchainSigner   blockchain.Signer
+	DepositSigner      blockchain.Signer
+	// AlerterSigner is nil when no alerter keystore is configured
+	AlerterSigner blockchain.Signer
 
 	EnableAuthentication bool
 	AuthEngine           http.Authentication
@@ -56,6 +67,12 @@ type Config struct {
 	WhitelistAddress ethereum.Address
 
 	ChainType string
+
+	ActivityTTLs map[string]time.Duration
+	MetricDepths map[string]float64
+	Hedging      common.HedgingConfig
+
+	SetRateTolerance float64
 }
 
 func (self *Config) MapTokens() map[string]common.Token {
@@ -1,6 +1,7 @@
 package configuration
 
 import (
+	"log"
 	"os"
 	"strings"
 	"sync"
@@ -11,6 +12,7 @@ import (
 	"github.com/KyberNetwork/reserve-data/exchange/binance"
 	"github.com/KyberNetwork/reserve-data/exchange/bittrex"
 	"github.com/KyberNetwork/reserve-data/exchange/huobi"
+	"github.com/KyberNetwork/reserve-data/execution"
 	"github.com/KyberNetwork/reserve-data/signer"
 )
 
@@ -46,6 +48,20 @@ func getHuobiInterface(kyberENV string) huobi.Interface {
 	return envInterface
 }
 
+// getExchangePairs returns pairs of an exchange declared in the setting
+// file, or its tokens against ETH if none is declared. It panics on an
+// unknown token as the exchange can't be used.
+func getExchangePairs(addressConfig common.AddressConfig, exchange string) []common.TokenPair {
+	pairs, err := addressConfig.GetExchangePairs(exchange)
+	if err != nil {
+		panic(err)
+	}
+	if len(pairs) == 0 {
+		log.Printf("No pair of %s is declared in exchange_pairs of setting file nor derived from its tokens", exchange)
+	}
+	return pairs
+}
+
 func NewExchangePool(
 	feeConfig common.ExchangeFeesConfig,
 	addressConfig common.AddressConfig,
@@ -59,7 +75,7 @@ func NewExchangePool(
 		switch exparam {
 		case "bittrex":
 			endpoint := bittrex.NewBittrexEndpoint(signer, getBittrexInterface(kyberENV))
-			bit := exchange.NewBittrex(addressConfig.Exchanges["bittrex"], feeConfig.Exchanges["bittrex"], endpoint, bittrexStorage)
+			bit := exchange.NewBittrex(addressConfig.Exchanges["bittrex"], feeConfig.Exchanges["bittrex"], getExchangePairs(addressConfig, "bittrex"), endpoint, bittrexStorage)
 			wait := sync.WaitGroup{}
 			for tokenID, addr := range addressConfig.Exchanges["bittrex"] {
 				wait.Add(1)
@@ -70,7 +86,7 @@ func NewExchangePool(
 			exchanges[bit.ID()] = bit
 		case "binance":
 			endpoint := binance.NewBinanceEndpoint(signer, getBinanceInterface(kyberENV))
-			bin := exchange.NewBinance(addressConfig.Exchanges["binance"], feeConfig.Exchanges["binance"], endpoint)
+			bin := exchange.NewBinance(addressConfig.Exchanges["binance"], feeConfig.Exchanges["binance"], getExchangePairs(addressConfig, "binance"), endpoint)
 			wait := sync.WaitGroup{}
 			for tokenID, addr := range addressConfig.Exchanges["binance"] {
 				wait.Add(1)
@@ -80,7 +96,7 @@ func NewExchangePool(
 			bin.UpdatePairsPrecision()
 			exchanges[bin.ID()] = bin
 		case "huobi":
-			huobi := exchange.NewHuobi(huobi.NewHuobiEndpoint(signer, getHuobiInterface(kyberENV)))
+			huobi := exchange.NewHuobi(getExchangePairs(addressConfig, "huobi"), huobi.NewHuobiEndpoint(signer, getHuobiInterface(kyberENV)))
 			wait := sync.WaitGroup{}
 			for tokenID, addr := range addressConfig.Exchanges["huobi"] {
 				wait.Add(1)
@@ -109,3 +125,14 @@ func (self *ExchangePool) CoreExchanges() []common.Exchange {
 	}
 	return result
 }
+
+// ExecutionExchanges returns exchanges parent orders can be executed on.
+func (self *ExchangePool) ExecutionExchanges() []execution.Exchange {
+	result := []execution.Exchange{}
+	for _, ex := range self.Exchanges {
+		if executionExchange, ok := ex.(execution.Exchange); ok {
+			result = append(result, executionExchange)
+		}
+	}
+	return result
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/KyberNetwork/reserve-data/blockchain"
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/KyberNetwork/reserve-data/data/fetcher"
 	"github.com/KyberNetwork/reserve-data/data/fetcher/http_runner"
@@ -63,6 +64,29 @@ func GetConfigPaths(kyberENV string) SettingPaths {
 	}
 }
 
+// GetStoragePaths returns paths of data and stat databases of the env
+func GetStoragePaths(kyberENV string) (string, string) {
+	setPath := GetConfigPaths(kyberENV)
+	return setPath.dataStoragePath, setPath.statStoragePath
+}
+
+// GetAuthEngine builds authentication engine of the env without opening
+// any database, so it can be used by cli commands while server is running
+func GetAuthEngine(kyberENV string) http.KNAuthentication {
+	setPath := GetConfigPaths(kyberENV)
+	fileSigner, _ := signer.NewFileSigner(setPath.signerPath)
+	return newAuthEngine(fileSigner)
+}
+
+func newAuthEngine(fileSigner *signer.FileSigner) http.KNAuthentication {
+	return http.KNAuthentication{
+		fileSigner.KNSecret,
+		fileSigner.KNReadOnly,
+		fileSigner.KNConfiguration,
+		fileSigner.KNConfirmConf,
+	}
+}
+
 // GetConfig: load and set all config with preset params and customize param depends on env
 // This is to generalized all the getconfig function.
 func GetConfig(kyberENV string, authEnbl bool, endpointOW string) *Config {
@@ -96,6 +120,14 @@ func GetConfig(kyberENV string, authEnbl bool, endpointOW string) *Config {
 		}
 	}
 
+	activityTTLs := map[string]time.Duration{}
+	for action, ttl := range common.DefaultActivityTTLs {
+		activityTTLs[action] = ttl
+	}
+	for action, seconds := range addressConfig.ActivityTTLs {
+		activityTTLs[action] = time.Duration(seconds) * time.Second
+	}
+
 	dataStorage, err := storage.NewBoltStorage(setPath.dataStoragePath)
 	if err != nil {
 		panic(err)
@@ -112,11 +144,17 @@ func GetConfig(kyberENV string, authEnbl bool, endpointOW string) *Config {
 		fetcherRunner = http_runner.NewHttpRunner(8001)
 		statFetcherRunner = http_runner.NewHttpRunner(8002)
 	} else {
-		fetcherRunner = fetcher.NewTickerRunner(7*time.Second, 5*time.Second, 3*time.Second, 5*time.Second, 30*time.Second)
-		statFetcherRunner = fetcher.NewTickerRunner(7*time.Second, 5*time.Second, 3*time.Second, 5*time.Second, 30*time.Second)
+		fetcherRunner = fetcher.NewTickerRunner(7*time.Second, 5*time.Second, 3*time.Second, 5*time.Second, 30*time.Second, time.Minute)
+		statFetcherRunner = fetcher.NewTickerRunner(7*time.Second, 5*time.Second, 3*time.Second, 5*time.Second, 30*time.Second, time.Minute)
 	}
 
 	fileSigner, depositSigner := signer.NewFileSigner(setPath.signerPath)
+	var alerterSigner blockchain.Signer
+	if alerter, err := signer.NewAlerterSigner(*fileSigner); err != nil {
+		log.Printf("Trade kill switch is disabled: %s", err)
+	} else {
+		alerterSigner = alerter
+	}
 
 	exchangePool := NewExchangePool(feeConfig, addressConfig, fileSigner, dataStorage, kyberENV)
 	//exchangePool := exchangePoolFunc(feeConfig, addressConfig, fileSigner, storage)
@@ -133,14 +171,7 @@ func GetConfig(kyberENV string, authEnbl bool, endpointOW string) *Config {
 	}
 
 	bkendpoints := setPath.bkendpoints
-	var hmac512auth http.KNAuthentication
-
-	hmac512auth = http.KNAuthentication{
-		fileSigner.KNSecret,
-		fileSigner.KNReadOnly,
-		fileSigner.KNConfiguration,
-		fileSigner.KNConfirmConf,
-	}
+	hmac512auth := newAuthEngine(fileSigner)
 
 	if !authEnbl {
 		log.Printf("\nWARNING: No authentication mode\n")
@@ -155,13 +186,18 @@ func GetConfig(kyberENV string, authEnbl bool, endpointOW string) *Config {
 		FetcherStorage:          dataStorage,
 		StatFetcherStorage:      statStorage,
 		MetricStorage:           dataStorage,
+		AccountingStorage:       dataStorage,
+		ExecutionStorage:        dataStorage,
+		RouterStorage:           dataStorage,
 		FetcherRunner:           fetcherRunner,
 		StatFetcherRunner:       statFetcherRunner,
 		FetcherExchanges:        exchangePool.FetcherExchanges(),
+		ExecutionExchanges:      exchangePool.ExecutionExchanges(),
 		Exchanges:               exchangePool.CoreExchanges(),
 		BlockchainSigner:        fileSigner,
 		EnableAuthentication:    authEnbl,
 		DepositSigner:           depositSigner,
+		AlerterSigner:           alerterSigner,
 		AuthEngine:              hmac512auth,
 		EthereumEndpoint:        endpoint,
 		BackupEthereumEndpoints: bkendpoints,
@@ -173,5 +209,9 @@ func GetConfig(kyberENV string, authEnbl bool, endpointOW string) *Config {
 		NetworkAddress:          networkAddr,
 		WhitelistAddress:        whitelistAddr,
 		ChainType:               chainType,
+		ActivityTTLs:            activityTTLs,
+		MetricDepths:            addressConfig.MetricDepths,
+		Hedging:                 addressConfig.Hedging,
+		SetRateTolerance:        addressConfig.SetRateTolerance,
 	}
 }
@@ -0,0 +1,314 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ActivityState is a status of one side (exchange or blockchain) of an
+// activity. It is stored as plain string in ActivityRecord to keep the
+// persisted records and the API backward compatible.
+type ActivityState string
+
+const (
+	ActivityStateNone      ActivityState = ""
+	ActivityStateSubmitted ActivityState = "submitted"
+	ActivityStatePending   ActivityState = "pending"
+	ActivityStateDone      ActivityState = "done"
+	ActivityStateMined     ActivityState = "mined"
+	ActivityStateFailed    ActivityState = "failed"
+	ActivityStateLost      ActivityState = "lost"
+	// ActivityStateExpired marks a side which didn't reach a final state
+	// within the TTL of its action. It needs to be resolved by an operator.
+	ActivityStateExpired ActivityState = "expired"
+	// ActivityStateCancelled marks a parent order cancelled by an operator
+	// before it is fully executed.
+	ActivityStateCancelled ActivityState = "cancelled"
+)
+
+const (
+	ExchangeSide   string = "exchange"
+	BlockchainSide string = "blockchain"
+	// sources of transitions which are not reported by exchanges or chain
+	TimeoutSource  string = "timeout"
+	OperatorSource string = "operator"
+)
+
+type transitions map[ActivityState][]ActivityState
+
+// ActivityLifecycle describes allowed status transitions of one action,
+// separately for its exchange side and its blockchain side.
+type ActivityLifecycle struct {
+	Exchange   transitions
+	Blockchain transitions
+}
+
+// ActivityLifecycles maps each action to its lifecycle.
+// Terminal states have no outgoing transitions.
+var ActivityLifecycles = map[string]ActivityLifecycle{
+	"trade": ActivityLifecycle{
+		Exchange: transitions{
+			ActivityStateNone:      {ActivityStateSubmitted, ActivityStateDone, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateSubmitted: {ActivityStateDone, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateExpired:   {ActivityStateDone, ActivityStateFailed},
+		},
+		Blockchain: transitions{},
+	},
+	"deposit": ActivityLifecycle{
+		Exchange: transitions{
+			ActivityStateNone:    {ActivityStatePending, ActivityStateDone, ActivityStateFailed, ActivityStateExpired},
+			ActivityStatePending: {ActivityStateDone, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateExpired: {ActivityStateDone, ActivityStateFailed},
+		},
+		Blockchain: transitions{
+			ActivityStateNone:      {ActivityStateSubmitted, ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateSubmitted: {ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateExpired:   {ActivityStateMined, ActivityStateFailed},
+		},
+	},
+	"withdraw": ActivityLifecycle{
+		Exchange: transitions{
+			ActivityStateNone:      {ActivityStateSubmitted, ActivityStateDone, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateSubmitted: {ActivityStateDone, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateExpired:   {ActivityStateDone, ActivityStateFailed},
+		},
+		Blockchain: transitions{
+			ActivityStateNone:      {ActivityStateSubmitted, ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateSubmitted: {ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateExpired:   {ActivityStateMined, ActivityStateFailed},
+		},
+	},
+	// parent orders are executed by algorithms as trade activities
+	"parent_order": ActivityLifecycle{
+		Exchange: transitions{
+			ActivityStateNone:      {ActivityStateSubmitted, ActivityStateFailed},
+			ActivityStateSubmitted: {ActivityStateDone, ActivityStateFailed, ActivityStateCancelled},
+		},
+		Blockchain: transitions{},
+	},
+	"set_rates": ActivityLifecycle{
+		Exchange: transitions{},
+		Blockchain: transitions{
+			ActivityStateNone:      {ActivityStateSubmitted, ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateSubmitted: {ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateExpired:   {ActivityStateMined, ActivityStateFailed},
+		},
+	},
+	// trading of the reserve or of a token is disabled or enabled on chain
+	"set_trade_enabled": ActivityLifecycle{
+		Exchange: transitions{},
+		Blockchain: transitions{
+			ActivityStateNone:      {ActivityStateSubmitted, ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateSubmitted: {ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateExpired:   {ActivityStateMined, ActivityStateFailed},
+		},
+	},
+	"set_step_function": ActivityLifecycle{
+		Exchange: transitions{},
+		Blockchain: transitions{
+			ActivityStateNone:      {ActivityStateSubmitted, ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateSubmitted: {ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateExpired:   {ActivityStateMined, ActivityStateFailed},
+		},
+	},
+	// a step of listing a token in the pricing contract
+	"list_token": ActivityLifecycle{
+		Exchange: transitions{},
+		Blockchain: transitions{
+			ActivityStateNone:      {ActivityStateSubmitted, ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateSubmitted: {ActivityStateMined, ActivityStateFailed, ActivityStateExpired},
+			ActivityStateExpired:   {ActivityStateMined, ActivityStateFailed},
+		},
+	},
+}
+
+// DefaultActivityTTLs is the maximum time an activity of each action can
+// stay pending before it is expired. It can be overridden in setting file.
+var DefaultActivityTTLs = map[string]time.Duration{
+	"trade":             24 * time.Hour,
+	"deposit":           6 * time.Hour,
+	"withdraw":          6 * time.Hour,
+	"set_rates":         time.Hour,
+	"set_trade_enabled": time.Hour,
+	"set_step_function": time.Hour,
+	"list_token":        time.Hour,
+}
+
+// ActivityTransition is one status change of an activity
+type ActivityTransition struct {
+	Side      string
+	From      ActivityState
+	To        ActivityState
+	Source    string
+	Timestamp Timestamp
+	Reason    string
+}
+
+func (self transitions) allow(from, to ActivityState) bool {
+	for _, s := range self[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransit returns true if the action's lifecycle allows moving the given
+// side from one state to another.
+func CanTransit(action string, side string, from, to ActivityState) bool {
+	lifecycle, found := ActivityLifecycles[action]
+	if !found {
+		return false
+	}
+	switch side {
+	case ExchangeSide:
+		return lifecycle.Exchange.allow(from, to)
+	case BlockchainSide:
+		return lifecycle.Blockchain.allow(from, to)
+	}
+	return false
+}
+
+func (self *ActivityRecord) transit(side string, current *string, status string, source string, reason string, timestamp Timestamp) error {
+	from := ActivityState(*current)
+	to := ActivityState(status)
+	// empty status means the source has no news about this activity yet
+	if to == ActivityStateNone || to == from {
+		return nil
+	}
+	if !CanTransit(self.Action, side, from, to) {
+		return errors.New(fmt.Sprintf(
+			"Invalid %s status transition of %s activity %s: %s -> %s (source: %s)",
+			side, self.Action, self.ID, from, to, source))
+	}
+	*current = status
+	self.History = append(self.History, ActivityTransition{
+		Side:      side,
+		From:      from,
+		To:        to,
+		Source:    source,
+		Timestamp: timestamp,
+		Reason:    reason,
+	})
+	return nil
+}
+
+// UpdateExchangeStatus moves exchange status of the activity to status
+// and records the transition in its history. Transitions which are not
+// allowed by the lifecycle of the action are rejected and the record
+// is left untouched.
+func (self *ActivityRecord) UpdateExchangeStatus(status string, source string, timestamp Timestamp) error {
+	return self.transit(ExchangeSide, &self.ExchangeStatus, status, source, "", timestamp)
+}
+
+// UpdateMiningStatus is the blockchain side counterpart of
+// UpdateExchangeStatus.
+func (self *ActivityRecord) UpdateMiningStatus(status string, source string, timestamp Timestamp) error {
+	return self.transit(BlockchainSide, &self.MiningStatus, status, source, "", timestamp)
+}
+
+// IsExpired returns true if any side of the activity is expired and
+// needs an operator to resolve it.
+func (self ActivityRecord) IsExpired() bool {
+	return ActivityState(self.ExchangeStatus) == ActivityStateExpired ||
+		ActivityState(self.MiningStatus) == ActivityStateExpired
+}
+
+// IsOverdue returns true if the activity is still pending after the TTL
+// of its action.
+func (self ActivityRecord) IsOverdue(ttls map[string]time.Duration, timepoint uint64) bool {
+	ttl, found := ttls[self.Action]
+	if !found || !self.IsPending() {
+		return false
+	}
+	created := self.Timestamp.ToUint64()
+	return timepoint > created && timepoint-created > uint64(ttl/time.Millisecond)
+}
+
+// Expire moves every unfinished side of the activity to expired state. It
+// returns an error and leaves the record untouched if no side can be
+// expired. Expired activities are not pending anymore so their statuses
+// are not polled again, Resolve is the only way to finish them.
+func (self *ActivityRecord) Expire(source string, timestamp Timestamp) error {
+	expired := false
+	if CanTransit(self.Action, ExchangeSide, ActivityState(self.ExchangeStatus), ActivityStateExpired) {
+		self.transit(ExchangeSide, &self.ExchangeStatus, string(ActivityStateExpired), source, "", timestamp)
+		expired = true
+	}
+	if CanTransit(self.Action, BlockchainSide, ActivityState(self.MiningStatus), ActivityStateExpired) {
+		self.transit(BlockchainSide, &self.MiningStatus, string(ActivityStateExpired), source, "", timestamp)
+		expired = true
+	}
+	if !expired {
+		return errors.New(fmt.Sprintf(
+			"No side of %s activity %s can be expired (exchange status: %s, mining status: %s)",
+			self.Action, self.ID, self.ExchangeStatus, self.MiningStatus))
+	}
+	return nil
+}
+
+// Resolve manually finishes a stuck activity. status must be either "done"
+// or "failed", the reason is kept in the transition history.
+func (self *ActivityRecord) Resolve(status string, reason string, timestamp Timestamp) error {
+	var estate, mstate ActivityState
+	switch ActivityState(status) {
+	case ActivityStateDone:
+		estate, mstate = ActivityStateDone, ActivityStateMined
+	case ActivityStateFailed:
+		estate, mstate = ActivityStateFailed, ActivityStateFailed
+	default:
+		return errors.New(fmt.Sprintf("Activity can only be resolved as %s or %s", ActivityStateDone, ActivityStateFailed))
+	}
+	if reason == "" {
+		return errors.New("Reason is required to resolve an activity")
+	}
+	resolved := false
+	if CanTransit(self.Action, ExchangeSide, ActivityState(self.ExchangeStatus), estate) {
+		self.transit(ExchangeSide, &self.ExchangeStatus, string(estate), OperatorSource, reason, timestamp)
+		resolved = true
+	}
+	if CanTransit(self.Action, BlockchainSide, ActivityState(self.MiningStatus), mstate) {
+		self.transit(BlockchainSide, &self.MiningStatus, string(mstate), OperatorSource, reason, timestamp)
+		resolved = true
+	}
+	if !resolved {
+		return errors.New(fmt.Sprintf("Activity %s is already finished", self.ID))
+	}
+	return nil
+}
+
+// NewActivityRecord creates a record with its initial statuses, the initial
+// statuses are recorded as the first transitions of the record.
+func NewActivityRecord(
+	action string,
+	id ActivityID,
+	destination string,
+	params map[string]interface{}, result map[string]interface{},
+	estatus string,
+	mstatus string,
+	timestamp Timestamp) ActivityRecord {
+
+	record := ActivityRecord{
+		Action:      action,
+		ID:          id,
+		Destination: destination,
+		Params:      params,
+		Result:      result,
+		Timestamp:   timestamp,
+		History:     []ActivityTransition{},
+	}
+	if estatus != "" {
+		record.ExchangeStatus = estatus
+		record.History = append(record.History, ActivityTransition{
+			ExchangeSide, ActivityStateNone, ActivityState(estatus), destination, timestamp, "",
+		})
+	}
+	if mstatus != "" {
+		record.MiningStatus = mstatus
+		record.History = append(record.History, ActivityTransition{
+			BlockchainSide, ActivityStateNone, ActivityState(mstatus), BlockchainSide, timestamp, "",
+		})
+	}
+	return record
+}
@@ -2,7 +2,10 @@ package common
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"sort"
 
 	ethereum "github.com/ethereum/go-ethereum/common"
 )
@@ -16,6 +19,31 @@ type token struct {
 
 type exchange map[string]string
 
+type pairConfig struct {
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+}
+
+// HedgingTokenConfig enables hedging of a token. Exposure is hedged once
+// it reaches Threshold, by trades of at most MaxTrade and at most
+// DailyLimit a day, 0 means no limit. Amounts are in the token.
+type HedgingTokenConfig struct {
+	Enabled    bool    `json:"enabled"`
+	Threshold  float64 `json:"threshold"`
+	MaxTrade   float64 `json:"max_trade"`
+	DailyLimit float64 `json:"daily_limit"`
+}
+
+// HedgingConfig configures hedging of reserve trades on Exchange against
+// ETH. Exposure is netted over Window seconds, Slippage is the fraction
+// of the best rate hedging orders may cross the book by.
+type HedgingConfig struct {
+	Exchange string                        `json:"exchange"`
+	Window   uint64                        `json:"window"`
+	Slippage float64                       `json:"slippage"`
+	Tokens   map[string]HedgingTokenConfig `json:"tokens"`
+}
+
 type TokenInfo struct {
 	Address  ethereum.Address `json:"address"`
 	Decimals int64            `json:"decimals"`
@@ -31,6 +59,46 @@ type AddressConfig struct {
 	Pricing   string              `json:"pricing"`
 	FeeBurner string              `json:"feeburner"`
 	Whitelist string              `json:"whitelist"`
+	// ActivityTTLs overrides DefaultActivityTTLs, in seconds per action
+	ActivityTTLs map[string]uint64 `json:"activity_ttls"`
+	// ExchangePairs lists pairs fetched and traded on each exchange
+	ExchangePairs map[string][]pairConfig `json:"exchange_pairs"`
+	// MetricDepths is the quantity of each token afp mid and spread
+	// computed by core are weighted to
+	MetricDepths map[string]float64 `json:"metric_depths"`
+	// Hedging is disabled when its exchange is empty
+	Hedging HedgingConfig `json:"hedging"`
+	// SetRateTolerance is the relative change of rates below which a token
+	// is not set again, all tokens are set when it is 0
+	SetRateTolerance float64 `json:"set_rate_tolerance"`
+}
+
+// GetExchangePairs returns the pairs declared for an exchange, their
+// tokens must be declared in tokens. Without declared pairs, each token of
+// the exchange but ETH is paired against ETH.
+func (self AddressConfig) GetExchangePairs(exchange string) ([]TokenPair, error) {
+	pairs := []TokenPair{}
+	declared, found := self.ExchangePairs[exchange]
+	if !found {
+		tokenIDs := []string{}
+		for tokenID := range self.Exchanges[exchange] {
+			if tokenID != "ETH" {
+				tokenIDs = append(tokenIDs, tokenID)
+			}
+		}
+		sort.Strings(tokenIDs)
+		for _, tokenID := range tokenIDs {
+			declared = append(declared, pairConfig{Base: tokenID, Quote: "ETH"})
+		}
+	}
+	for _, p := range declared {
+		pair, err := NewTokenPair(p.Base, p.Quote)
+		if err != nil {
+			return pairs, errors.New(fmt.Sprintf("Invalid pair %s-%s of %s: %s", p.Base, p.Quote, exchange, err))
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
 }
 
 func GetAddressConfigFromFile(path string) (AddressConfig, error) {
@@ -0,0 +1,21 @@
+package common
+
+import (
+	"log"
+)
+
+// Alerter notifies operators about situations which need human attention.
+type Alerter interface {
+	Alert(kind string, message string)
+}
+
+// LogAlerter writes alerts to the log with a searchable prefix.
+type LogAlerter struct{}
+
+func (self LogAlerter) Alert(kind string, message string) {
+	log.Printf("ALERT [%s]: %s", kind, message)
+}
+
+func NewLogAlerter() LogAlerter {
+	return LogAlerter{}
+}
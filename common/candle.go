@@ -0,0 +1,98 @@
+package common
+
+// CONSOLIDATED_EXCHANGE is the exchange ID of candles of books merged
+// across exchanges.
+const CONSOLIDATED_EXCHANGE ExchangeID = "consolidated"
+
+// CandleResolutions maps supported resolutions to their length in
+// miliseconds.
+var CandleResolutions = map[string]uint64{
+	"1m": 60 * 1000,
+	"1h": 60 * 60 * 1000,
+	"1d": 24 * 60 * 60 * 1000,
+}
+
+type OHLC struct {
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+}
+
+func (self *OHLC) update(value float64, first bool) {
+	if first {
+		*self = OHLC{value, value, value, value}
+		return
+	}
+	if value > self.High {
+		self.High = value
+	}
+	if value < self.Low {
+		self.Low = value
+	}
+	self.Close = value
+}
+
+// BookTop is the best bid and the best ask of an order book
+type BookTop struct {
+	Bid float64
+	Ask float64
+}
+
+// Candle aggregates best bid, best ask, their mid and their spread (ask
+// minus bid) of stored order books from Timestamp (miliseconds) for one
+// resolution. Samples is the number of books aggregated.
+type Candle struct {
+	Timestamp uint64
+	Mid       OHLC
+	Bid       OHLC
+	Ask       OHLC
+	Spread    OHLC
+	Samples   uint64
+}
+
+// Update adds top of one order book to the candle.
+func (self *Candle) Update(top BookTop) {
+	first := self.Samples == 0
+	self.Bid.update(top.Bid, first)
+	self.Ask.update(top.Ask, first)
+	self.Mid.update((top.Bid+top.Ask)/2, first)
+	self.Spread.update(top.Ask-top.Bid, first)
+	self.Samples++
+}
+
+// GetBookTop returns top of a book, ok is false when the book is invalid
+// or one of its sides is empty.
+func GetBookTop(price ExchangePrice) (BookTop, bool) {
+	if !price.Valid || len(price.Bids) == 0 || len(price.Asks) == 0 {
+		return BookTop{}, false
+	}
+	top := BookTop{price.Bids[0].Rate, price.Asks[0].Rate}
+	for _, entry := range price.Bids {
+		if entry.Rate > top.Bid {
+			top.Bid = entry.Rate
+		}
+	}
+	for _, entry := range price.Asks {
+		if entry.Rate < top.Ask {
+			top.Ask = entry.Rate
+		}
+	}
+	return top, true
+}
+
+// GetBookTops returns top of book of each exchange having a valid book
+// and of the book consolidated across them.
+func GetBookTops(price OnePrice) map[ExchangeID]BookTop {
+	result := map[ExchangeID]BookTop{}
+	for exchangeID, exchangePrice := range price {
+		if top, ok := GetBookTop(exchangePrice); ok {
+			result[exchangeID] = top
+		}
+	}
+	consolidated := ConsolidatePrice(price, nil)
+	if len(consolidated.Bids) > 0 && len(consolidated.Asks) > 0 {
+		result[CONSOLIDATED_EXCHANGE] = BookTop{consolidated.Bids[0].Rate, consolidated.Asks[0].Rate}
+	}
+	return result
+}
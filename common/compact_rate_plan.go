@@ -0,0 +1,73 @@
+package common
+
+import (
+	"math"
+	"math/big"
+	"sort"
+)
+
+// CompactRateDecision is how the target rates of a token are set: on its
+// current base rates with compact rates, or with new base rates (Base).
+// Compacts are the compact rates on the current base rates, in 0.1% of the
+// base rate, Headroom is how far the larger one is from overflow and
+// Margin the headroom below which the token is rebased, from its
+// Volatility.
+type CompactRateDecision struct {
+	Token       string
+	Base        bool
+	CompactBuy  int64
+	CompactSell int64
+	Headroom    int64
+	Margin      int64
+	Volatility  float64
+	Reason      string
+}
+
+// CompactRatePlan is the decision for each token of a set rates tx. BaseTx
+// is true when base rates of any token are set, which costs more gas.
+// Skipped are tokens not set as their rates are unchanged within rate
+// tolerance.
+type CompactRatePlan struct {
+	BaseTx  bool
+	Tokens  []CompactRateDecision
+	Skipped []string
+}
+
+func entryRate(base *big.Int, compact int8) float64 {
+	if base == nil {
+		return 0
+	}
+	rate, _ := big.NewFloat(0).SetInt(base).Float64()
+	return rate * float64(1000+int64(compact)) / 1000
+}
+
+// RateVolatility returns, for each token, the largest relative change of
+// its buy or sell rate between two consecutive entries of history.
+func RateVolatility(history []AllRateEntry) map[string]float64 {
+	entries := append([]AllRateEntry{}, history...)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].BlockNumber < entries[j].BlockNumber
+	})
+	result := map[string]float64{}
+	last := map[string]RateEntry{}
+	for _, entry := range entries {
+		if !entry.Valid {
+			continue
+		}
+		for tokenID, rate := range entry.Data {
+			if previous, found := last[tokenID]; found {
+				pairs := [][2]float64{
+					{entryRate(previous.BaseBuy, previous.CompactBuy), entryRate(rate.BaseBuy, rate.CompactBuy)},
+					{entryRate(previous.BaseSell, previous.CompactSell), entryRate(rate.BaseSell, rate.CompactSell)},
+				}
+				for _, pair := range pairs {
+					if pair[0] > 0 {
+						result[tokenID] = math.Max(result[tokenID], math.Abs(pair[1]-pair[0])/pair[0])
+					}
+				}
+			}
+			last[tokenID] = rate
+		}
+	}
+	return result
+}
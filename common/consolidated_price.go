@@ -0,0 +1,125 @@
+package common
+
+import (
+	"sort"
+)
+
+// ConsolidatedPriceEntry is a price level of one exchange in a book
+// merged across exchanges.
+type ConsolidatedPriceEntry struct {
+	Exchange ExchangeID
+	Quantity float64
+	Rate     float64
+}
+
+// ConsolidatedPrice is the book of one pair merged across exchanges, bids
+// are sorted from the highest rate and asks from the lowest rate.
+type ConsolidatedPrice struct {
+	Exchanges []ExchangeID
+	Bids      []ConsolidatedPriceEntry
+	Asks      []ConsolidatedPriceEntry
+}
+
+// VWAP is the volume weighted average rate to fill Quantity against a
+// book. Complete is false when the book is not deep enough, Rate is then
+// the average of Filled.
+type VWAP struct {
+	Quantity float64
+	Filled   float64
+	Rate     float64
+	Complete bool
+}
+
+type BuySellVWAP struct {
+	Buy  VWAP
+	Sell VWAP
+}
+
+type ConsolidatedVWAP struct {
+	Consolidated BuySellVWAP
+	Exchanges    map[ExchangeID]BuySellVWAP
+}
+
+type ConsolidatedPriceResponse struct {
+	Version     Version
+	Timestamp   Timestamp
+	ReturnTime  Timestamp
+	FeeAdjusted bool
+	Exchanges   []ExchangeID
+	Bids        []ConsolidatedPriceEntry
+	Asks        []ConsolidatedPriceEntry
+	VWAP        *ConsolidatedVWAP
+}
+
+// ConsolidatePrice merges valid books of all exchanges of a pair. When
+// fees are given, rates are adjusted by the fee of their exchange: bids
+// are what is received after selling and asks are what is paid to buy.
+func ConsolidatePrice(price OnePrice, fees map[ExchangeID]float64) ConsolidatedPrice {
+	result := ConsolidatedPrice{
+		Exchanges: []ExchangeID{},
+		Bids:      []ConsolidatedPriceEntry{},
+		Asks:      []ConsolidatedPriceEntry{},
+	}
+	for exchangeID, exchangePrice := range price {
+		if exchangePrice.Valid {
+			result.Exchanges = append(result.Exchanges, exchangeID)
+		}
+	}
+	// iterate exchanges in order so levels at the same rate are stable
+	sort.Slice(result.Exchanges, func(i, j int) bool { return result.Exchanges[i] < result.Exchanges[j] })
+	for _, exchangeID := range result.Exchanges {
+		fee := fees[exchangeID]
+		for _, bid := range price[exchangeID].Bids {
+			result.Bids = append(result.Bids, ConsolidatedPriceEntry{exchangeID, bid.Quantity, bid.Rate * (1 - fee)})
+		}
+		for _, ask := range price[exchangeID].Asks {
+			result.Asks = append(result.Asks, ConsolidatedPriceEntry{exchangeID, ask.Quantity, ask.Rate * (1 + fee)})
+		}
+	}
+	sort.SliceStable(result.Bids, func(i, j int) bool { return result.Bids[i].Rate > result.Bids[j].Rate })
+	sort.SliceStable(result.Asks, func(i, j int) bool { return result.Asks[i].Rate < result.Asks[j].Rate })
+	return result
+}
+
+func getVWAP(entries []ConsolidatedPriceEntry, quantity float64, exchangeID ExchangeID) VWAP {
+	result := VWAP{Quantity: quantity}
+	var cost float64
+	for _, entry := range entries {
+		if result.Filled >= quantity {
+			break
+		}
+		if exchangeID != "" && entry.Exchange != exchangeID {
+			continue
+		}
+		qty := entry.Quantity
+		if result.Filled+qty > quantity {
+			qty = quantity - result.Filled
+		}
+		result.Filled += qty
+		cost += qty * entry.Rate
+	}
+	if result.Filled > 0 {
+		result.Rate = cost / result.Filled
+	}
+	result.Complete = result.Filled >= quantity
+	return result
+}
+
+// GetVWAP returns rates to buy (against asks) and to sell (against bids)
+// quantity of base token on the consolidated book and on each exchange.
+func (self ConsolidatedPrice) GetVWAP(quantity float64) ConsolidatedVWAP {
+	result := ConsolidatedVWAP{
+		Consolidated: BuySellVWAP{
+			getVWAP(self.Asks, quantity, ""),
+			getVWAP(self.Bids, quantity, ""),
+		},
+		Exchanges: map[ExchangeID]BuySellVWAP{},
+	}
+	for _, exchangeID := range self.Exchanges {
+		result.Exchanges[exchangeID] = BuySellVWAP{
+			getVWAP(self.Asks, quantity, exchangeID),
+			getVWAP(self.Bids, quantity, exchangeID),
+		}
+	}
+	return result
+}
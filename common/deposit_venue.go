@@ -0,0 +1,179 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LATENCY_COST_PER_HOUR is the fraction of proceeds a venue is discounted
+// by for each hour deposits take to be credited, as the price can move
+// while funds are in flight.
+const LATENCY_COST_PER_HOUR float64 = 0.001
+
+// DepositVenueInput is what is known about an exchange to deposit a token
+// to: its fees, its book of the token against ETH, its available balance
+// of the token and credit latency of past deposits of the token.
+type DepositVenueInput struct {
+	Exchange     ExchangeID
+	Supported    bool
+	Fees         ExchangeFees
+	Book         ExchangePrice
+	Balance      float64
+	BalanceValid bool
+	Latency      TransferLatency
+}
+
+// DepositVenue is an exchange ranked for a deposit. Proceeds is what
+// selling the credited amount yields in ETH after taker fee and after the
+// balance already on the exchange is sold, Score is proceeds less the fee
+// to withdraw them, discounted by credit latency. Reasons explain the
+// score, or why the exchange is not eligible.
+type DepositVenue struct {
+	Exchange    ExchangeID
+	Eligible    bool
+	DepositFee  float64
+	WithdrawFee float64
+	Credited    float64
+	Balance     float64
+	Depth       float64
+	Complete    bool
+	SellRate    float64
+	Proceeds    float64
+	Latency     uint64
+	Score       float64
+	Reasons     []string
+}
+
+type DepositRecommendation struct {
+	Token       string
+	Amount      float64
+	Recommended ExchangeID
+	Venues      []DepositVenue
+}
+
+// proceeds returns ETH received selling quantity against bids after
+// quantity before is sold, and the quantity the bids absorb.
+func proceeds(bids []PriceEntry, before, quantity, fee float64) (float64, float64) {
+	var result, filled, skipped float64
+	for _, bid := range bids {
+		qty := bid.Quantity
+		if skipped < before {
+			skip := before - skipped
+			if skip > qty {
+				skip = qty
+			}
+			skipped += skip
+			qty -= skip
+		}
+		if filled+qty > quantity {
+			qty = quantity - filled
+		}
+		if qty <= 0 {
+			continue
+		}
+		filled += qty
+		result += qty * bid.Rate * (1 - fee)
+	}
+	return result, filled
+}
+
+func rankDepositVenue(token string, amount float64, input DepositVenueInput, latency uint64) DepositVenue {
+	result := DepositVenue{
+		Exchange:    input.Exchange,
+		DepositFee:  input.Fees.Funding.Deposit[token],
+		WithdrawFee: input.Fees.Funding.Withdraw["ETH"],
+		Balance:     input.Balance,
+		Reasons:     []string{},
+	}
+	switch {
+	case !input.Supported:
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s deposit is not supported", token))
+		return result
+	case !input.Book.Valid:
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s-ETH book is not valid: %s", token, input.Book.Error))
+		return result
+	}
+	result.Credited = amount - result.DepositFee
+	if result.Credited <= 0 {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("deposit fee %f %s exceeds the amount", result.DepositFee, token))
+		return result
+	}
+	result.Eligible = true
+	if result.DepositFee > 0 {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("deposit fee %f %s", result.DepositFee, token))
+	}
+	if !input.BalanceValid {
+		result.Reasons = append(result.Reasons, "balance is unknown, assumed empty")
+	} else if input.Balance > 0 {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%f %s already on the exchange is sold first", input.Balance, token))
+	}
+	takerFee := input.Fees.Trading["taker"]
+	result.Proceeds, result.Depth = proceeds(input.Book.Bids, input.Balance, result.Credited, takerFee)
+	result.Complete = result.Depth >= result.Credited
+	if result.Depth > 0 {
+		result.SellRate = result.Proceeds / result.Depth
+	}
+	if result.Complete {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("bids absorb %f %s at %f ETH after %.2f%% taker fee", result.Credited, token, result.SellRate, takerFee*100))
+	} else {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("bids absorb only %f of %f %s, the rest is not valued", result.Depth, result.Credited, token))
+	}
+	result.Score = result.Proceeds - result.WithdrawFee
+	if result.WithdrawFee > 0 {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("withdraw fee %f ETH", result.WithdrawFee))
+	}
+	result.Latency = latency
+	if input.Latency.Count > 0 {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("average credit latency %s over %d deposits", time.Duration(latency)*time.Millisecond, input.Latency.Count))
+	} else {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("no credited deposit, latency assumed %s", time.Duration(latency)*time.Millisecond))
+	}
+	discount := LATENCY_COST_PER_HOUR * float64(latency) / float64(time.Hour/time.Millisecond)
+	if discount > 1 {
+		discount = 1
+	}
+	if result.Score > 0 {
+		result.Score *= 1 - discount
+	}
+	result.Reasons = append(result.Reasons, fmt.Sprintf("score discounted %.3f%% for latency", discount*100))
+	return result
+}
+
+// RankDepositVenues ranks exchanges to deposit amount of token to and sell
+// it there. Eligible exchanges come first by score. Exchanges without
+// credited deposits of the token are assumed as slow as the slowest one.
+func RankDepositVenues(token string, amount float64, inputs []DepositVenueInput) DepositRecommendation {
+	result := DepositRecommendation{
+		Token:  token,
+		Amount: amount,
+		Venues: []DepositVenue{},
+	}
+	var slowest uint64
+	for _, input := range inputs {
+		if input.Latency.Count > 0 && input.Latency.Average > slowest {
+			slowest = input.Latency.Average
+		}
+	}
+	for _, input := range inputs {
+		latency := slowest
+		if input.Latency.Count > 0 {
+			latency = input.Latency.Average
+		}
+		result.Venues = append(result.Venues, rankDepositVenue(token, amount, input, latency))
+	}
+	sort.SliceStable(result.Venues, func(i, j int) bool {
+		a, b := result.Venues[i], result.Venues[j]
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Exchange < b.Exchange
+	})
+	if len(result.Venues) > 0 && result.Venues[0].Eligible {
+		result.Recommended = result.Venues[0].Exchange
+	}
+	return result
+}
@@ -13,7 +13,7 @@ type Exchange interface {
 	Address(token Token) (address ethereum.Address, supported bool)
 	UpdateDepositAddress(token Token, addr string)
 	Withdraw(token Token, amount *big.Int, address ethereum.Address, timepoint uint64) (string, error)
-	Trade(tradeType string, base Token, quote Token, rate float64, amount float64, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error)
+	Trade(tradeType string, base Token, quote Token, rate float64, amount float64, params OrderParams, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error)
 	CancelOrder(id ActivityID) error
 	MarshalText() (text []byte, err error)
 	GetInfo() (ExchangeInfo, error)
@@ -22,6 +22,73 @@ type Exchange interface {
 	TokenAddresses() map[string]ethereum.Address
 }
 
+// order types of OrderParams
+const (
+	ORDER_LIMIT string = "limit"
+	// market orders have no price, the rate is still required as an
+	// estimate of it, e.g. huobi market buys spend amount*rate of quote
+	ORDER_MARKET string = "market"
+	// limit order cancelled for the part not filled immediately
+	ORDER_IOC string = "ioc"
+	// limit order cancelled when it can't be filled entirely immediately
+	ORDER_FOK string = "fok"
+	// limit order rejected when it would be filled immediately
+	ORDER_POST_ONLY string = "post_only"
+)
+
+// OrderParams are options of an order placed by Exchange.Trade, the zero
+// value is a plain limit order. ClientOrderID is an id of our choice the
+// exchange keeps with the order.
+type OrderParams struct {
+	OrderType     string
+	ClientOrderID string
+}
+
+// Type returns the order type, limit if it is not set.
+func (self OrderParams) Type() string {
+	if self.OrderType == "" {
+		return ORDER_LIMIT
+	}
+	return self.OrderType
+}
+
+// Validate returns an error if the order type is unknown or rate, the
+// price of limit orders and the price estimate of market orders, isn't
+// positive.
+func (self OrderParams) Validate(rate float64) error {
+	if rate <= 0 {
+		return errors.New(fmt.Sprintf("Rate of %s order must be positive", self.Type()))
+	}
+	switch self.Type() {
+	case ORDER_LIMIT, ORDER_MARKET, ORDER_IOC, ORDER_FOK, ORDER_POST_ONLY:
+		return nil
+	}
+	return errors.New(fmt.Sprintf("Order type %s is not supported", self.OrderType))
+}
+
+// CheckSupported returns an error if the order is invalid or the exchange
+// supports neither the order type nor client order ids when one is given.
+func (self OrderParams) CheckSupported(exchange ExchangeID, rate float64, types []string, clientOrderID bool) error {
+	if err := self.Validate(rate); err != nil {
+		return err
+	}
+	if self.ClientOrderID != "" && !clientOrderID {
+		return errors.New(fmt.Sprintf("Client order id is unsupported on %s", exchange))
+	}
+	for _, t := range types {
+		if t == self.Type() {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("Order type %s is unsupported on %s", self.Type(), exchange))
+}
+
+// TokenPairAdder is an exchange pairs can be added to while it is running,
+// e.g. when a token is listed.
+type TokenPairAdder interface {
+	AddTokenPair(pair TokenPair)
+}
+
 var SupportedExchanges = map[ExchangeID]Exchange{}
 
 func GetExchange(id string) (Exchange, error) {
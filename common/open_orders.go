@@ -0,0 +1,76 @@
+package common
+
+import (
+	"strings"
+)
+
+// OrderFilter selects open orders by exchange, pair and side, empty fields
+// match every order.
+type OrderFilter struct {
+	Exchange ExchangeID
+	Base     string
+	Quote    string
+	Side     string
+}
+
+func (self OrderFilter) match(order OpenOrder) bool {
+	return (self.Exchange == "" || self.Exchange == order.Exchange) &&
+		(self.Base == "" || strings.ToUpper(self.Base) == order.Base) &&
+		(self.Quote == "" || strings.ToUpper(self.Quote) == order.Quote) &&
+		(self.Side == "" || strings.ToLower(self.Side) == order.Side)
+}
+
+// OpenOrder is an order which can be cancelled on its exchange. ID is the
+// id of its trade activity or, for orders without one, an id with the
+// exchange order id as EID. Error is set when cancelling it failed.
+type OpenOrder struct {
+	Exchange ExchangeID
+	ID       ActivityID
+	Base     string
+	Quote    string
+	Side     string
+	Error    string
+}
+
+// GetOpenOrders returns orders matching filter among open orders fetched
+// from exchanges and trade activities still pending, as the latter can be
+// placed after open orders are fetched.
+func GetOpenOrders(exchangeOrders map[ExchangeID]OrderEntry, pendings []ActivityRecord, filter OrderFilter) []OpenOrder {
+	result := []OpenOrder{}
+	found := map[ExchangeID]map[string]bool{}
+	add := func(order OpenOrder) {
+		if found[order.Exchange] == nil {
+			found[order.Exchange] = map[string]bool{}
+		}
+		if found[order.Exchange][order.ID.EID] || !filter.match(order) {
+			return
+		}
+		found[order.Exchange][order.ID.EID] = true
+		result = append(result, order)
+	}
+	for _, activity := range pendings {
+		if activity.Action != "trade" || !activity.IsExchangePending() {
+			continue
+		}
+		side, _ := activity.Params["type"].(string)
+		add(OpenOrder{
+			Exchange: ExchangeID(activity.Destination),
+			ID:       activity.ID,
+			Base:     strings.ToUpper(paramToken(activity.Params["base"])),
+			Quote:    strings.ToUpper(paramToken(activity.Params["quote"])),
+			Side:     strings.ToLower(side),
+		})
+	}
+	for exchangeID, entry := range exchangeOrders {
+		for _, order := range entry.Data {
+			add(OpenOrder{
+				Exchange: exchangeID,
+				ID:       NewActivityID(order.Time*1000000, order.ID),
+				Base:     strings.ToUpper(order.Base),
+				Quote:    strings.ToUpper(order.Quote),
+				Side:     strings.ToLower(order.Side),
+			})
+		}
+	}
+	return result
+}
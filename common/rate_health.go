@@ -0,0 +1,124 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+)
+
+// kinds of rate health issues, they are also the kinds of their alerts
+const (
+	// rates of the token expire within RATE_EXPIRY_WARNING_BLOCKS blocks
+	RATE_EXPIRING string = "rate_expiring"
+	// rates of the token are expired, the reserve quotes 0 for it
+	RATE_EXPIRED string = "rate_expired"
+	// the token is not listed in the pricing contract
+	TOKEN_NOT_LISTED string = "token_not_listed"
+	// trade of the token is disabled in the pricing contract
+	TOKEN_TRADE_DISABLED string = "token_trade_disabled"
+	// max per block or max total imbalance of the token is 0 so no trade
+	// of it can be recorded
+	TOKEN_IMBALANCE_LIMIT_ZERO string = "token_imbalance_limit_zero"
+)
+
+// RATE_EXPIRY_WARNING_BLOCKS is the number of blocks before rates of a
+// token expire they are reported as expiring.
+const RATE_EXPIRY_WARNING_BLOCKS uint64 = 5
+
+// TokenRateState is the state of a token in the pricing contract:
+// whether it is listed and its trade enabled, the block its rates were
+// last set at and its token control info in token units.
+type TokenRateState struct {
+	Listed                  bool
+	Enabled                 bool
+	UpdateBlock             uint64
+	MinimalRecordResolution float64
+	MaxPerBlockImbalance    float64
+	MaxTotalImbalance       float64
+}
+
+// RateHealthIssue is one condition of a token which stops or will soon
+// stop the reserve from trading it.
+type RateHealthIssue struct {
+	Kind    string
+	Token   string
+	Message string
+}
+
+// Key identifies an issue across checks so it is only alerted once.
+func (self RateHealthIssue) Key() string {
+	return fmt.Sprintf("%s|%s", self.Kind, self.Token)
+}
+
+// TokenRateHealth is the rate validity of a token at a block. Rates expire
+// at ExpiryBlock, BlocksToExpiry is 0 or negative once they are expired.
+type TokenRateHealth struct {
+	TokenRateState
+	ExpiryBlock    uint64
+	BlocksToExpiry int64
+	Expired        bool
+	Healthy        bool
+}
+
+// RateHealth is the result of checking rates of all tokens at Block,
+// Issues are sorted by token then kind.
+type RateHealth struct {
+	Timestamp     uint64
+	Block         uint64
+	ValidDuration uint64
+	Healthy       bool
+	Tokens        map[string]TokenRateHealth
+	Issues        []RateHealthIssue
+}
+
+// CheckRateHealth computes rate validity of tokens at block from their
+// state in the pricing contract, rates are valid for validDuration blocks
+// after they are set.
+func CheckRateHealth(block, validDuration uint64, states map[string]TokenRateState) RateHealth {
+	result := RateHealth{
+		Block:         block,
+		ValidDuration: validDuration,
+		Healthy:       true,
+		Tokens:        map[string]TokenRateHealth{},
+		Issues:        []RateHealthIssue{},
+	}
+	for tokenID, state := range states {
+		health := TokenRateHealth{
+			TokenRateState: state,
+			ExpiryBlock:    state.UpdateBlock + validDuration,
+		}
+		health.BlocksToExpiry = int64(health.ExpiryBlock) - int64(block)
+		health.Expired = health.BlocksToExpiry <= 0
+		issues := []RateHealthIssue{}
+		switch {
+		case health.Expired:
+			issues = append(issues, RateHealthIssue{RATE_EXPIRED, tokenID, fmt.Sprintf(
+				"rates of %s set at block %d expired at block %d", tokenID, state.UpdateBlock, health.ExpiryBlock)})
+		case uint64(health.BlocksToExpiry) <= RATE_EXPIRY_WARNING_BLOCKS:
+			issues = append(issues, RateHealthIssue{RATE_EXPIRING, tokenID, fmt.Sprintf(
+				"rates of %s set at block %d expire in %d blocks", tokenID, state.UpdateBlock, health.BlocksToExpiry)})
+		}
+		if !state.Listed {
+			issues = append(issues, RateHealthIssue{TOKEN_NOT_LISTED, tokenID, fmt.Sprintf(
+				"%s is not listed in the pricing contract", tokenID)})
+		} else if !state.Enabled {
+			issues = append(issues, RateHealthIssue{TOKEN_TRADE_DISABLED, tokenID, fmt.Sprintf(
+				"trade of %s is disabled in the pricing contract", tokenID)})
+		}
+		if state.Listed && (state.MaxPerBlockImbalance == 0 || state.MaxTotalImbalance == 0) {
+			issues = append(issues, RateHealthIssue{TOKEN_IMBALANCE_LIMIT_ZERO, tokenID, fmt.Sprintf(
+				"%s max per block imbalance is %f and max total imbalance is %f",
+				tokenID, state.MaxPerBlockImbalance, state.MaxTotalImbalance)})
+		}
+		health.Healthy = len(issues) == 0
+		result.Healthy = result.Healthy && health.Healthy
+		result.Tokens[tokenID] = health
+		result.Issues = append(result.Issues, issues...)
+	}
+	sort.Slice(result.Issues, func(i, j int) bool {
+		if result.Issues[i].Token != result.Issues[j].Token {
+			return result.Issues[i].Token < result.Issues[j].Token
+		}
+		return result.Issues[i].Kind < result.Issues[j].Kind
+	})
+	return result
+}
@@ -0,0 +1,203 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// kinds of reconciliation issues, they are also the kinds of their alerts
+const (
+	// fills of an order no trade activity placed, e.g. manual trading on
+	// our accounts
+	RECONCILIATION_UNKNOWN_FILLS string = "unknown_fills"
+	// trade activities whose order is done on the exchange without fills
+	RECONCILIATION_MISSING_FILLS string = "missing_fills"
+	// filled quantity differs from the activity amount, either the order
+	// is done and partially filled or it is filled more than the amount
+	RECONCILIATION_QUANTITY_MISMATCH string = "quantity_mismatch"
+	// average fill price is worse than the activity rate
+	RECONCILIATION_PRICE_MISMATCH string = "price_mismatch"
+)
+
+// RECONCILIATION_TOLERANCE is the relative difference of quantities and
+// prices which is not reported as a mismatch
+const RECONCILIATION_TOLERANCE float64 = 0.001
+
+// ReconciliationIssue is one trade activity or one order of unknown fills
+// which don't reconcile. Activity is nil for unknown fills.
+type ReconciliationIssue struct {
+	Kind      string
+	Exchange  ExchangeID
+	OrderID   string
+	Activity  *ActivityID
+	Type      string
+	Amount    float64
+	Rate      float64
+	FilledQty float64
+	FillPrice float64
+	Fills     []TradeHistory
+}
+
+// Key identifies an issue across reports so it is only alerted once.
+func (self ReconciliationIssue) Key() string {
+	return fmt.Sprintf("%s|%s|%s", self.Kind, self.Exchange, self.OrderID)
+}
+
+func (self ReconciliationIssue) String() string {
+	if self.Activity == nil {
+		return fmt.Sprintf("%d fills of order %s on %s have no trade activity", len(self.Fills), self.OrderID, self.Exchange)
+	}
+	return fmt.Sprintf(
+		"%s order %s on %s of activity %s: amount %s at %s, filled %s at %s",
+		self.Type, self.OrderID, self.Exchange, self.Activity,
+		strconv.FormatFloat(self.Amount, 'f', -1, 64),
+		strconv.FormatFloat(self.Rate, 'f', -1, 64),
+		strconv.FormatFloat(self.FilledQty, 'f', -1, 64),
+		strconv.FormatFloat(self.FillPrice, 'f', -1, 64),
+	)
+}
+
+// ReconciliationReport is the result of reconciling trade activities and
+// fills from FromTime to ToTime (miliseconds). Matched is the number of
+// trade activities reconciled without issue.
+type ReconciliationReport struct {
+	Timestamp uint64
+	FromTime  uint64
+	ToTime    uint64
+	Matched   int
+	Issues    []ReconciliationIssue
+}
+
+type orderKey struct {
+	exchange ExchangeID
+	orderID  string
+}
+
+// tradeOrderID returns the exchange order id of a trade activity, ids are
+// either <order id>_<symbol> or the order id alone.
+func tradeOrderID(record ActivityRecord) string {
+	return strings.Split(record.ID.EID, "_")[0]
+}
+
+// activityFloat reads a number of activity params, they are either the
+// recorded values or strings after being stored.
+func activityFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		result, _ := strconv.ParseFloat(v, 64)
+		return result
+	}
+	return 0
+}
+
+func sumFills(fills []TradeHistory) (float64, float64) {
+	var qty, cost float64
+	for _, fill := range fills {
+		qty += fill.Qty
+		cost += fill.Qty * fill.Price
+	}
+	if qty == 0 {
+		return 0, 0
+	}
+	return qty, cost / qty
+}
+
+func differs(a, b float64) bool {
+	return math.Abs(a-b) > RECONCILIATION_TOLERANCE*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// Reconcile matches fills to trade activities by exchange and order id.
+// Activities placed and fills traded from fromTime to toTime are checked,
+// activities and fills outside of it are only used for matching so they
+// should cover enough time before (orders filled long after being placed)
+// and after (fills fetched after the order is done).
+func Reconcile(activities []ActivityRecord, history AllTradeHistory, fromTime, toTime uint64) ReconciliationReport {
+	report := ReconciliationReport{
+		FromTime: fromTime,
+		ToTime:   toTime,
+		Issues:   []ReconciliationIssue{},
+	}
+	fills := map[orderKey][]TradeHistory{}
+	for exchangeID, exchangeHistory := range history.Data {
+		for _, trades := range exchangeHistory {
+			for _, trade := range trades {
+				// trades stored before order ids were recorded can't be
+				// matched
+				if trade.OrderID == "" {
+					continue
+				}
+				key := orderKey{exchangeID, trade.OrderID}
+				fills[key] = append(fills[key], trade)
+			}
+		}
+	}
+	placed := map[orderKey]bool{}
+	for _, activity := range activities {
+		if activity.Action != "trade" || activity.ID.EID == "" || activity.ExchangeStatus == string(ActivityStateFailed) {
+			continue
+		}
+		key := orderKey{ExchangeID(activity.Destination), tradeOrderID(activity)}
+		placed[key] = true
+		timepoint := activity.ID.Timepoint / 1000000
+		if timepoint < fromTime || timepoint > toTime {
+			continue
+		}
+		activityID := activity.ID
+		issue := ReconciliationIssue{
+			Exchange: key.exchange,
+			OrderID:  key.orderID,
+			Activity: &activityID,
+			Rate:     activityFloat(activity.Params["rate"]),
+			Amount:   activityFloat(activity.Params["amount"]),
+			Fills:    fills[key],
+		}
+		issue.Type, _ = activity.Params["type"].(string)
+		issue.FilledQty, issue.FillPrice = sumFills(issue.Fills)
+		done := activity.ExchangeStatus == string(ActivityStateDone)
+		switch {
+		case done && len(issue.Fills) == 0:
+			issue.Kind = RECONCILIATION_MISSING_FILLS
+		case issue.FilledQty > issue.Amount && differs(issue.FilledQty, issue.Amount),
+			done && differs(issue.FilledQty, issue.Amount):
+			issue.Kind = RECONCILIATION_QUANTITY_MISMATCH
+		case len(issue.Fills) > 0 && issue.Type == "buy" && issue.FillPrice > issue.Rate && differs(issue.FillPrice, issue.Rate),
+			len(issue.Fills) > 0 && issue.Type == "sell" && issue.FillPrice < issue.Rate && differs(issue.FillPrice, issue.Rate):
+			issue.Kind = RECONCILIATION_PRICE_MISMATCH
+		}
+		if issue.Kind != "" {
+			report.Issues = append(report.Issues, issue)
+		} else if done {
+			report.Matched++
+		}
+	}
+	for key, orderFills := range fills {
+		if placed[key] {
+			continue
+		}
+		inRange := []TradeHistory{}
+		for _, fill := range orderFills {
+			if fromTime <= fill.Timestamp && fill.Timestamp <= toTime {
+				inRange = append(inRange, fill)
+			}
+		}
+		if len(inRange) == 0 {
+			continue
+		}
+		issue := ReconciliationIssue{
+			Kind:     RECONCILIATION_UNKNOWN_FILLS,
+			Exchange: key.exchange,
+			OrderID:  key.orderID,
+			Type:     inRange[0].Type,
+			Fills:    inRange,
+		}
+		issue.FilledQty, issue.FillPrice = sumFills(inRange)
+		report.Issues = append(report.Issues, issue)
+	}
+	sort.Slice(report.Issues, func(i, j int) bool { return report.Issues[i].Key() < report.Issues[j].Key() })
+	return report
+}
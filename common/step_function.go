@@ -0,0 +1,122 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// types of step functions in the pricing contract
+const (
+	QTY_STEP_FUNCTION       string = "qty"
+	IMBALANCE_STEP_FUNCTION string = "imbalance"
+)
+
+// MAX_STEPS_IN_FUNCTION is the number of steps the pricing contract
+// accepts in a step function
+const MAX_STEPS_IN_FUNCTION int = 10
+
+// StepFunction adjusts rates by Y[i] basis points when the quantity or
+// imbalance, in token, is up to X[i]. The last step applies beyond the
+// last X.
+type StepFunction struct {
+	X []float64
+	Y []int64
+}
+
+func (self StepFunction) Validate() error {
+	if len(self.X) != len(self.Y) {
+		return errors.New(fmt.Sprintf("%d steps but %d adjustments", len(self.X), len(self.Y)))
+	}
+	if len(self.X) > MAX_STEPS_IN_FUNCTION {
+		return errors.New(fmt.Sprintf("%d steps, at most %d are allowed", len(self.X), MAX_STEPS_IN_FUNCTION))
+	}
+	for i := range self.X {
+		if i > 0 && self.X[i] <= self.X[i-1] {
+			return errors.New(fmt.Sprintf("steps must be increasing, %f is after %f", self.X[i], self.X[i-1]))
+		}
+		if self.Y[i] <= -10000 {
+			return errors.New(fmt.Sprintf("adjustment %d bps makes rates 0 or negative", self.Y[i]))
+		}
+	}
+	return nil
+}
+
+func (self StepFunction) Equal(other StepFunction) bool {
+	if len(self.X) != len(other.X) || len(self.Y) != len(other.Y) {
+		return false
+	}
+	for i := range self.X {
+		if self.X[i] != other.X[i] {
+			return false
+		}
+	}
+	for i := range self.Y {
+		if self.Y[i] != other.Y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TokenStepFunctions are the step functions of a token in the pricing
+// contract, quantity functions adjust rates by the quantity of a trade and
+// imbalance functions by the imbalance of the reserve.
+type TokenStepFunctions struct {
+	QtyBuy        StepFunction
+	QtySell       StepFunction
+	ImbalanceBuy  StepFunction
+	ImbalanceSell StepFunction
+}
+
+func (self TokenStepFunctions) Validate() error {
+	functions := map[string]StepFunction{
+		"QtyBuy":        self.QtyBuy,
+		"QtySell":       self.QtySell,
+		"ImbalanceBuy":  self.ImbalanceBuy,
+		"ImbalanceSell": self.ImbalanceSell,
+	}
+	for name, function := range functions {
+		if err := function.Validate(); err != nil {
+			return errors.New(fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+	return nil
+}
+
+// StepFunctionDiff is a change of the buy and sell step functions of one
+// type of a token, they are set together on chain.
+type StepFunctionDiff struct {
+	Token        string
+	Type         string
+	CurrentBuy   StepFunction
+	CurrentSell  StepFunction
+	ProposedBuy  StepFunction
+	ProposedSell StepFunction
+}
+
+// DiffStepFunctions returns the quantity and imbalance step functions of
+// token which differ between current and proposed.
+func DiffStepFunctions(token string, current, proposed TokenStepFunctions) []StepFunctionDiff {
+	result := []StepFunctionDiff{}
+	if !current.QtyBuy.Equal(proposed.QtyBuy) || !current.QtySell.Equal(proposed.QtySell) {
+		result = append(result, StepFunctionDiff{
+			Token:        token,
+			Type:         QTY_STEP_FUNCTION,
+			CurrentBuy:   current.QtyBuy,
+			CurrentSell:  current.QtySell,
+			ProposedBuy:  proposed.QtyBuy,
+			ProposedSell: proposed.QtySell,
+		})
+	}
+	if !current.ImbalanceBuy.Equal(proposed.ImbalanceBuy) || !current.ImbalanceSell.Equal(proposed.ImbalanceSell) {
+		result = append(result, StepFunctionDiff{
+			Token:        token,
+			Type:         IMBALANCE_STEP_FUNCTION,
+			CurrentBuy:   current.ImbalanceBuy,
+			CurrentSell:  current.ImbalanceSell,
+			ProposedBuy:  proposed.ImbalanceBuy,
+			ProposedSell: proposed.ImbalanceSell,
+		})
+	}
+	return result
+}
@@ -18,7 +18,7 @@ func (self TestExchange) Address(token Token) (address ethereum.Address, support
 func (self TestExchange) Withdraw(token Token, amount *big.Int, address ethereum.Address, timepoint uint64) (string, error) {
 	return "withdrawid", nil
 }
-func (self TestExchange) Trade(tradeType string, base Token, quote Token, rate float64, amount float64, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error) {
+func (self TestExchange) Trade(tradeType string, base Token, quote Token, rate float64, amount float64, params OrderParams, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error) {
 	return "tradeid", 10, 5, false, nil
 }
 func (self TestExchange) CancelOrder(id ActivityID) error {
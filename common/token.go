@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 )
 
 type Token struct {
@@ -33,8 +34,8 @@ func (self *TokenPair) PairID() TokenPairID {
 }
 
 func NewTokenPair(base, quote string) (TokenPair, error) {
-	bToken, err1 := GetToken(base)
-	qToken, err2 := GetToken(quote)
+	bToken, err1 := GetPairToken(base)
+	qToken, err2 := GetPairToken(quote)
 	if err1 != nil || err2 != nil {
 		return TokenPair{}, errors.New(fmt.Sprintf("%s or %s is not supported", base, quote))
 	} else {
@@ -54,8 +55,30 @@ func MustCreateTokenPair(base, quote string) TokenPair {
 var SupportedTokens map[string]Token
 var ExternalTokens map[string]Token
 
+var supportedTokensMu sync.RWMutex
+
+// GetSupportedTokens returns the reserve tokens, the map must not be
+// modified.
+func GetSupportedTokens() map[string]Token {
+	supportedTokensMu.RLock()
+	defer supportedTokensMu.RUnlock()
+	return SupportedTokens
+}
+
+// AddSupportedToken adds token to the reserve tokens while they are used.
+func AddSupportedToken(token Token) {
+	supportedTokensMu.Lock()
+	defer supportedTokensMu.Unlock()
+	tokens := map[string]Token{}
+	for id, t := range SupportedTokens {
+		tokens[id] = t
+	}
+	tokens[token.ID] = token
+	SupportedTokens = tokens
+}
+
 func GetToken(id string) (Token, error) {
-	t := SupportedTokens[strings.ToUpper(id)]
+	t := GetSupportedTokens()[strings.ToUpper(id)]
 	if t.ID == "" {
 		return t, errors.New(fmt.Sprintf("Token %s is not supported", id))
 	} else {
@@ -63,6 +86,16 @@ func GetToken(id string) (Token, error) {
 	}
 }
 
+// GetPairToken returns a token which can be the base or the quote of an
+// exchange pair. Besides reserve tokens, external tokens (e.g. BTC, USDT)
+// can be traded on exchanges.
+func GetPairToken(id string) (Token, error) {
+	if t, found := ExternalTokens[strings.ToUpper(id)]; found {
+		return t, nil
+	}
+	return GetToken(id)
+}
+
 func MustGetToken(id string) Token {
 	t, e := GetToken(id)
 	if e != nil {
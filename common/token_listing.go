@@ -0,0 +1,85 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+// steps of listing a token, in the order they are done
+const (
+	LISTING_ADD_TOKEN           string = "add_token"
+	LISTING_SET_CONTROL_INFO    string = "set_token_control_info"
+	LISTING_SET_QTY_STEPS       string = "set_qty_step_function"
+	LISTING_SET_IMBALANCE_STEPS string = "set_imbalance_step_function"
+	LISTING_ENABLE_TOKEN_TRADE  string = "enable_token_trade"
+)
+
+// statuses of a token listing
+const (
+	LISTING_PENDING     string = "pending"
+	LISTING_IN_PROGRESS string = "listing"
+	LISTING_LISTED      string = "listed"
+	LISTING_FAILED      string = "failed"
+)
+
+// TokenListing is a token to list in the pricing contract and on
+// exchanges. Control info is in token, Pairs are the quote tokens of the
+// token on each exchange and DepositAddresses the addresses to deposit the
+// token to on each exchange. Empty step functions are set to a single step
+// without adjustment as the pricing contract can't rate a token without
+// them.
+type TokenListing struct {
+	ID                      string
+	Address                 string
+	Decimals                int64
+	MinimalRecordResolution float64
+	MaxPerBlockImbalance    float64
+	MaxTotalImbalance       float64
+	StepFunctions           TokenStepFunctions
+	Pairs                   map[string][]string
+	DepositAddresses        map[string]string
+}
+
+func (self TokenListing) Token() Token {
+	return Token{
+		ID:      strings.ToUpper(self.ID),
+		Address: self.Address,
+		Decimal: self.Decimals,
+	}
+}
+
+func (self TokenListing) Validate() error {
+	if self.ID == "" || strings.ToUpper(self.ID) == "ETH" {
+		return errors.New(fmt.Sprintf("Invalid token id %s", self.ID))
+	}
+	if !ethereum.IsHexAddress(self.Address) {
+		return errors.New(fmt.Sprintf("Invalid token address %s", self.Address))
+	}
+	if self.Decimals < 0 || self.Decimals > 18 {
+		return errors.New(fmt.Sprintf("Invalid decimals %d", self.Decimals))
+	}
+	if self.MinimalRecordResolution <= 0 || self.MaxPerBlockImbalance <= 0 || self.MaxTotalImbalance <= 0 {
+		return errors.New("Minimal record resolution, max per block imbalance and max total imbalance must be positive")
+	}
+	if err := self.StepFunctions.Validate(); err != nil {
+		return err
+	}
+	for exchange, quotes := range self.Pairs {
+		if len(quotes) == 0 {
+			return errors.New(fmt.Sprintf("No quote token of %s on %s", self.ID, exchange))
+		}
+	}
+	return nil
+}
+
+// DefaultStepFunction returns function, or a single step without
+// adjustment if it is empty.
+func DefaultStepFunction(function StepFunction) StepFunction {
+	if len(function.X) == 0 {
+		return StepFunction{X: []float64{0}, Y: []int64{0}}
+	}
+	return function
+}
@@ -0,0 +1,227 @@
+package common
+
+import (
+	"sort"
+	"strconv"
+)
+
+// stages of a transfer between the reserve and an exchange
+const (
+	// deposit tx is sent from the reserve or withdrawal is requested to the
+	// exchange
+	TRANSFER_REQUESTED string = "requested"
+	// tx of the transfer is mined, deposits are waiting for confirmations
+	// of the exchange and withdrawals increase balance of the reserve
+	TRANSFER_MINED string = "mined"
+	// withdrawal is processed by the exchange
+	TRANSFER_PROCESSED string = "processed"
+	// exchange reports tx hash of a withdrawal
+	TRANSFER_BROADCAST string = "broadcast"
+	// deposit is credited to the exchange balance
+	TRANSFER_CREDITED string = "credited"
+	TRANSFER_FAILED   string = "failed"
+	TRANSFER_EXPIRED  string = "expired"
+)
+
+// TransferStage is a stage a transfer reached at Timestamp (miliseconds)
+type TransferStage struct {
+	Stage     string
+	Timestamp uint64
+	Source    string
+	Reason    string
+}
+
+// TransferTimeline links a deposit from its reserve tx to the exchange
+// credit, or a withdrawal from its exchange id to the tx increasing the
+// reserve balance. Confirmations is the number of blocks from the deposit
+// tx to the first auth data the exchange credited it in. Latency is the
+// time funds are in flight (miliseconds): from deposit tx mined to credit,
+// or from withdrawal request to its tx mined. It is 0 until the transfer
+// is complete.
+type TransferTimeline struct {
+	ID            ActivityID
+	Action        string
+	Exchange      string
+	Token         string
+	Amount        string
+	Tx            string
+	WithdrawID    string
+	BlockNumber   uint64
+	Confirmations uint64
+	Complete      bool
+	Latency       uint64
+	Stages        []TransferStage
+}
+
+// Stage returns the first time the transfer reached the stage.
+func (self TransferTimeline) Stage(stage string) (TransferStage, bool) {
+	for _, s := range self.Stages {
+		if s.Stage == stage {
+			return s, true
+		}
+	}
+	return TransferStage{}, false
+}
+
+func resultUint64(value interface{}) uint64 {
+	switch v := value.(type) {
+	case uint64:
+		return v
+	case float64:
+		return uint64(v)
+	case string:
+		result, _ := strconv.ParseUint(v, 10, 64)
+		return result
+	}
+	return 0
+}
+
+func resultString(value interface{}) string {
+	if v, ok := value.(string); ok {
+		return v
+	}
+	return ""
+}
+
+func paramToken(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case Token:
+		return v.ID
+	}
+	return ""
+}
+
+// transitionStage maps a status transition of a transfer to its stage
+func transitionStage(action string, t ActivityTransition) string {
+	switch t.To {
+	case ActivityStateFailed:
+		return TRANSFER_FAILED
+	case ActivityStateExpired:
+		return TRANSFER_EXPIRED
+	case ActivityStateMined:
+		return TRANSFER_MINED
+	case ActivityStateDone:
+		if action == "deposit" {
+			return TRANSFER_CREDITED
+		}
+		return TRANSFER_PROCESSED
+	}
+	return ""
+}
+
+// NewTransferTimeline builds the timeline of a deposit or withdraw
+// activity from its status history, false is returned for other actions.
+func NewTransferTimeline(activity ActivityRecord) (TransferTimeline, bool) {
+	if activity.Action != "deposit" && activity.Action != "withdraw" {
+		return TransferTimeline{}, false
+	}
+	requested, _ := strconv.ParseUint(string(activity.Timestamp), 10, 64)
+	result := TransferTimeline{
+		ID:          activity.ID,
+		Action:      activity.Action,
+		Exchange:    activity.Destination,
+		Token:       paramToken(activity.Params["token"]),
+		Amount:      resultString(activity.Params["amount"]),
+		Tx:          resultString(activity.Result["tx"]),
+		WithdrawID:  resultString(activity.Result["id"]),
+		BlockNumber: resultUint64(activity.Result["blockNumber"]),
+		Stages: []TransferStage{
+			TransferStage{Stage: TRANSFER_REQUESTED, Timestamp: requested, Source: activity.Destination},
+		},
+	}
+	if txTime := resultUint64(activity.Result["txTime"]); txTime > 0 {
+		result.Stages = append(result.Stages, TransferStage{
+			Stage:     TRANSFER_BROADCAST,
+			Timestamp: txTime,
+			Source:    activity.Destination,
+		})
+	}
+	for _, t := range activity.History {
+		stage := transitionStage(activity.Action, t)
+		if stage == "" {
+			continue
+		}
+		timestamp, _ := strconv.ParseUint(string(t.Timestamp), 10, 64)
+		result.Stages = append(result.Stages, TransferStage{
+			Stage:     stage,
+			Timestamp: timestamp,
+			Source:    t.Source,
+			Reason:    t.Reason,
+		})
+	}
+	// broadcast is not a status transition, put it in order
+	sort.SliceStable(result.Stages, func(i, j int) bool {
+		return result.Stages[i].Timestamp < result.Stages[j].Timestamp
+	})
+	if creditBlock := resultUint64(activity.Result["creditBlock"]); creditBlock > result.BlockNumber && result.BlockNumber > 0 {
+		result.Confirmations = creditBlock - result.BlockNumber
+	}
+	mined, minedFound := result.Stage(TRANSFER_MINED)
+	_, failed := result.Stage(TRANSFER_FAILED)
+	switch activity.Action {
+	case "deposit":
+		credited, creditedFound := result.Stage(TRANSFER_CREDITED)
+		if minedFound && creditedFound && !failed {
+			result.Complete = true
+			if credited.Timestamp > mined.Timestamp {
+				result.Latency = credited.Timestamp - mined.Timestamp
+			}
+		}
+	case "withdraw":
+		_, processed := result.Stage(TRANSFER_PROCESSED)
+		if minedFound && processed && !failed {
+			result.Complete = true
+			if mined.Timestamp > requested {
+				result.Latency = mined.Timestamp - requested
+			}
+		}
+	}
+	return result, true
+}
+
+// GetTransferTimelines returns timelines of deposits and withdrawals of
+// activities.
+func GetTransferTimelines(activities []ActivityRecord) []TransferTimeline {
+	result := []TransferTimeline{}
+	for _, activity := range activities {
+		if timeline, ok := NewTransferTimeline(activity); ok {
+			result = append(result, timeline)
+		}
+	}
+	return result
+}
+
+// TransferLatency is credit latency (miliseconds) of complete transfers
+// of one action to or from one exchange.
+type TransferLatency struct {
+	Count   int
+	Average uint64
+	Max     uint64
+}
+
+// GetTransferLatencies returns latencies of complete transfers by exchange
+// and by action.
+func GetTransferLatencies(timelines []TransferTimeline) map[string]map[string]TransferLatency {
+	totals := map[string]map[string]uint64{}
+	result := map[string]map[string]TransferLatency{}
+	for _, timeline := range timelines {
+		if !timeline.Complete {
+			continue
+		}
+		if _, found := result[timeline.Exchange]; !found {
+			result[timeline.Exchange] = map[string]TransferLatency{}
+			totals[timeline.Exchange] = map[string]uint64{}
+		}
+		latency := result[timeline.Exchange][timeline.Action]
+		latency.Count++
+		if timeline.Latency > latency.Max {
+			latency.Max = timeline.Latency
+		}
+		totals[timeline.Exchange][timeline.Action] += timeline.Latency
+		latency.Average = totals[timeline.Exchange][timeline.Action] / uint64(latency.Count)
+		result[timeline.Exchange][timeline.Action] = latency
+	}
+	return result
+}
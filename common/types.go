@@ -257,46 +257,59 @@ type ActivityRecord struct {
 	ExchangeStatus string
 	MiningStatus   string
 	Timestamp      Timestamp
+	History        []ActivityTransition
+}
+
+func (self ActivityRecord) exchangeState() ActivityState {
+	return ActivityState(self.ExchangeStatus)
+}
+
+func (self ActivityRecord) miningState() ActivityState {
+	return ActivityState(self.MiningStatus)
 }
 
 func (self ActivityRecord) IsExchangePending() bool {
+	estate, mstate := self.exchangeState(), self.miningState()
 	switch self.Action {
 	case "withdraw":
-		return (self.ExchangeStatus == "" || self.ExchangeStatus == "submitted") &&
-			self.MiningStatus != "failed"
+		return (estate == ActivityStateNone || estate == ActivityStateSubmitted) &&
+			mstate != ActivityStateFailed
 	case "deposit":
-		return (self.ExchangeStatus == "" || self.ExchangeStatus == "pending") &&
-			self.MiningStatus != "failed"
-	case "trade":
-		return self.ExchangeStatus == "" || self.ExchangeStatus == "submitted"
+		return (estate == ActivityStateNone || estate == ActivityStatePending) &&
+			mstate != ActivityStateFailed
+	case "trade", "parent_order":
+		return estate == ActivityStateNone || estate == ActivityStateSubmitted
 	}
 	return true
 }
 
 func (self ActivityRecord) IsBlockchainPending() bool {
+	estate, mstate := self.exchangeState(), self.miningState()
 	switch self.Action {
-	case "withdraw", "deposit", "set_rates":
-		return (self.MiningStatus == "" || self.MiningStatus == "submitted") && self.ExchangeStatus != "failed"
+	case "withdraw", "deposit", "set_rates", "set_trade_enabled", "set_step_function", "list_token":
+		return (mstate == ActivityStateNone || mstate == ActivityStateSubmitted) &&
+			estate != ActivityStateFailed
 	}
 	return true
 }
 
 func (self ActivityRecord) IsPending() bool {
+	estate, mstate := self.exchangeState(), self.miningState()
 	switch self.Action {
 	case "withdraw":
-		return (self.ExchangeStatus == "" || self.ExchangeStatus == "submitted" ||
-			self.MiningStatus == "" || self.MiningStatus == "submitted") &&
-			self.MiningStatus != "failed" && self.ExchangeStatus != "failed"
+		return (estate == ActivityStateNone || estate == ActivityStateSubmitted ||
+			mstate == ActivityStateNone || mstate == ActivityStateSubmitted) &&
+			mstate != ActivityStateFailed && estate != ActivityStateFailed
 	case "deposit":
-		return (self.ExchangeStatus == "" || self.ExchangeStatus == "pending" ||
-			self.MiningStatus == "" || self.MiningStatus == "submitted") &&
-			self.MiningStatus != "failed" && self.ExchangeStatus != "failed"
-	case "trade":
-		return (self.ExchangeStatus == "" || self.ExchangeStatus == "submitted") &&
-			self.ExchangeStatus != "failed"
-	case "set_rates":
-		return (self.MiningStatus == "" || self.MiningStatus == "submitted") &&
-			self.ExchangeStatus != "failed"
+		return (estate == ActivityStateNone || estate == ActivityStatePending ||
+			mstate == ActivityStateNone || mstate == ActivityStateSubmitted) &&
+			mstate != ActivityStateFailed && estate != ActivityStateFailed
+	case "trade", "parent_order":
+		return (estate == ActivityStateNone || estate == ActivityStateSubmitted) &&
+			estate != ActivityStateFailed
+	case "set_rates", "set_trade_enabled", "set_step_function", "list_token":
+		return (mstate == ActivityStateNone || mstate == ActivityStateSubmitted) &&
+			estate != ActivityStateFailed
 	}
 	return true
 }
@@ -356,6 +369,23 @@ func BigToFloat(b *big.Int, decimal int64) float64 {
 	return result
 }
 
+// FloatToBig converts an amount in token to its wei, the inverse of
+// BigToFloat, rounded to the nearest wei.
+func FloatToBig(amount float64, decimal int64) *big.Int {
+	f, _ := new(big.Float).SetPrec(256).SetString(strconv.FormatFloat(amount, 'f', -1, 64))
+	power := new(big.Float).SetInt(new(big.Int).Exp(
+		big.NewInt(10), big.NewInt(decimal), nil,
+	))
+	f.Mul(f, power)
+	if amount < 0 {
+		f.Sub(f, big.NewFloat(0.5))
+	} else {
+		f.Add(f, big.NewFloat(0.5))
+	}
+	result, _ := f.Int(nil)
+	return result
+}
+
 func AddrToString(addr ethereum.Address) string {
 	return strings.ToLower(addr.String())
 }
@@ -465,6 +495,7 @@ type AuthDataSnapshot struct {
 	Timestamp         Timestamp
 	ReturnTime        Timestamp
 	ExchangeBalances  map[ExchangeID]EBalanceEntry
+	ExchangeOrders    map[ExchangeID]OrderEntry
 	ReserveBalances   map[string]BalanceEntry
 	PendingActivities []ActivityRecord
 	Block             uint64
@@ -480,6 +511,7 @@ type AuthDataResponse struct {
 		Timestamp         Timestamp
 		ReturnTime        Timestamp
 		ExchangeBalances  map[ExchangeID]EBalanceEntry
+		ExchangeOrders    map[ExchangeID]OrderEntry
 		ReserveBalances   map[string]BalanceResponse
 		PendingActivities []ActivityRecord
 		Block             uint64
@@ -527,6 +559,13 @@ type AllRateResponse struct {
 	ToBlockNumber uint64
 }
 
+// TradeStatus is whether trading of the reserve is enabled on chain and
+// whether trading of each token is enabled in the pricing contract.
+type TradeStatus struct {
+	TradeEnabled bool
+	Tokens       map[string]bool
+}
+
 type KNLog interface {
 	BlockNo() uint64
 	Type() string
@@ -574,6 +613,9 @@ type TradeHistory struct {
 	Qty       float64
 	Type      string // buy or sell
 	Timestamp uint64
+	// OrderID is the exchange order the trade filled, it is the order id
+	// part of trade activity ids
+	OrderID string
 }
 
 type ExchangeTradeHistory map[TokenPairID][]TradeHistory
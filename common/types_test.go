@@ -3,7 +3,10 @@ package common
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
 	"testing"
+	"time"
 )
 
 func TestStringToActivityID(t *testing.T) {
@@ -63,3 +66,449 @@ func TestJSONToActivityID(t *testing.T) {
 		}
 	}
 }
+
+func TestActivityRecordTransition(t *testing.T) {
+	record := NewActivityRecord(
+		"deposit", ActivityID{1, "1"}, "binance",
+		map[string]interface{}{}, map[string]interface{}{},
+		"", "submitted", Timestamp("1"),
+	)
+	if err := record.UpdateMiningStatus("mined", "blockchain", Timestamp("2")); err != nil {
+		t.Fatalf("Expected submitted -> mined to be allowed, got error: %v", err)
+	}
+	if err := record.UpdateExchangeStatus("done", "binance", Timestamp("3")); err != nil {
+		t.Fatalf("Expected exchange status to become done, got error: %v", err)
+	}
+	if len(record.History) != 3 {
+		t.Fatalf("Expected 3 transitions in history, got %d", len(record.History))
+	}
+	last := record.History[2]
+	if last.Side != ExchangeSide || last.From != ActivityStateNone || last.To != ActivityStateDone || last.Source != "binance" {
+		t.Fatalf("Unexpected last transition: %+v", last)
+	}
+	if record.IsPending() {
+		t.Fatalf("Expected deposit to be finished")
+	}
+}
+
+func TestActivityRecordInvalidTransition(t *testing.T) {
+	record := NewActivityRecord(
+		"set_rates", ActivityID{1, "1"}, "blockchain",
+		map[string]interface{}{}, map[string]interface{}{},
+		"", "failed", Timestamp("1"),
+	)
+	if err := record.UpdateMiningStatus("mined", "blockchain", Timestamp("2")); err == nil {
+		t.Fatalf("Expected failed -> mined to be rejected")
+	}
+	if record.MiningStatus != "failed" || len(record.History) != 1 {
+		t.Fatalf("Expected record to be untouched after invalid transition, got %+v", record)
+	}
+	if err := record.UpdateExchangeStatus("done", "binance", Timestamp("2")); err == nil {
+		t.Fatalf("Expected set_rates to have no exchange transitions")
+	}
+}
+
+func TestActivityRecordExpireAndResolve(t *testing.T) {
+	record := NewActivityRecord(
+		"deposit", ActivityID{1000, "1"}, "binance",
+		map[string]interface{}{}, map[string]interface{}{},
+		"", "submitted", Timestamp("1000"),
+	)
+	ttls := map[string]time.Duration{"deposit": time.Second}
+	if record.IsOverdue(ttls, 1500) {
+		t.Fatalf("Expected activity not to be overdue before its ttl")
+	}
+	if !record.IsOverdue(ttls, 2001) {
+		t.Fatalf("Expected activity to be overdue after its ttl")
+	}
+	if err := record.Expire(TimeoutSource, Timestamp("2001")); err != nil {
+		t.Fatalf("Expected pending activity to be expired, got error: %v", err)
+	}
+	if !record.IsExpired() || record.IsPending() {
+		t.Fatalf("Expected expired activity not to be pending, got %+v", record)
+	}
+	if err := record.Expire(TimeoutSource, Timestamp("2002")); err == nil {
+		t.Fatalf("Expected expiring an expired activity to be rejected")
+	}
+	if err := record.Resolve("done", "", Timestamp("3000")); err == nil {
+		t.Fatalf("Expected resolving without a reason to be rejected")
+	}
+	if err := record.Resolve("done", "credited on exchange", Timestamp("3000")); err != nil {
+		t.Fatalf("Expected expired activity to be resolved, got error: %v", err)
+	}
+	if record.ExchangeStatus != "done" || record.MiningStatus != "mined" {
+		t.Fatalf("Unexpected statuses after resolving: %s, %s", record.ExchangeStatus, record.MiningStatus)
+	}
+	last := record.History[len(record.History)-1]
+	if last.Source != OperatorSource || last.Reason != "credited on exchange" {
+		t.Fatalf("Expected resolution to be kept in history, got %+v", last)
+	}
+	if err := record.Resolve("failed", "again", Timestamp("4000")); err == nil {
+		t.Fatalf("Expected finished activity not to be resolved again")
+	}
+}
+
+func TestGetExchangePairsWithExternalQuote(t *testing.T) {
+	SupportedTokens = map[string]Token{
+		"ETH": Token{"ETH", "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", 18},
+		"KNC": Token{"KNC", "0xdd974d5c2e2928dea5f71b9825b8b646686bd200", 18},
+	}
+	ExternalTokens = map[string]Token{
+		"BTC":  Token{"BTC", "", 8},
+		"USDT": Token{"USDT", "0xdac17f958d2ee523a2206206994597c13d831ec7", 6},
+	}
+	config := AddressConfig{}
+	err := json.Unmarshal([]byte(`{"exchange_pairs": {"binance": [
+		{"base": "KNC", "quote": "BTC"},
+		{"base": "ETH", "quote": "USDT"}
+	]}}`), &config)
+	if err != nil {
+		t.Fatalf("Couldn't parse setting: %v", err)
+	}
+	pairs, err := config.GetExchangePairs("binance")
+	if err != nil {
+		t.Fatalf("Expected pairs with external tokens to be valid, got error: %v", err)
+	}
+	if len(pairs) != 2 || pairs[0].PairID() != "KNC-BTC" || pairs[1].PairID() != "ETH-USDT" {
+		t.Fatalf("Expected KNC-BTC and ETH-USDT, got %v", pairs)
+	}
+	config.ExchangePairs["binance"] = append(config.ExchangePairs["binance"], pairConfig{"XYZ", "ETH"})
+	if _, err = config.GetExchangePairs("binance"); err == nil {
+		t.Fatalf("Expected pair with unknown token to be rejected")
+	}
+	// tokens of an exchange without declared pairs are paired against ETH
+	config.Exchanges = map[string]exchange{"huobi": {"ETH": "0x1", "KNC": "0x2"}}
+	pairs, err = config.GetExchangePairs("huobi")
+	if err != nil || len(pairs) != 1 || pairs[0].PairID() != "KNC-ETH" {
+		t.Fatalf("Expected KNC-ETH by default, got %v (%v)", pairs, err)
+	}
+}
+
+func TestConsolidatePriceAndVWAP(t *testing.T) {
+	price := OnePrice{
+		"binance": ExchangePrice{
+			Valid: true,
+			Bids:  []PriceEntry{{10, 0.010}, {10, 0.008}},
+			Asks:  []PriceEntry{{10, 0.011}, {10, 0.013}},
+		},
+		"bittrex": ExchangePrice{
+			Valid: true,
+			Bids:  []PriceEntry{{5, 0.009}},
+			Asks:  []PriceEntry{{5, 0.012}},
+		},
+		"huobi": ExchangePrice{Valid: false, Error: "timeout"},
+	}
+	consolidated := ConsolidatePrice(price, nil)
+	if len(consolidated.Exchanges) != 2 || len(consolidated.Bids) != 3 || len(consolidated.Asks) != 3 {
+		t.Fatalf("Expected books of 2 valid exchanges to be merged, got %+v", consolidated)
+	}
+	if consolidated.Bids[1].Exchange != "bittrex" || consolidated.Asks[1].Exchange != "bittrex" {
+		t.Fatalf("Expected merged books to be sorted by rate, got %+v", consolidated)
+	}
+	vwap := consolidated.GetVWAP(15)
+	// 10 at 0.011 on binance and 5 at 0.012 on bittrex
+	if buy := vwap.Consolidated.Buy; !buy.Complete || math.Abs(buy.Rate-(10*0.011+5*0.012)/15) > 1e-12 {
+		t.Fatalf("Unexpected consolidated buy VWAP %+v", buy)
+	}
+	if sell := vwap.Exchanges["bittrex"].Sell; sell.Complete || sell.Filled != 5 || sell.Rate != 0.009 {
+		t.Fatalf("Expected bittrex sell VWAP to be partially filled, got %+v", sell)
+	}
+	adjusted := ConsolidatePrice(price, map[ExchangeID]float64{"binance": 0.001})
+	if math.Abs(adjusted.Bids[0].Rate-0.010*0.999) > 1e-12 || math.Abs(adjusted.Asks[0].Rate-0.011*1.001) > 1e-12 {
+		t.Fatalf("Expected rates to be adjusted by fee, got %+v", adjusted)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	trade := func(eid string, tradeType string, rate float64, amount string, status string) ActivityRecord {
+		return ActivityRecord{
+			Action:         "trade",
+			ID:             ActivityID{Timepoint: 2000 * 1000000, EID: eid},
+			Destination:    "binance",
+			Params:         map[string]interface{}{"type": tradeType, "rate": rate, "amount": amount},
+			ExchangeStatus: status,
+		}
+	}
+	activities := []ActivityRecord{
+		trade("1_OMGETH", "buy", 0.02, "10", "done"),
+		trade("2_OMGETH", "buy", 0.02, "10", "done"),
+		trade("3_OMGETH", "buy", 0.02, "10", "done"),
+		trade("4_OMGETH", "sell", 0.02, "10", "done"),
+		trade("5_OMGETH", "sell", 0.02, "10", "submitted"),
+		trade("6_OMGETH", "sell", 0.02, "10", "failed"),
+	}
+	fill := func(id string, orderID string, price float64, qty float64) TradeHistory {
+		return TradeHistory{ID: id, Price: price, Qty: qty, Type: "buy", Timestamp: 2500, OrderID: orderID}
+	}
+	history := AllTradeHistory{
+		Data: map[ExchangeID]ExchangeTradeHistory{
+			"binance": ExchangeTradeHistory{
+				"OMG-ETH": []TradeHistory{
+					fill("a", "1", 0.019, 4), fill("b", "1", 0.02, 6),
+					fill("c", "3", 0.02, 5),
+					fill("d", "4", 0.018, 10),
+					fill("e", "5", 0.021, 2),
+					fill("f", "9", 0.02, 1),
+				},
+			},
+		},
+	}
+	report := Reconcile(activities, history, 1000, 3000)
+	kinds := map[string]string{}
+	for _, issue := range report.Issues {
+		kinds[issue.OrderID] = issue.Kind
+	}
+	expected := map[string]string{
+		"2": RECONCILIATION_MISSING_FILLS,
+		"3": RECONCILIATION_QUANTITY_MISMATCH,
+		"4": RECONCILIATION_PRICE_MISMATCH,
+		"9": RECONCILIATION_UNKNOWN_FILLS,
+	}
+	if len(kinds) != len(expected) || report.Matched != 1 {
+		t.Fatalf("Expected issues %v and 1 matched trade, got %v and %d", expected, kinds, report.Matched)
+	}
+	for orderID, kind := range expected {
+		if kinds[orderID] != kind {
+			t.Fatalf("Expected %s issue for order %s, got %v", kind, orderID, kinds)
+		}
+	}
+	// fills out of the window are not reported without an activity
+	if report = Reconcile(nil, history, 3000, 4000); len(report.Issues) != 0 {
+		t.Fatalf("Expected no issue out of window, got %+v", report.Issues)
+	}
+}
+
+func TestTransferTimeline(t *testing.T) {
+	deposit := NewActivityRecord(
+		"deposit", ActivityID{Timepoint: 1000 * 1000000, EID: "0x1"}, "binance",
+		map[string]interface{}{"token": "OMG", "amount": "10"},
+		map[string]interface{}{"tx": "0x1"},
+		"", "submitted", Timestamp("1000"))
+	deposit.UpdateMiningStatus("mined", BlockchainSide, Timestamp("2000"))
+	deposit.Result["blockNumber"] = float64(100)
+	deposit.UpdateExchangeStatus("done", "binance", Timestamp("5000"))
+	deposit.Result["creditBlock"] = uint64(130)
+	withdraw := NewActivityRecord(
+		"withdraw", ActivityID{Timepoint: 1500 * 1000000, EID: "w1"}, "binance",
+		map[string]interface{}{"token": "OMG", "amount": "5"},
+		map[string]interface{}{"id": "w1", "tx": "0x2", "txTime": uint64(3000)},
+		"submitted", "", Timestamp("1500"))
+	withdraw.UpdateExchangeStatus("done", "binance", Timestamp("2500"))
+	withdraw.UpdateMiningStatus("mined", BlockchainSide, Timestamp("4500"))
+	pending := NewActivityRecord(
+		"deposit", ActivityID{Timepoint: 1800 * 1000000, EID: "0x3"}, "binance",
+		map[string]interface{}{"token": "OMG", "amount": "1"},
+		map[string]interface{}{"tx": "0x3"},
+		"", "submitted", Timestamp("1800"))
+	trade := NewActivityRecord(
+		"trade", ActivityID{Timepoint: 1900 * 1000000, EID: "1_OMGETH"}, "binance",
+		map[string]interface{}{}, map[string]interface{}{},
+		"submitted", "", Timestamp("1900"))
+
+	timelines := GetTransferTimelines([]ActivityRecord{deposit, withdraw, pending, trade})
+	if len(timelines) != 3 {
+		t.Fatalf("Expected 3 transfers, got %d", len(timelines))
+	}
+	d := timelines[0]
+	if !d.Complete || d.Latency != 3000 || d.Confirmations != 30 || d.BlockNumber != 100 || d.Token != "OMG" {
+		t.Fatalf("Unexpected deposit timeline: %+v", d)
+	}
+	stages := []string{}
+	for _, s := range timelines[1].Stages {
+		stages = append(stages, s.Stage)
+	}
+	if fmt.Sprint(stages) != "[requested processed broadcast mined]" {
+		t.Fatalf("Unexpected withdraw stages: %v", stages)
+	}
+	if !timelines[1].Complete || timelines[1].Latency != 3000 || timelines[1].WithdrawID != "w1" {
+		t.Fatalf("Unexpected withdraw timeline: %+v", timelines[1])
+	}
+	if timelines[2].Complete || timelines[2].Latency != 0 {
+		t.Fatalf("Pending deposit must not be complete: %+v", timelines[2])
+	}
+
+	latencies := GetTransferLatencies(timelines)
+	if latencies["binance"]["deposit"].Count != 1 || latencies["binance"]["deposit"].Average != 3000 ||
+		latencies["binance"]["withdraw"].Max != 3000 {
+		t.Fatalf("Unexpected latencies: %+v", latencies)
+	}
+}
+
+func TestGetOpenOrders(t *testing.T) {
+	pendings := []ActivityRecord{
+		NewActivityRecord(
+			"trade", ActivityID{Timepoint: 1000, EID: "1_KNCETH"}, "binance",
+			map[string]interface{}{"type": "buy", "base": "KNC", "quote": "ETH"},
+			map[string]interface{}{}, "submitted", "", Timestamp("1")),
+		NewActivityRecord(
+			"trade", ActivityID{Timepoint: 2000, EID: "2_KNCETH"}, "binance",
+			map[string]interface{}{"type": "sell", "base": "KNC", "quote": "ETH"},
+			map[string]interface{}{}, "done", "", Timestamp("2")),
+		NewActivityRecord(
+			"deposit", ActivityID{Timepoint: 3000, EID: "0x1"}, "binance",
+			map[string]interface{}{}, map[string]interface{}{}, "", "submitted", Timestamp("3")),
+	}
+	orders := map[ExchangeID]OrderEntry{
+		"binance": OrderEntry{Valid: true, Data: []Order{
+			Order{ID: "1_KNCETH", Base: "KNC", Quote: "ETH", Side: "BUY"},
+			Order{ID: "3_OMGETH", Base: "OMG", Quote: "ETH", Side: "SELL"},
+		}},
+		"huobi": OrderEntry{Valid: true, Data: []Order{
+			Order{ID: "4_KNCETH", Base: "KNC", Quote: "ETH", Side: "sell"},
+		}},
+	}
+	all := GetOpenOrders(orders, pendings, OrderFilter{})
+	if len(all) != 3 {
+		t.Fatalf("Expected 3 open orders, got %+v", all)
+	}
+	if all[0].ID != pendings[0].ID {
+		t.Fatalf("Open order of a pending activity must keep its id, got %+v", all[0])
+	}
+	sells := GetOpenOrders(orders, pendings, OrderFilter{Side: "sell"})
+	if len(sells) != 2 {
+		t.Fatalf("Expected 2 sell orders, got %+v", sells)
+	}
+	filtered := GetOpenOrders(orders, pendings, OrderFilter{Exchange: "binance", Base: "knc", Quote: "ETH"})
+	if len(filtered) != 1 || filtered[0].ID.EID != "1_KNCETH" {
+		t.Fatalf("Expected only 1_KNCETH, got %+v", filtered)
+	}
+}
+
+func TestOrderParams(t *testing.T) {
+	if (OrderParams{}).Type() != ORDER_LIMIT {
+		t.Fatalf("Default order type must be limit")
+	}
+	if err := (OrderParams{OrderType: "stop"}).Validate(0.01); err == nil {
+		t.Fatalf("Expected unknown order type to be rejected")
+	}
+	if err := (OrderParams{OrderType: ORDER_MARKET}).Validate(0); err == nil {
+		t.Fatalf("Expected market order without rate to be rejected")
+	}
+	limitOnly := []string{ORDER_LIMIT}
+	if err := (OrderParams{}).CheckSupported("bittrex", 0.01, limitOnly, false); err != nil {
+		t.Fatalf("Expected limit order to be supported, got %s", err)
+	}
+	if err := (OrderParams{OrderType: ORDER_IOC}).CheckSupported("bittrex", 0.01, limitOnly, false); err == nil {
+		t.Fatalf("Expected ioc to be unsupported")
+	}
+	if err := (OrderParams{ClientOrderID: "a1"}).CheckSupported("bittrex", 0.01, limitOnly, false); err == nil {
+		t.Fatalf("Expected client order id to be unsupported")
+	}
+	if err := (OrderParams{OrderType: ORDER_POST_ONLY, ClientOrderID: "a1"}).CheckSupported(
+		"binance", 0.01, []string{ORDER_LIMIT, ORDER_POST_ONLY}, true); err != nil {
+		t.Fatalf("Expected post only order with client order id to be supported, got %s", err)
+	}
+}
+
+func TestRankDepositVenues(t *testing.T) {
+	hour := uint64(60 * 60 * 1000)
+	inputs := []DepositVenueInput{
+		{
+			// balance already on binance takes its best bid
+			Exchange:     "binance",
+			Supported:    true,
+			Fees:         ExchangeFees{Trading: TradingFee{"taker": 0.001}, Funding: FundingFee{Withdraw: map[string]float64{"ETH": 0.01}}},
+			Book:         ExchangePrice{Valid: true, Bids: []PriceEntry{{Quantity: 100, Rate: 0.002}, {Quantity: 100, Rate: 0.0019}}},
+			Balance:      100,
+			BalanceValid: true,
+			Latency:      TransferLatency{Count: 3, Average: hour, Max: hour},
+		},
+		{
+			Exchange:  "huobi",
+			Supported: true,
+			Fees: ExchangeFees{
+				Trading: TradingFee{"taker": 0.002},
+				Funding: FundingFee{Withdraw: map[string]float64{"ETH": 0.01}, Deposit: map[string]float64{"KNC": 1}},
+			},
+			Book:         ExchangePrice{Valid: true, Bids: []PriceEntry{{Quantity: 200, Rate: 0.00195}}},
+			BalanceValid: true,
+		},
+		{Exchange: "bittrex", Supported: false, Book: ExchangePrice{Valid: true}},
+	}
+	result := RankDepositVenues("KNC", 100, inputs)
+	if result.Recommended != "huobi" || len(result.Venues) != 3 || result.Venues[1].Exchange != "binance" || result.Venues[2].Eligible {
+		t.Fatalf("Unexpected ranking %+v", result)
+	}
+	huobi, binance := result.Venues[0], result.Venues[1]
+	if huobi.Credited != 99 || !huobi.Complete || huobi.Latency != hour {
+		t.Fatalf("Unexpected huobi venue %+v", huobi)
+	}
+	// 100 KNC sold at the second binance level
+	expected := (100*0.0019*0.999 - 0.01) * 0.999
+	if binance.Depth != 100 || binance.Score < expected-1e-12 || binance.Score > expected+1e-12 {
+		t.Fatalf("Unexpected binance venue %+v, expected score %f", binance, expected)
+	}
+}
+
+func TestCheckRateHealth(t *testing.T) {
+	state := func(updateBlock uint64, enabled bool, maxTotalImbalance float64) TokenRateState {
+		return TokenRateState{Listed: true, Enabled: enabled, UpdateBlock: updateBlock, MaxPerBlockImbalance: 100, MaxTotalImbalance: maxTotalImbalance}
+	}
+	states := map[string]TokenRateState{
+		"KNC": state(990, true, 1000),
+		"OMG": state(975, true, 1000),
+		"EOS": state(960, true, 1000),
+		"SNT": state(995, false, 0),
+	}
+	result := CheckRateHealth(1000, 30, states)
+	if result.Healthy || !result.Tokens["KNC"].Healthy || result.Tokens["KNC"].BlocksToExpiry != 20 {
+		t.Fatalf("Unexpected health %+v", result)
+	}
+	if result.Tokens["OMG"].BlocksToExpiry != 5 || !result.Tokens["EOS"].Expired || result.Tokens["EOS"].ExpiryBlock != 990 {
+		t.Fatalf("Unexpected health %+v", result.Tokens)
+	}
+	kinds := []string{}
+	for _, issue := range result.Issues {
+		kinds = append(kinds, issue.Token+" "+issue.Kind)
+	}
+	expected := []string{"EOS rate_expired", "OMG rate_expiring", "SNT token_imbalance_limit_zero", "SNT token_trade_disabled"}
+	if fmt.Sprint(kinds) != fmt.Sprint(expected) {
+		t.Fatalf("Expected issues %v, got %v", expected, kinds)
+	}
+}
+
+func TestStepFunctions(t *testing.T) {
+	valid := StepFunction{X: []float64{100, 500}, Y: []int64{0, -30}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected %+v to be valid, got %s", valid, err)
+	}
+	invalid := []StepFunction{
+		{X: []float64{100}, Y: []int64{0, -30}},
+		{X: []float64{500, 100}, Y: []int64{0, -30}},
+		{X: []float64{100}, Y: []int64{-10000}},
+		{X: make([]float64, 11), Y: make([]int64, 11)},
+	}
+	for _, function := range invalid {
+		if err := function.Validate(); err == nil {
+			t.Fatalf("Expected %+v to be invalid", function)
+		}
+	}
+	current := TokenStepFunctions{QtyBuy: valid, QtySell: valid, ImbalanceBuy: valid, ImbalanceSell: valid}
+	proposed := current
+	proposed.ImbalanceSell = StepFunction{X: []float64{100, 500}, Y: []int64{0, -50}}
+	diffs := DiffStepFunctions("KNC", current, proposed)
+	if len(diffs) != 1 || diffs[0].Type != IMBALANCE_STEP_FUNCTION || !diffs[0].ProposedBuy.Equal(valid) || !diffs[0].ProposedSell.Equal(proposed.ImbalanceSell) {
+		t.Fatalf("Unexpected diffs %+v", diffs)
+	}
+	if diffs := DiffStepFunctions("KNC", current, current); len(diffs) != 0 {
+		t.Fatalf("Expected no diff, got %+v", diffs)
+	}
+}
+
+func TestRateVolatility(t *testing.T) {
+	entry := func(block uint64, compactBuy int8) AllRateEntry {
+		return AllRateEntry{
+			Valid:       true,
+			BlockNumber: block,
+			Data: map[string]RateEntry{
+				"KNC": {BaseBuy: big.NewInt(1000000), CompactBuy: compactBuy, BaseSell: big.NewInt(1000000), CompactSell: 0},
+			},
+		}
+	}
+	// history is read newest first
+	history := []AllRateEntry{entry(3, 0), entry(2, 10), entry(1, 5)}
+	volatility := RateVolatility(history)
+	if math.Abs(volatility["KNC"]-10.0/1010) > 1e-9 {
+		t.Fatalf("Expected volatility of KNC to be %f, got %f", 10.0/1010, volatility["KNC"])
+	}
+}
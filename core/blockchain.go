@@ -19,7 +19,18 @@ type Blockchain interface {
 		sells []*big.Int,
 		block *big.Int,
 		nonce *big.Int,
-		gasPrice *big.Int) (*types.Transaction, error)
+		gasPrice *big.Int) (*types.Transaction, []ethereum.Address, error)
 	SetRateMinedNonce() (uint64, error)
+	PlanCompactRates(tokens []ethereum.Address, buys, sells []*big.Int) (common.CompactRatePlan, error)
+	SetTradeEnabled(enable bool) (*types.Transaction, error)
+	SetTokenTradeEnabled(token ethereum.Address, enable bool) (*types.Transaction, error)
+	GetTradeStatus() (common.TradeStatus, error)
+	SetQtyStepFunction(token ethereum.Address, xBuy []*big.Int, yBuy []*big.Int, xSell []*big.Int, ySell []*big.Int) (*types.Transaction, error)
+	SetImbalanceStepFunction(token ethereum.Address, xBuy []*big.Int, yBuy []*big.Int, xSell []*big.Int, ySell []*big.Int) (*types.Transaction, error)
+	GetStepFunctions(token common.Token) (common.TokenStepFunctions, error)
+	AddPricingToken(token ethereum.Address) (*types.Transaction, error)
+	SetTokenControlInfo(token ethereum.Address, minimalRecordResolution, maxPerBlockImbalance, maxTotalImbalance *big.Int) (*types.Transaction, error)
+	RegisterToken(token common.Token) error
+	TxStatus(hash ethereum.Hash) (string, uint64, error)
 	GetAddresses() *common.Addresses
 }
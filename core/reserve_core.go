@@ -49,6 +49,7 @@ func (self ReserveCore) Trade(
 	quote common.Token,
 	rate float64,
 	amount float64,
+	params common.OrderParams,
 	timepoint uint64) (common.ActivityID, float64, float64, bool, error) {
 
 	var id string
@@ -56,9 +57,12 @@ func (self ReserveCore) Trade(
 	var finished bool
 	var err error
 
-	err = sanityCheckTrading(exchange, base, quote, rate, amount)
+	err = params.Validate(rate)
 	if err == nil {
-		id, done, remaining, finished, err = exchange.Trade(tradeType, base, quote, rate, amount, timepoint)
+		err = sanityCheckTrading(exchange, base, quote, rate, amount)
+	}
+	if err == nil {
+		id, done, remaining, finished, err = exchange.Trade(tradeType, base, quote, rate, amount, params, timepoint)
 	}
 
 	var status string
@@ -77,13 +81,15 @@ func (self ReserveCore) Trade(
 		uid,
 		string(exchange.ID()),
 		map[string]interface{}{
-			"exchange":  exchange,
-			"type":      tradeType,
-			"base":      base,
-			"quote":     quote,
-			"rate":      rate,
-			"amount":    strconv.FormatFloat(amount, 'f', -1, 64),
-			"timepoint": timepoint,
+			"exchange":        exchange,
+			"type":            tradeType,
+			"base":            base,
+			"quote":           quote,
+			"rate":            rate,
+			"amount":          strconv.FormatFloat(amount, 'f', -1, 64),
+			"order_type":      params.Type(),
+			"client_order_id": params.ClientOrderID,
+			"timepoint":       timepoint,
 		}, map[string]interface{}{
 			"id":        id,
 			"done":      done,
@@ -250,6 +256,7 @@ func (self ReserveCore) SetRates(
 	var txprice string = "0"
 	var err error
 	var status string
+	var skipped []ethereum.Address
 
 	if lentokens != lenbuys || lentokens != lensells || lentokens != lenafps {
 		err = errors.New("Tokens, buys sells and afpMids must have the same length")
@@ -275,13 +282,13 @@ func (self ReserveCore) SetRates(
 					if oldNonce != nil {
 						newPrice := big.NewInt(0).Add(oldPrice, big.NewInt(10000000000))
 						log.Printf("Trying to replace old tx with new price: %s", newPrice.Text(10))
-						tx, err = self.blockchain.SetRates(
+						tx, skipped, err = self.blockchain.SetRates(
 							tokenAddrs, buys, sells, block,
 							oldNonce,
 							newPrice,
 						)
 					} else {
-						tx, err = self.blockchain.SetRates(
+						tx, skipped, err = self.blockchain.SetRates(
 							tokenAddrs, buys, sells, block,
 							nil,
 							big.NewInt(50100000000),
@@ -293,12 +300,24 @@ func (self ReserveCore) SetRates(
 	}
 	if err != nil {
 		status = "failed"
+	} else if tx == nil {
+		// rates of all tokens are unchanged so no tx is sent, rates on
+		// chain are already the ones to set
+		status = "mined"
 	} else {
 		status = "submitted"
 		txhex = tx.Hash().Hex()
 		txnonce = strconv.FormatUint(tx.Nonce(), 10)
 		txprice = tx.GasPrice().Text(10)
 	}
+	skippedTokens := []string{}
+	for _, addr := range skipped {
+		for _, token := range tokens {
+			if ethereum.HexToAddress(token.Address) == addr {
+				skippedTokens = append(skippedTokens, token.ID)
+			}
+		}
+	}
 	uid := timebasedID(txhex)
 	self.activityStorage.Record(
 		"set_rates",
@@ -310,6 +329,75 @@ func (self ReserveCore) SetRates(
 			"sells":  sells,
 			"block":  block,
 			"afpMid": afpMids,
+		}, map[string]interface{}{
+			"tx":       txhex,
+			"nonce":    txnonce,
+			"gasPrice": txprice,
+			"error":    err,
+			"skipped":  skippedTokens,
+		},
+		"",
+		status,
+		common.GetTimepoint(),
+	)
+	log.Printf(
+		"Core ----------> Set rates: ==> Result: tx: %s, skipped: %v, error: %s",
+		txhex, skippedTokens, err,
+	)
+	return uid, err
+}
+
+// PlanCompactRates returns whether target buys and sells of tokens would
+// be set as compact rates or with new base rates, without setting them.
+func (self ReserveCore) PlanCompactRates(tokens []common.Token, buys, sells []*big.Int) (common.CompactRatePlan, error) {
+	if len(tokens) != len(buys) || len(tokens) != len(sells) {
+		return common.CompactRatePlan{}, errors.New("Tokens, buys and sells must have the same length")
+	}
+	tokenAddrs := []ethereum.Address{}
+	for _, token := range tokens {
+		tokenAddrs = append(tokenAddrs, ethereum.HexToAddress(token.Address))
+	}
+	return self.blockchain.PlanCompactRates(tokenAddrs, buys, sells)
+}
+
+// SetTradeEnabled disables or enables trading of the reserve on chain, or
+// only of tokenID in the pricing contract when it is not empty.
+func (self ReserveCore) SetTradeEnabled(tokenID string, enable bool) (common.ActivityID, error) {
+	var tx *types.Transaction
+	var txhex string = ethereum.Hash{}.Hex()
+	var txnonce string = "0"
+	var txprice string = "0"
+	var err error
+	var status string
+
+	if tokenID == "" {
+		tx, err = self.blockchain.SetTradeEnabled(enable)
+	} else {
+		var token common.Token
+		token, err = common.GetToken(tokenID)
+		if err == nil && token.IsETH() {
+			err = errors.New("ETH trade can't be enabled or disabled, use the reserve instead")
+		}
+		if err == nil {
+			tx, err = self.blockchain.SetTokenTradeEnabled(ethereum.HexToAddress(token.Address), enable)
+		}
+	}
+	if err != nil {
+		status = "failed"
+	} else {
+		status = "submitted"
+		txhex = tx.Hash().Hex()
+		txnonce = strconv.FormatUint(tx.Nonce(), 10)
+		txprice = tx.GasPrice().Text(10)
+	}
+	uid := timebasedID(txhex)
+	self.activityStorage.Record(
+		"set_trade_enabled",
+		uid,
+		"blockchain",
+		map[string]interface{}{
+			"token":  tokenID,
+			"enable": enable,
 		}, map[string]interface{}{
 			"tx":       txhex,
 			"nonce":    txnonce,
@@ -321,12 +409,101 @@ func (self ReserveCore) SetRates(
 		common.GetTimepoint(),
 	)
 	log.Printf(
-		"Core ----------> Set rates: ==> Result: tx: %s, error: %s",
-		txhex, err,
+		"Core ----------> Set trade enabled: token: %s, enable: %t ==> Result: tx: %s, error: %s",
+		tokenID, enable, txhex, err,
 	)
 	return uid, err
 }
 
+// GetTradeStatus returns whether trading of the reserve and of each token
+// is enabled on chain.
+func (self ReserveCore) GetTradeStatus() (common.TradeStatus, error) {
+	return self.blockchain.GetTradeStatus()
+}
+
+// stepFunctionParams converts a step function to steps in token wei and
+// adjustments in basis points as the pricing contract takes them.
+func stepFunctionParams(token common.Token, function common.StepFunction) ([]*big.Int, []*big.Int) {
+	xs, ys := []*big.Int{}, []*big.Int{}
+	for _, x := range function.X {
+		xs = append(xs, common.FloatToBig(x, token.Decimal))
+	}
+	for _, y := range function.Y {
+		ys = append(ys, big.NewInt(y))
+	}
+	return xs, ys
+}
+
+// SetStepFunction sets the buy and sell quantity or imbalance step
+// functions of token, depending on functionType.
+func (self ReserveCore) SetStepFunction(token common.Token, functionType string, buy, sell common.StepFunction) (common.ActivityID, error) {
+	var tx *types.Transaction
+	var txhex string = ethereum.Hash{}.Hex()
+	var txnonce string = "0"
+	var txprice string = "0"
+	var err error
+	var status string
+
+	if token.IsETH() {
+		err = errors.New("ETH has no step functions")
+	} else if err = buy.Validate(); err != nil {
+		err = errors.New(fmt.Sprintf("Invalid buy step function: %s", err))
+	} else if err = sell.Validate(); err != nil {
+		err = errors.New(fmt.Sprintf("Invalid sell step function: %s", err))
+	} else {
+		xBuy, yBuy := stepFunctionParams(token, buy)
+		xSell, ySell := stepFunctionParams(token, sell)
+		addr := ethereum.HexToAddress(token.Address)
+		switch functionType {
+		case common.QTY_STEP_FUNCTION:
+			tx, err = self.blockchain.SetQtyStepFunction(addr, xBuy, yBuy, xSell, ySell)
+		case common.IMBALANCE_STEP_FUNCTION:
+			tx, err = self.blockchain.SetImbalanceStepFunction(addr, xBuy, yBuy, xSell, ySell)
+		default:
+			err = errors.New(fmt.Sprintf("Unknown step function type %s", functionType))
+		}
+	}
+	if err != nil {
+		status = "failed"
+	} else {
+		status = "submitted"
+		txhex = tx.Hash().Hex()
+		txnonce = strconv.FormatUint(tx.Nonce(), 10)
+		txprice = tx.GasPrice().Text(10)
+	}
+	uid := timebasedID(txhex)
+	self.activityStorage.Record(
+		"set_step_function",
+		uid,
+		"blockchain",
+		map[string]interface{}{
+			"token": token,
+			"type":  functionType,
+			"buy":   buy,
+			"sell":  sell,
+		}, map[string]interface{}{
+			"tx":       txhex,
+			"nonce":    txnonce,
+			"gasPrice": txprice,
+			"error":    err,
+		},
+		"",
+		status,
+		common.GetTimepoint(),
+	)
+	log.Printf(
+		"Core ----------> Set %s step function: token: %s ==> Result: tx: %s, error: %s",
+		functionType, token.ID, txhex, err,
+	)
+	return uid, err
+}
+
+// GetStepFunctions returns the step functions of token in the pricing
+// contract.
+func (self ReserveCore) GetStepFunctions(token common.Token) (common.TokenStepFunctions, error) {
+	return self.blockchain.GetStepFunctions(token)
+}
+
 func sanityCheck(buys, afpMid, sells []*big.Int) error {
 	eth := big.NewFloat(0).SetInt(big.NewInt(1000000000000000000))
 	for i, s := range sells {
@@ -352,8 +529,12 @@ func sanityCheck(buys, afpMid, sells []*big.Int) error {
 }
 
 func sanityCheckTrading(exchange common.Exchange, base, quote common.Token, rate, amount float64) error {
-	tokenPairID := makeTokenPair(base.ID, quote.ID)
+	tokenPairID := common.NewTokenPairID(base.ID, quote.ID)
 	exchangeInfo, err := exchange.GetExchangeInfo(tokenPairID)
+	if err != nil && base.IsETH() {
+		// pairs against ETH have ETH as quote unless declared otherwise
+		exchangeInfo, err = exchange.GetExchangeInfo(common.NewTokenPairID(quote.ID, base.ID))
+	}
 	if err != nil {
 		return err
 	}
@@ -397,10 +578,3 @@ func checkZeroValue(buy, sell *big.Int) int {
 	}
 	return -1
 }
-
-func makeTokenPair(base, quote string) common.TokenPairID {
-	if base == "ETH" {
-		return common.NewTokenPairID(quote, base)
-	}
-	return common.NewTokenPairID(base, quote)
-}
@@ -21,7 +21,7 @@ func (self testExchange) Address(token common.Token) (address ethereum.Address,
 func (self testExchange) Withdraw(token common.Token, amount *big.Int, address ethereum.Address, timepoint uint64) (string, error) {
 	return "withdrawid", nil
 }
-func (self testExchange) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error) {
+func (self testExchange) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, params common.OrderParams, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error) {
 	return "tradeid", 10, 5, false, nil
 }
 func (self testExchange) CancelOrder(id common.ActivityID) error {
@@ -68,7 +68,7 @@ func (self testBlockchain) SetRates(
 	sells []*big.Int,
 	block *big.Int,
 	nonce *big.Int,
-	gasPrice *big.Int) (*types.Transaction, error) {
+	gasPrice *big.Int) (*types.Transaction, []ethereum.Address, error) {
 	tx := types.NewTransaction(
 		0,
 		ethereum.Address{},
@@ -76,13 +76,57 @@ func (self testBlockchain) SetRates(
 		big.NewInt(300000),
 		big.NewInt(1000000000),
 		[]byte{})
-	return tx, nil
+	return tx, []ethereum.Address{}, nil
 }
 
 func (self testBlockchain) SetRateMinedNonce() (uint64, error) {
 	return 0, nil
 }
 
+func (self testBlockchain) PlanCompactRates(tokens []ethereum.Address, buys, sells []*big.Int) (common.CompactRatePlan, error) {
+	return common.CompactRatePlan{}, nil
+}
+
+func (self testBlockchain) SetTradeEnabled(enable bool) (*types.Transaction, error) {
+	return types.NewTransaction(0, ethereum.Address{}, big.NewInt(0), big.NewInt(300000), big.NewInt(1000000000), []byte{}), nil
+}
+
+func (self testBlockchain) SetTokenTradeEnabled(token ethereum.Address, enable bool) (*types.Transaction, error) {
+	return types.NewTransaction(0, ethereum.Address{}, big.NewInt(0), big.NewInt(300000), big.NewInt(1000000000), []byte{}), nil
+}
+
+func (self testBlockchain) GetTradeStatus() (common.TradeStatus, error) {
+	return common.TradeStatus{TradeEnabled: true, Tokens: map[string]bool{}}, nil
+}
+
+func (self testBlockchain) SetQtyStepFunction(token ethereum.Address, xBuy []*big.Int, yBuy []*big.Int, xSell []*big.Int, ySell []*big.Int) (*types.Transaction, error) {
+	return types.NewTransaction(0, ethereum.Address{}, big.NewInt(0), big.NewInt(300000), big.NewInt(1000000000), []byte{}), nil
+}
+
+func (self testBlockchain) SetImbalanceStepFunction(token ethereum.Address, xBuy []*big.Int, yBuy []*big.Int, xSell []*big.Int, ySell []*big.Int) (*types.Transaction, error) {
+	return types.NewTransaction(0, ethereum.Address{}, big.NewInt(0), big.NewInt(300000), big.NewInt(1000000000), []byte{}), nil
+}
+
+func (self testBlockchain) GetStepFunctions(token common.Token) (common.TokenStepFunctions, error) {
+	return common.TokenStepFunctions{}, nil
+}
+
+func (self testBlockchain) AddPricingToken(token ethereum.Address) (*types.Transaction, error) {
+	return types.NewTransaction(0, ethereum.Address{}, big.NewInt(0), big.NewInt(300000), big.NewInt(1000000000), []byte{}), nil
+}
+
+func (self testBlockchain) SetTokenControlInfo(token ethereum.Address, minimalRecordResolution, maxPerBlockImbalance, maxTotalImbalance *big.Int) (*types.Transaction, error) {
+	return types.NewTransaction(0, ethereum.Address{}, big.NewInt(0), big.NewInt(300000), big.NewInt(1000000000), []byte{}), nil
+}
+
+func (self testBlockchain) RegisterToken(token common.Token) error {
+	return nil
+}
+
+func (self testBlockchain) TxStatus(hash ethereum.Hash) (string, uint64, error) {
+	return "mined", 0, nil
+}
+
 func (self testBlockchain) GetAddresses() *common.Addresses {
 	return &common.Addresses{}
 }
@@ -146,3 +190,64 @@ func TestNotAllowDeposit(t *testing.T) {
 		t.Fatalf("Expected to be able to deposit different token")
 	}
 }
+
+func TestSetTradeEnabled(t *testing.T) {
+	common.SupportedTokens = map[string]common.Token{
+		"ETH": {ID: "ETH", Address: "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", Decimal: 18},
+		"KNC": {ID: "KNC", Address: "0x1111111111111111111111111111111111111111", Decimal: 18},
+	}
+	core := getTestCore(false)
+	if _, err := core.SetTradeEnabled("", false); err != nil {
+		t.Fatalf("Expected to be able to disable trade of the reserve: %s", err)
+	}
+	if _, err := core.SetTradeEnabled("KNC", false); err != nil {
+		t.Fatalf("Expected to be able to disable trade of a token: %s", err)
+	}
+	for _, token := range []string{"ETH", "XYZ"} {
+		if _, err := core.SetTradeEnabled(token, true); err == nil {
+			t.Fatalf("Expected to return an error enabling trade of %s", token)
+		}
+	}
+}
+
+func TestStepFunctionParams(t *testing.T) {
+	token := common.Token{ID: "KNC", Address: "0x1111111111111111111111111111111111111111", Decimal: 18}
+	xs, ys := stepFunctionParams(token, common.StepFunction{X: []float64{-0.1, 100}, Y: []int64{-30, 0}})
+	if xs[0].String() != "-100000000000000000" || xs[1].String() != "100000000000000000000" || ys[0].Int64() != -30 {
+		t.Fatalf("Unexpected params %v %v", xs, ys)
+	}
+	core := getTestCore(false)
+	invalid := common.StepFunction{X: []float64{100, 50}, Y: []int64{0, 10}}
+	if _, err := core.SetStepFunction(token, common.QTY_STEP_FUNCTION, invalid, common.StepFunction{}); err == nil {
+		t.Fatalf("Expected to return an error setting steps which are not increasing")
+	}
+}
+
+func TestListToken(t *testing.T) {
+	common.SupportedTokens = map[string]common.Token{
+		"ETH": {ID: "ETH", Address: "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", Decimal: 18},
+	}
+	listing := common.TokenListing{
+		ID:                      "KNC",
+		Address:                 "0x1111111111111111111111111111111111111111",
+		Decimals:                18,
+		MinimalRecordResolution: 0.0001,
+		MaxPerBlockImbalance:    1000,
+		MaxTotalImbalance:       3000,
+	}
+	core := getTestCore(false)
+	ids, err := core.ListToken(listing)
+	if err != nil {
+		t.Fatalf("Expected to be able to list a token: %s", err)
+	}
+	if len(ids) != 5 {
+		t.Fatalf("Expected 5 listing steps, got %d", len(ids))
+	}
+	if token, err := common.GetToken("KNC"); err != nil || token.Address != listing.Address {
+		t.Fatalf("Expected KNC to be supported after listing, got %+v, %v", token, err)
+	}
+	listing.MaxTotalImbalance = 0
+	if ids, err = core.ListToken(listing); err == nil || len(ids) != 0 {
+		t.Fatalf("Expected to return an error listing a token without imbalance limit")
+	}
+}
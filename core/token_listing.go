@@ -0,0 +1,191 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	ethereum "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// LISTING_POLL_INTERVAL is the time between two checks of the
+	// transaction of a listing step
+	LISTING_POLL_INTERVAL time.Duration = 5 * time.Second
+	// LISTING_STEP_TIMEOUT is how long a listing step waits for its
+	// transaction to be mined
+	LISTING_STEP_TIMEOUT time.Duration = 10 * time.Minute
+)
+
+// waitMined waits for tx to be mined, it returns an error if tx fails or
+// isn't mined within LISTING_STEP_TIMEOUT.
+func (self ReserveCore) waitMined(tx *types.Transaction) error {
+	deadline := time.Now().Add(LISTING_STEP_TIMEOUT)
+	for {
+		status, _, err := self.blockchain.TxStatus(tx.Hash())
+		if err != nil {
+			log.Printf("Getting status of tx %s failed: %s", tx.Hash().Hex(), err)
+		}
+		switch status {
+		case "mined":
+			return nil
+		case "failed":
+			return errors.New(fmt.Sprintf("Transaction %s failed", tx.Hash().Hex()))
+		}
+		if time.Now().After(deadline) {
+			return errors.New(fmt.Sprintf("Transaction %s is not mined after %s", tx.Hash().Hex(), LISTING_STEP_TIMEOUT))
+		}
+		time.Sleep(LISTING_POLL_INTERVAL)
+	}
+}
+
+// listingStep sends the transaction of a step of listing token, records
+// it as a list_token activity and waits for it to be mined.
+func (self ReserveCore) listingStep(token common.Token, step string, send func() (*types.Transaction, error)) (common.ActivityID, error) {
+	var txhex string = ethereum.Hash{}.Hex()
+	var txnonce string = "0"
+	var txprice string = "0"
+	var status string
+
+	tx, err := send()
+	if err != nil {
+		status = "failed"
+	} else {
+		status = "submitted"
+		txhex = tx.Hash().Hex()
+		txnonce = strconv.FormatUint(tx.Nonce(), 10)
+		txprice = tx.GasPrice().Text(10)
+	}
+	uid := timebasedID(txhex)
+	self.activityStorage.Record(
+		"list_token",
+		uid,
+		"blockchain",
+		map[string]interface{}{
+			"token": token,
+			"step":  step,
+		}, map[string]interface{}{
+			"tx":       txhex,
+			"nonce":    txnonce,
+			"gasPrice": txprice,
+			"error":    err,
+		},
+		"",
+		status,
+		common.GetTimepoint(),
+	)
+	log.Printf(
+		"Core ----------> List token %s, %s ==> Result: tx: %s, error: %s",
+		token.ID, step, txhex, err,
+	)
+	if err != nil {
+		return uid, err
+	}
+	return uid, self.waitMined(tx)
+}
+
+// RegisterListingOnExchanges adds deposit addresses and pairs of a listed
+// token to exchanges.
+func RegisterListingOnExchanges(listing common.TokenListing) error {
+	token := listing.Token()
+	for exchangeID, address := range listing.DepositAddresses {
+		exchange, err := common.GetExchange(exchangeID)
+		if err != nil {
+			return err
+		}
+		exchange.UpdateDepositAddress(token, address)
+	}
+	for exchangeID, quotes := range listing.Pairs {
+		exchange, err := common.GetExchange(exchangeID)
+		if err != nil {
+			return err
+		}
+		adder, ok := exchange.(common.TokenPairAdder)
+		if !ok {
+			return errors.New(fmt.Sprintf("Pairs can't be added to %s while it is running", exchangeID))
+		}
+		for _, quote := range quotes {
+			pair, err := common.NewTokenPair(token.ID, quote)
+			if err != nil {
+				return err
+			}
+			adder.AddTokenPair(pair)
+		}
+	}
+	return nil
+}
+
+// registerToken adds a listed token to the tokens of the blockchain with
+// its compact data index and to the pairs of exchanges, then to the
+// reserve tokens, without a restart. The token is only visible to fetchers
+// once everything it needs is set.
+func (self ReserveCore) registerToken(listing common.TokenListing) error {
+	token := listing.Token()
+	if err := self.blockchain.RegisterToken(token); err != nil {
+		return err
+	}
+	if err := RegisterListingOnExchanges(listing); err != nil {
+		return err
+	}
+	common.AddSupportedToken(token)
+	return nil
+}
+
+// ListToken lists a token in the pricing contract: it adds the token, sets
+// its control info and step functions then enables its trade, each step
+// once the previous one is mined. The token is then registered so it is
+// fetched and rated without a restart. It returns the list_token
+// activities of the steps done, and stops at the first failed step.
+func (self ReserveCore) ListToken(listing common.TokenListing) ([]common.ActivityID, error) {
+	ids := []common.ActivityID{}
+	if err := listing.Validate(); err != nil {
+		return ids, err
+	}
+	token := listing.Token()
+	addr := ethereum.HexToAddress(token.Address)
+	functions := listing.StepFunctions
+	qtyBuyX, qtyBuyY := stepFunctionParams(token, common.DefaultStepFunction(functions.QtyBuy))
+	qtySellX, qtySellY := stepFunctionParams(token, common.DefaultStepFunction(functions.QtySell))
+	imbalanceBuyX, imbalanceBuyY := stepFunctionParams(token, common.DefaultStepFunction(functions.ImbalanceBuy))
+	imbalanceSellX, imbalanceSellY := stepFunctionParams(token, common.DefaultStepFunction(functions.ImbalanceSell))
+	steps := []struct {
+		name string
+		send func() (*types.Transaction, error)
+	}{
+		{common.LISTING_ADD_TOKEN, func() (*types.Transaction, error) {
+			return self.blockchain.AddPricingToken(addr)
+		}},
+		{common.LISTING_SET_CONTROL_INFO, func() (*types.Transaction, error) {
+			return self.blockchain.SetTokenControlInfo(
+				addr,
+				common.FloatToBig(listing.MinimalRecordResolution, token.Decimal),
+				common.FloatToBig(listing.MaxPerBlockImbalance, token.Decimal),
+				common.FloatToBig(listing.MaxTotalImbalance, token.Decimal),
+			)
+		}},
+		{common.LISTING_SET_QTY_STEPS, func() (*types.Transaction, error) {
+			return self.blockchain.SetQtyStepFunction(addr, qtyBuyX, qtyBuyY, qtySellX, qtySellY)
+		}},
+		{common.LISTING_SET_IMBALANCE_STEPS, func() (*types.Transaction, error) {
+			return self.blockchain.SetImbalanceStepFunction(addr, imbalanceBuyX, imbalanceBuyY, imbalanceSellX, imbalanceSellY)
+		}},
+		{common.LISTING_ENABLE_TOKEN_TRADE, func() (*types.Transaction, error) {
+			return self.blockchain.SetTokenTradeEnabled(addr, true)
+		}},
+	}
+	for _, step := range steps {
+		id, err := self.listingStep(token, step.name, step.send)
+		ids = append(ids, id)
+		if err != nil {
+			return ids, errors.New(fmt.Sprintf("%s of %s failed: %s", step.name, token.ID, err))
+		}
+	}
+	if err := self.registerToken(listing); err != nil {
+		return ids, errors.New(fmt.Sprintf("%s is listed on chain but registering it failed: %s", token.ID, err))
+	}
+	return ids, nil
+}
@@ -0,0 +1,45 @@
+package data
+
+import (
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// GetConsolidatedPrice merges books of a pair across exchanges at the
+// given price version, or at the version of timepoint if version is 0.
+// fees are taker fees of exchanges to adjust rates with, nil means no
+// adjustment. VWAPs are calculated when quantity is positive.
+func (self ReserveData) GetConsolidatedPrice(
+	pairID common.TokenPairID,
+	version common.Version,
+	timepoint uint64,
+	fees map[common.ExchangeID]float64,
+	quantity float64) (common.ConsolidatedPriceResponse, error) {
+
+	timestamp := common.GetTimestamp()
+	var err error
+	if version == 0 {
+		version, err = self.storage.CurrentPriceVersion(timepoint)
+		if err != nil {
+			return common.ConsolidatedPriceResponse{}, err
+		}
+	}
+	data, err := self.storage.GetOnePrice(pairID, version)
+	if err != nil {
+		return common.ConsolidatedPriceResponse{}, err
+	}
+	consolidated := common.ConsolidatePrice(data, fees)
+	result := common.ConsolidatedPriceResponse{
+		Version:     version,
+		Timestamp:   timestamp,
+		FeeAdjusted: fees != nil,
+		Exchanges:   consolidated.Exchanges,
+		Bids:        consolidated.Bids,
+		Asks:        consolidated.Asks,
+	}
+	if quantity > 0 {
+		vwap := consolidated.GetVWAP(quantity)
+		result.VWAP = &vwap
+	}
+	result.ReturnTime = common.GetTimestamp()
+	return result, nil
+}
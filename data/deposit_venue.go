@@ -0,0 +1,91 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// LATENCY_DAYS is the number of days of past deposits credit latency is
+// averaged over
+const LATENCY_DAYS uint64 = 7
+
+const day uint64 = 24 * 60 * 60 * 1000
+
+// depositLatencies returns credit latency of deposits of token to each
+// exchange requested in the last LATENCY_DAYS before timepoint.
+func (self ReserveData) depositLatencies(token string, timepoint uint64) (map[string]common.TransferLatency, error) {
+	timelines := []common.TransferTimeline{}
+	// activities can be read one day at a time
+	for i := uint64(0); i < LATENCY_DAYS && timepoint > i*day; i++ {
+		toTime := timepoint - i*day
+		fromTime := uint64(0)
+		if toTime > day {
+			fromTime = toTime - day
+		}
+		dayTimelines, err := self.GetTransfers(fromTime, toTime)
+		if err != nil {
+			return map[string]common.TransferLatency{}, err
+		}
+		for _, timeline := range dayTimelines {
+			if timeline.Action == "deposit" && timeline.Token == token {
+				timelines = append(timelines, timeline)
+			}
+		}
+	}
+	result := map[string]common.TransferLatency{}
+	for exchange, latencies := range common.GetTransferLatencies(timelines) {
+		result[exchange] = latencies["deposit"]
+	}
+	return result, nil
+}
+
+// GetDepositVenues ranks exchanges to deposit amount of token to and sell
+// it against ETH, by their fees, the latest books and balances, and credit
+// latency of past deposits of the token.
+func (self ReserveData) GetDepositVenues(token common.Token, amount float64, exchanges []common.Exchange, timepoint uint64) (common.DepositRecommendation, error) {
+	if token.IsETH() {
+		return common.DepositRecommendation{}, errors.New("ETH can't be sold against ETH")
+	}
+	if amount <= 0 {
+		return common.DepositRecommendation{}, errors.New(fmt.Sprintf("Invalid amount %f", amount))
+	}
+	version, err := self.storage.CurrentPriceVersion(timepoint)
+	if err != nil {
+		return common.DepositRecommendation{}, err
+	}
+	price, err := self.storage.GetOnePrice(common.NewTokenPairID(token.ID, "ETH"), version)
+	if err != nil {
+		return common.DepositRecommendation{}, err
+	}
+	version, err = self.storage.CurrentAuthDataVersion(timepoint)
+	if err != nil {
+		return common.DepositRecommendation{}, err
+	}
+	authData, err := self.storage.GetAuthData(version)
+	if err != nil {
+		return common.DepositRecommendation{}, err
+	}
+	latencies, err := self.depositLatencies(token.ID, timepoint)
+	if err != nil {
+		return common.DepositRecommendation{}, err
+	}
+	inputs := []common.DepositVenueInput{}
+	for _, exchange := range exchanges {
+		_, supported := exchange.Address(token)
+		input := common.DepositVenueInput{
+			Exchange:  exchange.ID(),
+			Supported: supported,
+			Fees:      exchange.GetFee(),
+			Book:      price[exchange.ID()],
+			Latency:   latencies[string(exchange.ID())],
+		}
+		if balance, found := authData.ExchangeBalances[exchange.ID()]; found && balance.Valid {
+			input.Balance = balance.AvailableBalance[token.ID]
+			input.BalanceValid = true
+		}
+		inputs = append(inputs, input)
+	}
+	return common.RankDepositVenues(token.ID, amount, inputs), nil
+}
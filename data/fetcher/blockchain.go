@@ -14,4 +14,7 @@ type Blockchain interface {
 	TxStatus(tx ethereum.Hash) (string, uint64, error)
 	CurrentBlock() (uint64, error)
 	SetRateMinedNonce() (uint64, error)
+	// fetch rate validity duration and state of tokens in the pricing
+	// contract at specific block
+	FetchRateStates(atBlock uint64) (uint64, map[string]common.TokenRateState, error)
 }
@@ -10,8 +10,11 @@ type Exchange interface {
 	TokenPairs() []common.TokenPair
 	FetchPriceData(timepoint uint64) (map[common.TokenPairID]common.ExchangePrice, error)
 	FetchEBalanceData(timepoint uint64) (common.EBalanceEntry, error)
-	FetchTradeHistory(timepoint uint64) (map[common.TokenPairID][]common.TradeHistory, error)
-	// FetchOrderData(timepoint uint64) (common.OrderEntry, error)
+	// FetchTradeHistory returns trades done after the given last trade of
+	// each pair, pairs without a last trade are fetched from the beginning
+	FetchTradeHistory(timepoint uint64, lastTrades map[common.TokenPairID]common.TradeHistory) (map[common.TokenPairID][]common.TradeHistory, error)
+	// FetchOrderData returns open orders of every pair of the exchange
+	FetchOrderData(timepoint uint64) (common.OrderEntry, error)
 	OrderStatus(id common.ActivityID, timepoint uint64) (string, error)
 	DepositStatus(id common.ActivityID, timepoint uint64) (string, error)
 	WithdrawStatus(id common.ActivityID, timepoint uint64) (string, string, error)
@@ -1,12 +1,14 @@
 package fetcher
 
 import (
+	"fmt"
 	"log"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
 	ethereum "github.com/ethereum/go-ethereum/common"
 )
 
@@ -19,6 +21,13 @@ type Fetcher struct {
 	currentBlock           uint64
 	currentBlockUpdateTime uint64
 	simulationMode         bool
+	activityTTLs           map[string]time.Duration
+	metricDepths           map[string]float64
+	alerter                common.Alerter
+	lastReconciliation     uint64
+	reconciliationAlerts   map[string]bool
+	lastRateHealth         uint64
+	rateHealthAlerts       map[string]bool
 }
 
 func NewFetcher(
@@ -33,9 +42,31 @@ func NewFetcher(
 		runner:         runner,
 		rmaddr:         address,
 		simulationMode: simulationMode,
+		activityTTLs:   common.DefaultActivityTTLs,
+		metricDepths:   map[string]float64{},
+		alerter:        common.NewLogAlerter(),
+
+		reconciliationAlerts: map[string]bool{},
+		rateHealthAlerts:     map[string]bool{},
 	}
 }
 
+// SetActivityTTLs overrides the time activities of each action can stay
+// pending before they are expired.
+func (self *Fetcher) SetActivityTTLs(ttls map[string]time.Duration) {
+	self.activityTTLs = ttls
+}
+
+// SetMetricDepths sets the quantity of each token that afp mid and spread
+// computed by core are weighted to, tokens without depth use top of books.
+func (self *Fetcher) SetMetricDepths(depths map[string]float64) {
+	self.metricDepths = depths
+}
+
+func (self *Fetcher) SetAlerter(alerter common.Alerter) {
+	self.alerter = alerter
+}
+
 func (self *Fetcher) SetBlockchain(blockchain Blockchain) {
 	self.blockchain = blockchain
 	self.FetchCurrentBlock(common.GetTimepoint())
@@ -57,6 +88,7 @@ func (self *Fetcher) Run() error {
 	go self.RunRateFetcher()
 	go self.RunBlockFetcher()
 	go self.RunTradeHistoryFetcher()
+	go self.RunMetricFetcher()
 	log.Printf("Fetcher runner is running...")
 	return nil
 }
@@ -69,6 +101,9 @@ func (self *Fetcher) RunBlockFetcher() {
 		timepoint := common.TimeToTimepoint(t)
 		self.FetchCurrentBlock(timepoint)
 		log.Printf("fetched block from blockchain")
+		if timepoint >= self.lastRateHealth+RATE_HEALTH_INTERVAL {
+			self.CheckRateHealth(timepoint)
+		}
 	}
 }
 
@@ -120,12 +155,14 @@ func (self *Fetcher) FetchAllAuthData(timepoint uint64) {
 		Valid:             true,
 		Timestamp:         common.GetTimestamp(),
 		ExchangeBalances:  map[common.ExchangeID]common.EBalanceEntry{},
+		ExchangeOrders:    map[common.ExchangeID]common.OrderEntry{},
 		ReserveBalances:   map[string]common.BalanceEntry{},
 		PendingActivities: []common.ActivityRecord{},
 		Block:             0,
 	}
 	bbalances := map[string]common.BalanceEntry{}
 	ebalances := sync.Map{}
+	eorders := sync.Map{}
 	estatuses := sync.Map{}
 	bstatuses := sync.Map{}
 	pendings, err := self.storage.GetPendingActivities()
@@ -137,10 +174,16 @@ func (self *Fetcher) FetchAllAuthData(timepoint uint64) {
 	for _, exchange := range self.exchanges {
 		wait.Add(1)
 		go self.FetchAuthDataFromExchange(
-			&wait, exchange, &ebalances, &estatuses,
+			&wait, exchange, &ebalances, &eorders, &estatuses,
 			pendings, timepoint)
 	}
 	wait.Wait()
+	// open orders are informative, failing to fetch them doesn't
+	// invalidate the snapshot
+	eorders.Range(func(key, value interface{}) bool {
+		snapshot.ExchangeOrders[key.(common.ExchangeID)] = value.(common.OrderEntry)
+		return true
+	})
 	self.FetchAuthDataFromBlockchain(
 		bbalances, &bstatuses, pendings, timepoint)
 	snapshot.Block = self.currentBlock
@@ -161,7 +204,12 @@ func (self *Fetcher) FetchTradeHistoryFromExchange(
 	timepoint uint64) {
 
 	defer wait.Done()
-	tradeHistory, err := exchange.FetchTradeHistory(timepoint)
+	lastTrades, err := self.storage.GetLastTradeHistory(exchange.ID())
+	if err != nil {
+		log.Printf("Get last trades of %s failed, fetching its whole trade history: %s", exchange.ID(), err)
+		lastTrades = map[common.TokenPairID]common.TradeHistory{}
+	}
+	tradeHistory, err := exchange.FetchTradeHistory(timepoint, lastTrades)
 	if err != nil {
 		log.Printf("Fetch trade history from exchange failed: %s", err.Error())
 	}
@@ -199,6 +247,62 @@ func (self *Fetcher) RunTradeHistoryFetcher() {
 		log.Printf("got signal in trade history channel with timestamp %d", common.TimeToTimepoint(t))
 		self.FetchAllTradeHistory(common.TimeToTimepoint(t))
 		log.Printf("fetched trade history from exchanges")
+		if common.TimeToTimepoint(t) >= self.lastReconciliation+RECONCILIATION_INTERVAL {
+			self.ReconcileTrades(common.TimeToTimepoint(t))
+		}
+	}
+}
+
+func (self *Fetcher) RunMetricFetcher() {
+	for {
+		log.Printf("waiting for signal from runner metric channel")
+		t := <-self.runner.GetMetricTicker()
+		log.Printf("got signal in metric channel with timestamp %d", common.TimeToTimepoint(t))
+		self.FetchMetric(common.TimeToTimepoint(t))
+		log.Printf("computed metrics from stored order books")
+	}
+}
+
+// FetchMetric computes afp mid and spread of supported tokens from the
+// latest order books stored before timepoint and stores them as metrics
+// of core source.
+func (self *Fetcher) FetchMetric(timepoint uint64) {
+	version, err := self.storage.CurrentPriceVersion(timepoint)
+	if err != nil {
+		log.Printf("Getting price version failed: %s", err)
+		return
+	}
+	prices, err := self.storage.GetAllPrices(version)
+	if err != nil {
+		log.Printf("Getting prices failed: %s", err)
+		return
+	}
+	entry := metric.MetricEntry{
+		Timestamp: timepoint,
+		Source:    metric.CORE_SOURCE,
+		Data:      map[string]metric.TokenMetric{},
+	}
+	for _, token := range common.GetSupportedTokens() {
+		if token.IsETH() {
+			continue
+		}
+		price, found := prices.Data[common.NewTokenPairID(token.ID, "ETH")]
+		if !found {
+			continue
+		}
+		tokenMetric, err := metric.ComputeTokenMetric(price, self.metricDepths[token.ID])
+		if err != nil {
+			log.Printf("Computing metric of %s failed: %s", token.ID, err)
+			continue
+		}
+		entry.Data[token.ID] = tokenMetric
+	}
+	if len(entry.Data) == 0 {
+		return
+	}
+	err = self.storage.StoreMetric(&entry, timepoint)
+	if err != nil {
+		log.Printf("Storing metric failed: %s", err)
 	}
 }
 
@@ -260,7 +364,7 @@ func (self *Fetcher) FetchStatusFromBlockchain(pendings []common.ActivityRecord)
 		log.Printf("Getting mined nonce failed: %s", nerr)
 	}
 	for _, activity := range pendings {
-		if activity.IsBlockchainPending() && (activity.Action == "set_rates" || activity.Action == "deposit" || activity.Action == "withdraw") {
+		if activity.IsBlockchainPending() && (activity.Action == "set_rates" || activity.Action == "deposit" || activity.Action == "withdraw" || activity.Action == "set_trade_enabled" || activity.Action == "set_step_function" || activity.Action == "list_token") {
 			var blockNum uint64
 			var status string
 			var err error
@@ -362,8 +466,15 @@ func (self *Fetcher) PersistSnapshot(
 		return true
 	})
 
+	timestamp := common.Timestamp(strconv.FormatUint(timepoint, 10))
 	pendingActivities := []common.ActivityRecord{}
 	for _, activity := range pendings {
+		// parent orders are updated by execution, their trades are
+		// fetched as other trades
+		if activity.Action == "parent_order" {
+			pendingActivities = append(pendingActivities, activity)
+			continue
+		}
 		status, _ := estatuses.Load(activity.ID)
 		var activityStatus common.ActivityStatus
 		if status != nil {
@@ -371,10 +482,21 @@ func (self *Fetcher) PersistSnapshot(
 			log.Printf("In PersistSnapshot: exchange activity status for %+v: %+v", activity.ID, activityStatus)
 			if activityStatus.Error == nil {
 				if activity.IsExchangePending() {
-					activity.ExchangeStatus = activityStatus.ExchangeStatus
+					err := activity.UpdateExchangeStatus(activityStatus.ExchangeStatus, activity.Destination, timestamp)
+					if err != nil {
+						log.Printf("In PersistSnapshot: %s", err)
+					}
+					// block of the auth data a deposit is credited in, to
+					// know confirmations required by the exchange
+					if activity.Action == "deposit" && activity.ExchangeStatus == string(common.ActivityStateDone) {
+						activity.Result["creditBlock"] = snapshot.Block
+					}
 				}
 				if activity.Result["tx"] != nil && activity.Result["tx"].(string) == "" {
 					activity.Result["tx"] = activityStatus.Tx
+					if activityStatus.Tx != "" {
+						activity.Result["txTime"] = timepoint
+					}
 				}
 			} else {
 				snapshot.Valid = false
@@ -387,18 +509,36 @@ func (self *Fetcher) PersistSnapshot(
 			log.Printf("In PersistSnapshot: blockchain activity status for %+v: %+v", activity.ID, activityStatus)
 			if activityStatus.Error == nil {
 				if activity.IsBlockchainPending() {
-					activity.MiningStatus = activityStatus.MiningStatus
+					err := activity.UpdateMiningStatus(activityStatus.MiningStatus, common.BlockchainSide, timestamp)
+					if err != nil {
+						log.Printf("In PersistSnapshot: %s", err)
+					}
 				}
 			} else {
 				snapshot.Valid = false
 				snapshot.Error = activityStatus.Error.Error()
 			}
 		}
+		// expired activities are not polled anymore, they stay expired
+		// until an operator resolves them
+		if activity.IsOverdue(self.activityTTLs, timepoint) {
+			if err := activity.Expire(common.TimeoutSource, timestamp); err != nil {
+				log.Printf("In PersistSnapshot: %s", err)
+			} else {
+				self.alerter.Alert("activity_expired", fmt.Sprintf(
+					"%s activity %s on %s is still pending after %s, it is expired and needs to be resolved manually",
+					activity.Action, activity.ID, activity.Destination, self.activityTTLs[activity.Action]))
+			}
+		}
 		log.Printf("Aggregate statuses, final activity: %+v", activity)
 		if activity.IsPending() {
 			pendingActivities = append(pendingActivities, activity)
 		}
-		activity.Result["blockNumber"] = activityStatus.BlockNumber
+		// mined activities have no blockchain status anymore, keep their
+		// block number
+		if activityStatus.BlockNumber != 0 || activity.Result["blockNumber"] == nil {
+			activity.Result["blockNumber"] = activityStatus.BlockNumber
+		}
 		err := self.storage.UpdateActivity(activity.ID, activity)
 		if err != nil {
 			snapshot.Valid = false
@@ -414,10 +554,17 @@ func (self *Fetcher) PersistSnapshot(
 
 func (self *Fetcher) FetchAuthDataFromExchange(
 	wg *sync.WaitGroup, exchange Exchange,
-	allBalances *sync.Map, allStatuses *sync.Map,
+	allBalances *sync.Map, allOrders *sync.Map, allStatuses *sync.Map,
 	pendings []common.ActivityRecord,
 	timepoint uint64) {
 	defer wg.Done()
+	orders, err := exchange.FetchOrderData(timepoint)
+	if err != nil {
+		log.Printf("Fetching open orders from %s failed: %v\n", exchange.Name(), err)
+		orders.Valid = false
+		orders.Error = err.Error()
+	}
+	allOrders.Store(exchange.ID(), orders)
 	// we apply double check strategy to mitigate race condition on exchange side like this:
 	// 1. Get list of pending activity status (A)
 	// 2. Get list of balances (B)
@@ -425,7 +572,6 @@ func (self *Fetcher) FetchAuthDataFromExchange(
 	// 4. if C != A, repeat 1, otherwise return A, B
 	var balances common.EBalanceEntry
 	var statuses map[common.ActivityID]common.ActivityStatus
-	var err error
 	for {
 		preStatuses := self.FetchStatusFromExchange(exchange, pendings, timepoint)
 		balances, err = exchange.FetchEBalanceData(timepoint)
@@ -14,6 +14,7 @@ type HttpRunner struct {
 	rticker chan time.Time
 	bticker chan time.Time
 	tticker chan time.Time
+	mticker chan time.Time
 	server  *HttpRunnerServer
 }
 
@@ -35,6 +36,9 @@ func (self *HttpRunner) GetRateTicker() <-chan time.Time {
 func (self *HttpRunner) GetTradeHistoryTicker() <-chan time.Time {
 	return self.tticker
 }
+func (self *HttpRunner) GetMetricTicker() <-chan time.Time {
+	return self.mticker
+}
 
 func (self *HttpRunner) Start() error {
 	if self.server != nil {
@@ -67,6 +71,7 @@ func NewHttpRunner(port int) *HttpRunner {
 	rchan := make(chan time.Time)
 	bchan := make(chan time.Time)
 	tchan := make(chan time.Time)
+	mchan := make(chan time.Time)
 	runner := HttpRunner{
 		port,
 		ochan,
@@ -74,6 +79,7 @@ func NewHttpRunner(port int) *HttpRunner {
 		rchan,
 		bchan,
 		tchan,
+		mchan,
 		nil,
 	}
 	runner.Start()
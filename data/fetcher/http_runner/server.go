@@ -92,12 +92,24 @@ func (self *HttpRunnerServer) ttick(c *gin.Context) {
 	)
 }
 
+func (self *HttpRunnerServer) mtick(c *gin.Context) {
+	timepoint := getTimePoint(c)
+	self.runner.mticker <- common.TimepointToTime(timepoint)
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+		},
+	)
+}
+
 func (self *HttpRunnerServer) init() {
 	self.r.GET("/otick", self.otick)
 	self.r.GET("/atick", self.atick)
 	self.r.GET("/rtick", self.rtick)
 	self.r.GET("/btick", self.btick)
 	self.r.GET("/ttick", self.ttick)
+	self.r.GET("/mtick", self.mtick)
 }
 
 func (self *HttpRunnerServer) Start() error {
@@ -0,0 +1,42 @@
+package fetcher
+
+import (
+	"log"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// RATE_HEALTH_INTERVAL is the time between two checks of rate validity,
+// about a block so expiring rates are alerted a few blocks ahead
+const RATE_HEALTH_INTERVAL uint64 = 15 * 1000
+
+// CheckRateHealth reads state of tokens in the pricing contract at the
+// current block, stores their rate validity and alerts issues which were
+// not alerted before.
+func (self *Fetcher) CheckRateHealth(timepoint uint64) {
+	block := self.currentBlock
+	duration, states, err := self.blockchain.FetchRateStates(block)
+	if err != nil {
+		log.Printf("Rate health: fetching token states failed: %s", err)
+		return
+	}
+	health := common.CheckRateHealth(block, duration, states)
+	health.Timestamp = timepoint
+	if err = self.storage.StoreRateHealth(health); err != nil {
+		log.Printf("Rate health: storing failed: %s", err)
+		return
+	}
+	self.lastRateHealth = timepoint
+	log.Printf("Rate health: %d tokens checked at block %d, %d issues", len(health.Tokens), block, len(health.Issues))
+	// only keep keys of the current issues so an issue is alerted again
+	// if it comes back after being resolved
+	alerts := map[string]bool{}
+	for _, issue := range health.Issues {
+		key := issue.Key()
+		alerts[key] = true
+		if !self.rateHealthAlerts[key] {
+			self.alerter.Alert(issue.Kind, issue.Message)
+		}
+	}
+	self.rateHealthAlerts = alerts
+}
@@ -0,0 +1,58 @@
+package fetcher
+
+import (
+	"log"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+const (
+	// RECONCILIATION_INTERVAL is the time between two reconciliations
+	RECONCILIATION_INTERVAL uint64 = 10 * 60 * 1000
+	// RECONCILIATION_DELAY leaves time for statuses and fills of recent
+	// trades to be fetched before they are reconciled
+	RECONCILIATION_DELAY uint64 = 10 * 60 * 1000
+	// RECONCILIATION_WINDOW is the time reconciled by each report
+	RECONCILIATION_WINDOW uint64 = 6 * 60 * 60 * 1000
+	// RECONCILIATION_LOOKBACK is how long before the window activities are
+	// searched for the orders of fills, activities can only be read one
+	// day at a time so the whole range must stay within a day
+	RECONCILIATION_LOOKBACK uint64 = 17 * 60 * 60 * 1000
+)
+
+// ReconcileTrades matches fills stored by trade history fetcher to trade
+// activities, stores the report and alerts issues which were not alerted
+// before.
+func (self *Fetcher) ReconcileTrades(timepoint uint64) {
+	toTime := timepoint - RECONCILIATION_DELAY
+	fromTime := toTime - RECONCILIATION_WINDOW
+	activities, err := self.storage.GetAllRecords((fromTime-RECONCILIATION_LOOKBACK)*1000000, timepoint*1000000)
+	if err != nil {
+		log.Printf("Reconciliation: getting activities failed: %s", err)
+		return
+	}
+	history, err := self.storage.GetTradeHistory(fromTime-RECONCILIATION_LOOKBACK, timepoint, "", "")
+	if err != nil {
+		log.Printf("Reconciliation: getting trade history failed: %s", err)
+		return
+	}
+	report := common.Reconcile(activities, history, fromTime, toTime)
+	report.Timestamp = timepoint
+	if err = self.storage.StoreReconciliationReport(report); err != nil {
+		log.Printf("Reconciliation: storing report failed: %s", err)
+		return
+	}
+	self.lastReconciliation = timepoint
+	log.Printf("Reconciliation: %d trades matched, %d issues", report.Matched, len(report.Issues))
+	// issues stay in reports while they are in the window, only keep keys
+	// of the current ones so they are alerted once
+	alerts := map[string]bool{}
+	for _, issue := range report.Issues {
+		key := issue.Key()
+		alerts[key] = true
+		if !self.reconciliationAlerts[key] {
+			self.alerter.Alert(issue.Kind, issue.String())
+		}
+	}
+	self.reconciliationAlerts = alerts
+}
@@ -11,6 +11,7 @@ type FetcherRunner interface {
 	GetRateTicker() <-chan time.Time
 	GetBlockTicker() <-chan time.Time
 	GetTradeHistoryTicker() <-chan time.Time
+	GetMetricTicker() <-chan time.Time
 	// Start must be non-blocking and must only return after runner
 	// gets to ready state before GetOrderbookTicker() and
 	// GetAuthDataTicker() get called
@@ -25,11 +26,13 @@ type TickerRunner struct {
 	rduration time.Duration
 	bduration time.Duration
 	tduration time.Duration
+	mduration time.Duration
 	oclock    *time.Ticker
 	aclock    *time.Ticker
 	rclock    *time.Ticker
 	bclock    *time.Ticker
 	tclock    *time.Ticker
+	mclock    *time.Ticker
 	signal    chan bool
 }
 
@@ -63,6 +66,12 @@ func (self *TickerRunner) GetTradeHistoryTicker() <-chan time.Time {
 	}
 	return self.tclock.C
 }
+func (self *TickerRunner) GetMetricTicker() <-chan time.Time {
+	if self.mclock == nil {
+		<-self.signal
+	}
+	return self.mclock.C
+}
 
 func (self *TickerRunner) Start() error {
 	self.oclock = time.NewTicker(self.oduration)
@@ -75,6 +84,8 @@ func (self *TickerRunner) Start() error {
 	self.signal <- true
 	self.tclock = time.NewTicker(self.tduration)
 	self.signal <- true
+	self.mclock = time.NewTicker(self.mduration)
+	self.signal <- true
 	return nil
 }
 
@@ -84,21 +95,24 @@ func (self *TickerRunner) Stop() error {
 	self.rclock.Stop()
 	self.bclock.Stop()
 	self.tclock.Stop()
+	self.mclock.Stop()
 	return nil
 }
 
-func NewTickerRunner(oduration, aduration, rduration, bduration, tduration time.Duration) *TickerRunner {
+func NewTickerRunner(oduration, aduration, rduration, bduration, tduration, mduration time.Duration) *TickerRunner {
 	return &TickerRunner{
 		oduration,
 		aduration,
 		rduration,
 		bduration,
 		tduration,
+		mduration,
+		nil,
 		nil,
 		nil,
 		nil,
 		nil,
 		nil,
-		make(chan bool, 5),
+		make(chan bool, 6),
 	}
 }
@@ -2,6 +2,7 @@ package fetcher
 
 import (
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
 )
 
 type Storage interface {
@@ -9,6 +10,15 @@ type Storage interface {
 	StoreRate(data common.AllRateEntry, timepoint uint64) error
 	StoreAuthSnapshot(data *common.AuthDataSnapshot, timepoint uint64) error
 	StoreTradeHistory(data common.AllTradeHistory, timepoint uint64) error
+	GetLastTradeHistory(exchangeID common.ExchangeID) (map[common.TokenPairID]common.TradeHistory, error)
+	StoreMetric(data *metric.MetricEntry, timepoint uint64) error
+	StoreReconciliationReport(report common.ReconciliationReport) error
+	StoreRateHealth(health common.RateHealth) error
+
+	CurrentPriceVersion(timepoint uint64) (common.Version, error)
+	GetAllPrices(version common.Version) (common.AllPriceEntry, error)
+	GetTradeHistory(fromTime, toTime uint64, exchangeID common.ExchangeID, pairID common.TokenPairID) (common.AllTradeHistory, error)
+	GetAllRecords(fromTime, toTime uint64) ([]common.ActivityRecord, error)
 
 	GetPendingActivities() ([]common.ActivityRecord, error)
 	UpdateActivity(id common.ActivityID, act common.ActivityRecord) error
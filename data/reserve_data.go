@@ -1,6 +1,8 @@
 package data
 
 import (
+	"io"
+
 	"github.com/KyberNetwork/reserve-data/common"
 )
 
@@ -48,6 +50,10 @@ func (self ReserveData) GetOnePrice(pairID common.TokenPairID, timepoint uint64)
 	}
 }
 
+func (self ReserveData) GetCandles(pairID common.TokenPairID, exchangeID common.ExchangeID, resolution string, fromTime, toTime uint64) ([]common.Candle, error) {
+	return self.storage.GetCandles(pairID, exchangeID, resolution, fromTime, toTime)
+}
+
 func (self ReserveData) CurrentAuthDataVersion(timepoint uint64) (common.Version, error) {
 	return self.storage.CurrentAuthDataVersion(timepoint)
 }
@@ -69,6 +75,7 @@ func (self ReserveData) GetAuthData(timepoint uint64) (common.AuthDataResponse,
 		result.Data.Timestamp = data.Timestamp
 		result.Data.ReturnTime = data.ReturnTime
 		result.Data.ExchangeBalances = data.ExchangeBalances
+		result.Data.ExchangeOrders = data.ExchangeOrders
 		result.Data.PendingActivities = data.PendingActivities
 		result.Data.Block = data.Block
 		result.Data.ReserveBalances = map[string]common.BalanceResponse{}
@@ -200,11 +207,33 @@ func (self ReserveData) GetPendingActivities() ([]common.ActivityRecord, error)
 	return self.storage.GetPendingActivities()
 }
 
-func (self ReserveData) GetTradeHistory(timepoint uint64) (common.AllTradeHistory, error) {
-	data, err := self.storage.GetTradeHistory(timepoint)
+func (self ReserveData) GetExpiredActivities() ([]common.ActivityRecord, error) {
+	return self.storage.GetExpiredActivities()
+}
+
+func (self ReserveData) ResolveActivity(id common.ActivityID, status string, reason string, timepoint uint64) (common.ActivityRecord, error) {
+	return self.storage.ResolveActivity(id, status, reason, timepoint)
+}
+
+func (self ReserveData) GetTradeHistory(fromTime, toTime uint64, exchangeID common.ExchangeID, pairID common.TokenPairID) (common.AllTradeHistory, error) {
+	data, err := self.storage.GetTradeHistory(fromTime, toTime, exchangeID, pairID)
 	return data, err
 }
 
+func (self ReserveData) GetReconciliationReports(fromTime, toTime uint64) ([]common.ReconciliationReport, error) {
+	return self.storage.GetReconciliationReports(fromTime, toTime)
+}
+
+// GetRateHealth returns the latest check of rate validity and token state
+// in the pricing contract.
+func (self ReserveData) GetRateHealth() (common.RateHealth, error) {
+	return self.storage.GetRateHealth()
+}
+
+func (self ReserveData) Backup(w io.Writer) (int64, error) {
+	return self.storage.Backup(w)
+}
+
 func (self ReserveData) Run() error {
 	return self.fetcher.Run()
 }
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"io"
+
 	"github.com/KyberNetwork/reserve-data/common"
 )
 
@@ -17,7 +19,16 @@ type Storage interface {
 	GetRates(fromTime, toTime uint64) ([]common.AllRateEntry, error)
 
 	GetAllRecords(fromTime, toTime uint64) ([]common.ActivityRecord, error)
+	GetActivity(id common.ActivityID) (common.ActivityRecord, error)
 	GetPendingActivities() ([]common.ActivityRecord, error)
+	GetExpiredActivities() ([]common.ActivityRecord, error)
+	ResolveActivity(id common.ActivityID, status string, reason string, timepoint uint64) (common.ActivityRecord, error)
+
+	GetCandles(pairID common.TokenPairID, exchangeID common.ExchangeID, resolution string, fromTime, toTime uint64) ([]common.Candle, error)
+
+	GetTradeHistory(fromTime, toTime uint64, exchangeID common.ExchangeID, pairID common.TokenPairID) (common.AllTradeHistory, error)
+	GetReconciliationReports(fromTime, toTime uint64) ([]common.ReconciliationReport, error)
+	GetRateHealth() (common.RateHealth, error)
 
-	GetTradeHistory(timepoint uint64) (common.AllTradeHistory, error)
+	Backup(w io.Writer) (int64, error)
 }
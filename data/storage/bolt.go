@@ -12,6 +12,7 @@ import (
 
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/KyberNetwork/reserve-data/migration"
 	"github.com/boltdb/bolt"
 )
 
@@ -22,6 +23,11 @@ const (
 	ACTIVITY_BUCKET         string = "activities"
 	AUTH_DATA_BUCKET        string = "auth_data"
 	PENDING_ACTIVITY_BUCKET string = "pending_activities"
+	EXPIRED_ACTIVITY_BUCKET string = "expired_activities"
+	CANDLE_BUCKET           string = "candles"
+	RECONCILIATION_BUCKET   string = "reconciliation"
+	RATE_HEALTH_BUCKET      string = "rate_health"
+	ACCOUNTING_BUCKET       string = "accounting_snapshots"
 	BITTREX_DEPOSIT_HISTORY string = "bittrex_deposit_history"
 	METRIC_BUCKET           string = "metrics"
 	METRIC_TARGET_QUANTITY  string = "target_quantity"
@@ -31,10 +37,41 @@ const (
 	SETRATE_CONTROL         string = "setrate_control"
 	PENDING_PWI_EQUATION    string = "pending_pwi_equation"
 	PWI_EQUATION            string = "pwi_equation"
+	PENDING_TRADE_SWITCH    string = "pending_trade_switch"
+	PENDING_STEP_FUNCTIONS  string = "pending_step_functions"
+	TOKEN_LISTING           string = "token_listing"
 	MAX_NUMBER_VERSION      int    = 1000
 	MAX_GET_RATES_PERIOD    uint64 = 86400000 //1 days in milisec
 )
 
+// buckets is the bucket layout of the latest schema version, buckets are
+// created by Migrations.
+var buckets = []string{
+	PRICE_BUCKET,
+	RATE_BUCKET,
+	ORDER_BUCKET,
+	ACTIVITY_BUCKET,
+	PENDING_ACTIVITY_BUCKET,
+	EXPIRED_ACTIVITY_BUCKET,
+	BITTREX_DEPOSIT_HISTORY,
+	AUTH_DATA_BUCKET,
+	METRIC_BUCKET,
+	METRIC_TARGET_QUANTITY,
+	PENDING_TARGET_QUANTITY,
+	TRADE_HISTORY,
+	ENABLE_REBALANCE,
+	SETRATE_CONTROL,
+	PENDING_PWI_EQUATION,
+	PWI_EQUATION,
+	CANDLE_BUCKET,
+	RECONCILIATION_BUCKET,
+	ACCOUNTING_BUCKET,
+	RATE_HEALTH_BUCKET,
+	PENDING_TRADE_SWITCH,
+	PENDING_STEP_FUNCTIONS,
+	TOKEN_LISTING,
+}
+
 type BoltStorage struct {
 	mu sync.RWMutex
 	db *bolt.DB
@@ -49,24 +86,10 @@ func NewBoltStorage(path string) (*BoltStorage, error) {
 		return nil, err
 	}
 	// init buckets
-	db.Update(func(tx *bolt.Tx) error {
-		tx.CreateBucket([]byte(PRICE_BUCKET))
-		tx.CreateBucket([]byte(RATE_BUCKET))
-		tx.CreateBucket([]byte(ORDER_BUCKET))
-		tx.CreateBucket([]byte(ACTIVITY_BUCKET))
-		tx.CreateBucket([]byte(PENDING_ACTIVITY_BUCKET))
-		tx.CreateBucket([]byte(BITTREX_DEPOSIT_HISTORY))
-		tx.CreateBucket([]byte(AUTH_DATA_BUCKET))
-		tx.CreateBucket([]byte(METRIC_BUCKET))
-		tx.CreateBucket([]byte(METRIC_TARGET_QUANTITY))
-		tx.CreateBucket([]byte(PENDING_TARGET_QUANTITY))
-		tx.CreateBucket([]byte(TRADE_HISTORY))
-		tx.CreateBucket([]byte(ENABLE_REBALANCE))
-		tx.CreateBucket([]byte(SETRATE_CONTROL))
-		tx.CreateBucket([]byte(PENDING_PWI_EQUATION))
-		tx.CreateBucket([]byte(PWI_EQUATION))
-		return nil
-	})
+	if _, err = migration.Run(db, Migrations); err != nil {
+		db.Close()
+		return nil, err
+	}
 	storage := &BoltStorage{sync.RWMutex{}, db}
 	return storage, nil
 }
@@ -281,7 +304,11 @@ func (self *BoltStorage) StorePrice(data common.AllPriceEntry, timepoint uint64)
 		if err != nil {
 			return err
 		}
-		return b.Put(uint64ToBytes(timepoint), dataJson)
+		if err = b.Put(uint64ToBytes(timepoint), dataJson); err != nil {
+			return err
+		}
+		err = updateCandles(tx, data, timepoint)
+		return err
 	})
 	return err
 }
@@ -338,16 +365,10 @@ func (self *BoltStorage) Record(
 	self.db.Update(func(tx *bolt.Tx) error {
 		var dataJson []byte
 		b := tx.Bucket([]byte(ACTIVITY_BUCKET))
-		record := common.ActivityRecord{
-			Action:         action,
-			ID:             id,
-			Destination:    destination,
-			Params:         params,
-			Result:         result,
-			ExchangeStatus: estatus,
-			MiningStatus:   mstatus,
-			Timestamp:      common.Timestamp(strconv.FormatUint(timepoint, 10)),
-		}
+		record := common.NewActivityRecord(
+			action, id, destination, params, result, estatus, mstatus,
+			common.Timestamp(strconv.FormatUint(timepoint, 10)),
+		)
 		dataJson, err = json.Marshal(record)
 		if err != nil {
 			return err
@@ -531,6 +552,12 @@ func (self *BoltStorage) UpdateActivity(id common.ActivityID, activity common.Ac
 					return err
 				}
 			}
+			if activity.IsExpired() {
+				err = tx.Bucket([]byte(EXPIRED_ACTIVITY_BUCKET)).Put(idBytes[:], dataJson)
+				if err != nil {
+					return err
+				}
+			}
 		}
 		b := tx.Bucket([]byte(ACTIVITY_BUCKET))
 		if err != nil {
@@ -541,6 +568,83 @@ func (self *BoltStorage) UpdateActivity(id common.ActivityID, activity common.Ac
 	return err
 }
 
+// GetActivity returns the activity of id.
+func (self *BoltStorage) GetActivity(id common.ActivityID) (common.ActivityRecord, error) {
+	record := common.ActivityRecord{}
+	var err error
+	self.db.View(func(tx *bolt.Tx) error {
+		idBytes := id.ToBytes()
+		data := tx.Bucket([]byte(ACTIVITY_BUCKET)).Get(idBytes[:])
+		if data == nil {
+			err = errors.New(fmt.Sprintf("Activity %s is not found", id))
+			return err
+		}
+		err = json.Unmarshal(data, &record)
+		return err
+	})
+	return record, err
+}
+
+func (self *BoltStorage) GetExpiredActivities() ([]common.ActivityRecord, error) {
+	result := []common.ActivityRecord{}
+	var err error
+	self.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EXPIRED_ACTIVITY_BUCKET))
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			record := common.ActivityRecord{}
+			err = json.Unmarshal(v, &record)
+			if err != nil {
+				return err
+			}
+			result = append(
+				[]common.ActivityRecord{record}, result...)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// ResolveActivity lets an operator finish a stuck activity as done or failed.
+// The activity is removed from pending and expired activities.
+func (self *BoltStorage) ResolveActivity(id common.ActivityID, status string, reason string, timepoint uint64) (common.ActivityRecord, error) {
+	record := common.ActivityRecord{}
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(ACTIVITY_BUCKET))
+		idBytes := id.ToBytes()
+		data := b.Get(idBytes[:])
+		if data == nil {
+			err = errors.New(fmt.Sprintf("Activity %s is not found", id))
+			return err
+		}
+		err = json.Unmarshal(data, &record)
+		if err != nil {
+			return err
+		}
+		err = record.Resolve(status, reason, common.Timestamp(strconv.FormatUint(timepoint, 10)))
+		if err != nil {
+			return err
+		}
+		var dataJson []byte
+		dataJson, err = json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		err = b.Put(idBytes[:], dataJson)
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket([]byte(PENDING_ACTIVITY_BUCKET)).Delete(idBytes[:])
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket([]byte(EXPIRED_ACTIVITY_BUCKET)).Delete(idBytes[:])
+		return err
+	})
+	return record, err
+}
+
 func (self *BoltStorage) IsNewBittrexDeposit(id uint64, actID common.ActivityID) bool {
 	res := true
 	self.db.View(func(tx *bolt.Tx) error {
@@ -593,13 +697,24 @@ func (self *BoltStorage) StoreMetric(data *metric.MetricEntry, timepoint uint64)
 		if err != nil {
 			return err
 		}
-		idByte := uint64ToBytes(data.Timestamp)
-		err = b.Put(idByte, dataJson)
+		err = b.Put(metricKey(data), dataJson)
 		return err
 	})
 	return err
 }
 
+// metricKey returns key of a metric entry. Metrics posted by analytics
+// are keyed by their timestamp only, as they were before sources were
+// recorded, metrics of other sources have their source appended so both
+// can be stored at the same timestamp.
+func metricKey(data *metric.MetricEntry) []byte {
+	key := uint64ToBytes(data.Timestamp)
+	if data.GetSource() != metric.ANALYTICS_SOURCE {
+		key = append(key, []byte(data.Source)...)
+	}
+	return key
+}
+
 func (self *BoltStorage) GetMetric(tokens []common.Token, fromTime, toTime uint64) (map[string]metric.MetricList, error) {
 	imResult := map[string]*metric.MetricList{}
 	for _, tok := range tokens {
@@ -613,7 +728,7 @@ func (self *BoltStorage) GetMetric(tokens []common.Token, fromTime, toTime uint6
 		min := uint64ToBytes(fromTime)
 		max := uint64ToBytes(toTime)
 
-		for k, v := c.Seek(min); k != nil && bytes.Compare(k, max) <= 0; k, v = c.Next() {
+		for k, v := c.Seek(min); k != nil && bytes.Compare(k[:8], max) <= 0; k, v = c.Next() {
 			data := metric.MetricEntry{}
 			err = json.Unmarshal(v, &data)
 			if err != nil {
@@ -624,6 +739,7 @@ func (self *BoltStorage) GetMetric(tokens []common.Token, fromTime, toTime uint6
 				if found {
 					*metricList = append(*metricList, metric.TokenMetricResponse{
 						Timestamp: data.Timestamp,
+						Source:    data.GetSource(),
 						AfpMid:    m.AfpMid,
 						Spread:    m.Spread,
 					})
@@ -781,42 +897,131 @@ func (self *BoltStorage) StoreTokenTargetQty(id, data string) error {
 	return err
 }
 
-func (self *BoltStorage) GetTradeHistory(timepoint uint64) (common.AllTradeHistory, error) {
-	result := common.AllTradeHistory{}
+// tradeHistoryKey orders trades of a pair by time, the trade ID makes
+// the key unique so a trade fetched again doesn't create a duplicate
+func tradeHistoryKey(trade common.TradeHistory) []byte {
+	return append(uint64ToBytes(trade.Timestamp), []byte(trade.ID)...)
+}
+
+// putTradeHistory adds trades which are not stored yet to the trade
+// history bucket, it returns number of added trades
+func putTradeHistory(b *bolt.Bucket, data common.AllTradeHistory) (int, error) {
+	added := 0
+	for exchangeID, exchangeHistory := range data.Data {
+		eb, err := b.CreateBucketIfNotExists([]byte(exchangeID))
+		if err != nil {
+			return added, err
+		}
+		for pairID, trades := range exchangeHistory {
+			pb, err := eb.CreateBucketIfNotExists([]byte(pairID))
+			if err != nil {
+				return added, err
+			}
+			for _, trade := range trades {
+				key := tradeHistoryKey(trade)
+				if pb.Get(key) != nil {
+					continue
+				}
+				dataJson, err := json.Marshal(trade)
+				if err != nil {
+					return added, err
+				}
+				if err = pb.Put(key, dataJson); err != nil {
+					return added, err
+				}
+				added++
+			}
+		}
+	}
+	return added, nil
+}
+
+// GetTradeHistory returns trades between fromTime and toTime (inclusive).
+// Empty exchangeID or pairID means all exchanges or all pairs.
+func (self *BoltStorage) GetTradeHistory(fromTime, toTime uint64, exchangeID common.ExchangeID, pairID common.TokenPairID) (common.AllTradeHistory, error) {
+	result := common.AllTradeHistory{
+		Timestamp: common.GetTimestamp(),
+		Data:      map[common.ExchangeID]common.ExchangeTradeHistory{},
+	}
 	var err error
 	self.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(TRADE_HISTORY))
-		_, data := b.Cursor().First()
-		if data == nil {
-			err = errors.New(fmt.Sprintf("There no data before timepoint %s", timepoint))
-		} else {
-			err = json.Unmarshal(data, &result)
-		}
+		err = b.ForEach(func(ek, ev []byte) error {
+			if ev != nil || (exchangeID != "" && string(ek) != string(exchangeID)) {
+				return nil
+			}
+			eb := b.Bucket(ek)
+			exchangeHistory := common.ExchangeTradeHistory{}
+			err := eb.ForEach(func(pk, pv []byte) error {
+				if pv != nil || (pairID != "" && string(pk) != string(pairID)) {
+					return nil
+				}
+				trades := []common.TradeHistory{}
+				c := eb.Bucket(pk).Cursor()
+				for k, v := c.Seek(uint64ToBytes(fromTime)); k != nil && bytesToUint64(k[:8]) <= toTime; k, v = c.Next() {
+					trade := common.TradeHistory{}
+					if err := json.Unmarshal(v, &trade); err != nil {
+						return err
+					}
+					trades = append(trades, trade)
+				}
+				if len(trades) > 0 {
+					exchangeHistory[common.TokenPairID(pk)] = trades
+				}
+				return nil
+			})
+			if len(exchangeHistory) > 0 {
+				result.Data[common.ExchangeID(ek)] = exchangeHistory
+			}
+			return err
+		})
 		return nil
 	})
 	return result, err
 }
 
-func (self *BoltStorage) StoreTradeHistory(data common.AllTradeHistory, timepoint uint64) error {
+// GetLastTradeHistory returns the latest stored trade of each pair of an
+// exchange, exchanges fetch only trades after it.
+func (self *BoltStorage) GetLastTradeHistory(exchangeID common.ExchangeID) (map[common.TokenPairID]common.TradeHistory, error) {
+	result := map[common.TokenPairID]common.TradeHistory{}
 	var err error
-	self.db.Update(func(tx *bolt.Tx) error {
-		var dataJson []byte
-		b := tx.Bucket([]byte(TRADE_HISTORY))
-		// prune out old data
-		c := b.Cursor()
-		k, _ := c.First()
-		if k != nil {
-			b.Delete([]byte(k))
+	self.db.View(func(tx *bolt.Tx) error {
+		eb := tx.Bucket([]byte(TRADE_HISTORY)).Bucket([]byte(exchangeID))
+		if eb == nil {
+			return nil
 		}
+		err = eb.ForEach(func(pk, pv []byte) error {
+			if pv != nil {
+				return nil
+			}
+			_, v := eb.Bucket(pk).Cursor().Last()
+			if v == nil {
+				return nil
+			}
+			trade := common.TradeHistory{}
+			if err := json.Unmarshal(v, &trade); err != nil {
+				return err
+			}
+			result[common.TokenPairID(pk)] = trade
+			return nil
+		})
+		return nil
+	})
+	return result, err
+}
 
-		// add new data
-		dataJson, err = json.Marshal(data)
-		if err != nil {
-			return err
-		}
-		idByte := uint64ToBytes(timepoint)
-		return b.Put(idByte, dataJson)
+// StoreTradeHistory appends new trades to the history, trades which are
+// already stored are skipped.
+func (self *BoltStorage) StoreTradeHistory(data common.AllTradeHistory, timepoint uint64) error {
+	var err error
+	var added int
+	err = self.db.Update(func(tx *bolt.Tx) error {
+		added, err = putTradeHistory(tx.Bucket([]byte(TRADE_HISTORY)), data)
+		return err
 	})
+	if err == nil && added > 0 {
+		log.Printf("Stored %d new trades to trade history", added)
+	}
 	return err
 }
 
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/KyberNetwork/reserve-data/accounting"
+	"github.com/boltdb/bolt"
+)
+
+// StoreAccountingSnapshot stores a daily snapshot keyed by its day.
+func (self *BoltStorage) StoreAccountingSnapshot(snapshot accounting.Snapshot) error {
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		var dataJson []byte
+		b := tx.Bucket([]byte(ACCOUNTING_BUCKET))
+		dataJson, err = json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		err = b.Put(uint64ToBytes(snapshot.Timestamp), dataJson)
+		return err
+	})
+	return err
+}
+
+// GetAccountingSnapshots returns daily snapshots of days starting between
+// fromTime and toTime, oldest first.
+func (self *BoltStorage) GetAccountingSnapshots(fromTime, toTime uint64) ([]accounting.Snapshot, error) {
+	result := []accounting.Snapshot{}
+	var err error
+	self.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(ACCOUNTING_BUCKET)).Cursor()
+		max := uint64ToBytes(toTime)
+		for k, v := c.Seek(uint64ToBytes(fromTime)); k != nil && bytes.Compare(k, max) <= 0; k, v = c.Next() {
+			snapshot := accounting.Snapshot{}
+			if err = json.Unmarshal(v, &snapshot); err != nil {
+				return err
+			}
+			result = append(result, snapshot)
+		}
+		return nil
+	})
+	return result, err
+}
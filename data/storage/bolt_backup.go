@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/migration"
+	"github.com/boltdb/bolt"
+)
+
+// Backup writes a consistent snapshot of the whole database to w.
+// It runs in a read transaction so the storage keeps serving while
+// the snapshot is streamed.
+func (self *BoltStorage) Backup(w io.Writer) (int64, error) {
+	var n int64
+	err := self.db.View(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// ValidateBoltFile checks that the file at path is a bolt database of
+// this storage. A database with a newer schema is rejected. Databases of
+// any version must have the initial buckets, then a copy of it is
+// migrated to the latest schema and must have the whole bucket layout.
+// It is used to check a backup before restoring it.
+func ValidateBoltFile(path string) error {
+	db, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tmp, err := ioutil.TempFile("", "validate_bolt")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	err = db.View(func(tx *bolt.Tx) error {
+		if err := migration.Check(tx, Migrations); err != nil {
+			return err
+		}
+		if err := migration.CheckBuckets(tx, initialBuckets); err != nil {
+			return err
+		}
+		return tx.CopyFile(tmp.Name(), 0600)
+	})
+	if err != nil {
+		return err
+	}
+	migrated, err := bolt.Open(tmp.Name(), 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer migrated.Close()
+	if _, err = migration.Run(migrated, Migrations); err != nil {
+		return err
+	}
+	return migrated.View(func(tx *bolt.Tx) error {
+		return migration.CheckBuckets(tx, buckets)
+	})
+}
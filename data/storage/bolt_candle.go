@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/boltdb/bolt"
+)
+
+// updateCandles adds top of books of a price snapshot to candles of each
+// pair, exchange and resolution. Candles are kept in CANDLE_BUCKET nested
+// by pair, exchange and resolution so they are not pruned with prices.
+func updateCandles(tx *bolt.Tx, data common.AllPriceEntry, timepoint uint64) error {
+	b := tx.Bucket([]byte(CANDLE_BUCKET))
+	for pairID, onePrice := range data.Data {
+		pb, err := b.CreateBucketIfNotExists([]byte(pairID))
+		if err != nil {
+			return err
+		}
+		for exchangeID, top := range common.GetBookTops(onePrice) {
+			eb, err := pb.CreateBucketIfNotExists([]byte(exchangeID))
+			if err != nil {
+				return err
+			}
+			for resolution, length := range common.CandleResolutions {
+				rb, err := eb.CreateBucketIfNotExists([]byte(resolution))
+				if err != nil {
+					return err
+				}
+				key := uint64ToBytes(timepoint - timepoint%length)
+				candle := common.Candle{Timestamp: timepoint - timepoint%length}
+				if v := rb.Get(key); v != nil {
+					if err = json.Unmarshal(v, &candle); err != nil {
+						return err
+					}
+				}
+				candle.Update(top)
+				dataJson, err := json.Marshal(candle)
+				if err != nil {
+					return err
+				}
+				if err = rb.Put(key, dataJson); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// GetCandles returns candles of a pair on an exchange (or
+// common.CONSOLIDATED_EXCHANGE) starting between fromTime and toTime.
+func (self *BoltStorage) GetCandles(pairID common.TokenPairID, exchangeID common.ExchangeID, resolution string, fromTime, toTime uint64) ([]common.Candle, error) {
+	result := []common.Candle{}
+	if _, supported := common.CandleResolutions[resolution]; !supported {
+		return result, errors.New(fmt.Sprintf("Resolution %s is not supported", resolution))
+	}
+	var err error
+	self.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(CANDLE_BUCKET)).Bucket([]byte(pairID))
+		if b == nil {
+			return nil
+		}
+		if b = b.Bucket([]byte(exchangeID)); b == nil {
+			return nil
+		}
+		if b = b.Bucket([]byte(resolution)); b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		max := uint64ToBytes(toTime)
+		for k, v := c.Seek(uint64ToBytes(fromTime)); k != nil && bytes.Compare(k, max) <= 0; k, v = c.Next() {
+			candle := common.Candle{}
+			if err = json.Unmarshal(v, &candle); err != nil {
+				return err
+			}
+			result = append(result, candle)
+		}
+		return nil
+	})
+	return result, err
+}
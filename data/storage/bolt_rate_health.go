@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/boltdb/bolt"
+)
+
+// StoreRateHealth stores a rate health check keyed by its timestamp, only
+// the latest MAX_NUMBER_VERSION checks are kept.
+func (self *BoltStorage) StoreRateHealth(health common.RateHealth) error {
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		var dataJson []byte
+		b := tx.Bucket([]byte(RATE_HEALTH_BUCKET))
+		dataJson, err = json.Marshal(health)
+		if err != nil {
+			return err
+		}
+		if err = b.Put(uint64ToBytes(health.Timestamp), dataJson); err != nil {
+			return err
+		}
+		err = self.PruneOutdatedData(tx, RATE_HEALTH_BUCKET)
+		return err
+	})
+	return err
+}
+
+// GetRateHealth returns the latest rate health check.
+func (self *BoltStorage) GetRateHealth() (common.RateHealth, error) {
+	result := common.RateHealth{}
+	var err error
+	self.db.View(func(tx *bolt.Tx) error {
+		_, v := tx.Bucket([]byte(RATE_HEALTH_BUCKET)).Cursor().Last()
+		if v == nil {
+			err = errors.New("Rate health is not checked yet")
+			return err
+		}
+		err = json.Unmarshal(v, &result)
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/boltdb/bolt"
+)
+
+// StoreReconciliationReport stores a report keyed by its timestamp, only
+// the latest MAX_NUMBER_VERSION reports are kept.
+func (self *BoltStorage) StoreReconciliationReport(report common.ReconciliationReport) error {
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		var dataJson []byte
+		b := tx.Bucket([]byte(RECONCILIATION_BUCKET))
+		dataJson, err = json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		if err = b.Put(uint64ToBytes(report.Timestamp), dataJson); err != nil {
+			return err
+		}
+		err = self.PruneOutdatedData(tx, RECONCILIATION_BUCKET)
+		return err
+	})
+	return err
+}
+
+// GetReconciliationReports returns reports done between fromTime and
+// toTime, oldest first.
+func (self *BoltStorage) GetReconciliationReports(fromTime, toTime uint64) ([]common.ReconciliationReport, error) {
+	result := []common.ReconciliationReport{}
+	var err error
+	self.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(RECONCILIATION_BUCKET)).Cursor()
+		max := uint64ToBytes(toTime)
+		for k, v := c.Seek(uint64ToBytes(fromTime)); k != nil && bytes.Compare(k, max) <= 0; k, v = c.Next() {
+			report := common.ReconciliationReport{}
+			if err = json.Unmarshal(v, &report); err != nil {
+				return err
+			}
+			result = append(result, report)
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/boltdb/bolt"
+)
+
+// StorePendingStepFunctions stores step functions of tokens proposed to be
+// set until they are confirmed or rejected, there can only be one proposal
+// at a time.
+func (self *BoltStorage) StorePendingStepFunctions(data map[string]common.TokenStepFunctions) error {
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(PENDING_STEP_FUNCTIONS))
+		_, v := b.Cursor().First()
+		if v != nil {
+			err = errors.New("There are other pending step functions, please confirm or reject them first")
+			return err
+		}
+		pending := metric.PendingStepFunctions{
+			ID:   common.GetTimepoint(),
+			Data: data,
+		}
+		var dataJson []byte
+		dataJson, err = json.Marshal(pending)
+		if err != nil {
+			return err
+		}
+		err = b.Put(uint64ToBytes(pending.ID), dataJson)
+		return err
+	})
+	return err
+}
+
+func (self *BoltStorage) GetPendingStepFunctions() (metric.PendingStepFunctions, error) {
+	var err error
+	var result metric.PendingStepFunctions
+	self.db.View(func(tx *bolt.Tx) error {
+		_, v := tx.Bucket([]byte(PENDING_STEP_FUNCTIONS)).Cursor().First()
+		if v == nil {
+			err = errors.New("There are no pending step functions")
+			return err
+		}
+		err = json.Unmarshal(v, &result)
+		return err
+	})
+	return result, err
+}
+
+func (self *BoltStorage) RemovePendingStepFunctions() error {
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(PENDING_STEP_FUNCTIONS))
+		k, _ := b.Cursor().First()
+		if k == nil {
+			err = errors.New("There are no pending step functions")
+			return err
+		}
+		err = b.Delete(k)
+		return err
+	})
+	return err
+}
@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/KyberNetwork/reserve-data/migration"
+	"github.com/boltdb/bolt"
 )
 
 func TestHasPendingDepositBoltStorage(t *testing.T) {
@@ -43,3 +46,308 @@ func TestHasPendingDepositBoltStorage(t *testing.T) {
 		t.Fatalf("Expected ram storage to return true when there is pending deposit")
 	}
 }
+
+func TestBackupBoltStorage(t *testing.T) {
+	boltFile := "test_bolt_backup.db"
+	backupFile := "test_bolt_backup_copy.db"
+	os.Remove(boltFile)
+	os.Remove(backupFile)
+	defer os.Remove(boltFile)
+	defer os.Remove(backupFile)
+	storage, err := NewBoltStorage(boltFile)
+	if err != nil {
+		t.Fatalf("Couldn't init bolt storage %v", err)
+	}
+	f, err := os.Create(backupFile)
+	if err != nil {
+		t.Fatalf("Couldn't create backup file %v", err)
+	}
+	_, err = storage.Backup(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("Expected backup to succeed, got error: %v", err)
+	}
+	if err = ValidateBoltFile(backupFile); err != nil {
+		t.Fatalf("Expected backup to have full bucket layout, got error: %v", err)
+	}
+	if err = ValidateBoltFile("bolt_test.go"); err == nil {
+		t.Fatalf("Expected non bolt file to be rejected")
+	}
+}
+
+func TestValidateOldBoltFile(t *testing.T) {
+	boltFile := "test_bolt_validate.db"
+	defer os.Remove(boltFile)
+	create := func(bucketNames ...string) {
+		os.Remove(boltFile)
+		db, err := bolt.Open(boltFile, 0600, nil)
+		if err != nil {
+			t.Fatalf("Couldn't open bolt db %v", err)
+		}
+		db.Update(func(tx *bolt.Tx) error {
+			for _, bucket := range bucketNames {
+				if _, err := tx.CreateBucket([]byte(bucket)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		db.Close()
+	}
+	// an empty database and a database of another storage are at schema
+	// version 0 too but must not be restored
+	create()
+	if err := ValidateBoltFile(boltFile); err == nil {
+		t.Fatalf("Expected empty database to be rejected")
+	}
+	create("logs", "address_id", "trade_stats")
+	if err := ValidateBoltFile(boltFile); err == nil {
+		t.Fatalf("Expected database of another storage to be rejected")
+	}
+	// a database created before schema versioning is migrated
+	create(initialBuckets...)
+	if err := ValidateBoltFile(boltFile); err != nil {
+		t.Fatalf("Expected database before schema versioning to be accepted, got error: %v", err)
+	}
+	// the backup itself is left untouched
+	db, err := bolt.Open(boltFile, 0600, nil)
+	if err != nil {
+		t.Fatalf("Couldn't open bolt db %v", err)
+	}
+	defer db.Close()
+	db.View(func(tx *bolt.Tx) error {
+		if version := migration.SchemaVersion(tx); version != 0 {
+			t.Fatalf("Expected backup to stay at schema version 0, got %d", version)
+		}
+		return nil
+	})
+}
+
+func TestMigrateBoltStorage(t *testing.T) {
+	boltFile := "test_bolt_migration.db"
+	os.Remove(boltFile)
+	defer os.Remove(boltFile)
+	// a database created before schema versioning has no metadata and
+	// no expired activities bucket
+	db, err := bolt.Open(boltFile, 0600, nil)
+	if err != nil {
+		t.Fatalf("Couldn't open bolt db %v", err)
+	}
+	db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte(PENDING_ACTIVITY_BUCKET))
+		return err
+	})
+	db.Close()
+	storage, err := NewBoltStorage(boltFile)
+	if err != nil {
+		t.Fatalf("Couldn't init bolt storage %v", err)
+	}
+	storage.db.View(func(tx *bolt.Tx) error {
+		if version := migration.SchemaVersion(tx); version != migration.Latest(Migrations) {
+			t.Fatalf("Expected schema version %d, got %d", migration.Latest(Migrations), version)
+		}
+		for _, bucket := range buckets {
+			if tx.Bucket([]byte(bucket)) == nil {
+				t.Fatalf("Expected bucket %s to be created", bucket)
+			}
+		}
+		return nil
+	})
+	storage.db.Close()
+	// a binary which only knows older migrations must not open it
+	latest := Migrations
+	defer func() { Migrations = latest }()
+	Migrations = Migrations[:1]
+	if _, err = NewBoltStorage(boltFile); err == nil {
+		t.Fatalf("Expected database with newer schema to be refused")
+	}
+	if err = ValidateBoltFile(boltFile); err == nil {
+		t.Fatalf("Expected backup with newer schema to be rejected")
+	}
+}
+
+func TestTradeHistoryBoltStorage(t *testing.T) {
+	boltFile := "test_bolt_trade_history.db"
+	os.Remove(boltFile)
+	defer os.Remove(boltFile)
+	storage, err := NewBoltStorage(boltFile)
+	if err != nil {
+		t.Fatalf("Couldn't init bolt storage %v", err)
+	}
+	pair := common.NewTokenPairID("OMG", "ETH")
+	store := func(trades ...common.TradeHistory) {
+		err := storage.StoreTradeHistory(common.AllTradeHistory{
+			Timestamp: common.GetTimestamp(),
+			Data: map[common.ExchangeID]common.ExchangeTradeHistory{
+				"binance": common.ExchangeTradeHistory{pair: trades},
+			},
+		}, common.GetTimepoint())
+		if err != nil {
+			t.Fatalf("Couldn't store trade history %v", err)
+		}
+	}
+	trade := func(id string, timestamp uint64) common.TradeHistory {
+		return common.TradeHistory{ID: id, Price: 0.01, Qty: 10, Type: "buy", Timestamp: timestamp}
+	}
+	store(trade("1", 1000), trade("2", 2000))
+	// the second fetch overlaps the first one
+	store(trade("2", 2000), trade("3", 3000))
+
+	all, err := storage.GetTradeHistory(0, 5000, "", "")
+	if err != nil {
+		t.Fatalf("Couldn't get trade history %v", err)
+	}
+	if trades := all.Data["binance"][pair]; len(trades) != 3 || trades[0].ID != "1" || trades[2].ID != "3" {
+		t.Fatalf("Expected 3 deduplicated trades in time order, got %v", trades)
+	}
+	ranged, _ := storage.GetTradeHistory(1500, 2000, "binance", pair)
+	if trades := ranged.Data["binance"][pair]; len(trades) != 1 || trades[0].ID != "2" {
+		t.Fatalf("Expected only trade 2 in range, got %v", trades)
+	}
+	other, _ := storage.GetTradeHistory(0, 5000, "huobi", "")
+	if len(other.Data) != 0 {
+		t.Fatalf("Expected no trade of other exchanges, got %v", other.Data)
+	}
+	last, _ := storage.GetLastTradeHistory("binance")
+	if last[pair].ID != "3" {
+		t.Fatalf("Expected last trade to be 3, got %v", last[pair])
+	}
+}
+
+func TestCandlesBoltStorage(t *testing.T) {
+	boltFile := "test_bolt_candles.db"
+	os.Remove(boltFile)
+	defer os.Remove(boltFile)
+	storage, err := NewBoltStorage(boltFile)
+	if err != nil {
+		t.Fatalf("Couldn't init bolt storage %v", err)
+	}
+	pair := common.NewTokenPairID("OMG", "ETH")
+	store := func(timepoint uint64, binanceBid, binanceAsk, huobiBid, huobiAsk float64) {
+		err := storage.StorePrice(common.AllPriceEntry{
+			Data: map[common.TokenPairID]common.OnePrice{
+				pair: common.OnePrice{
+					"binance": common.ExchangePrice{
+						Valid: true,
+						Bids:  []common.PriceEntry{{Quantity: 1, Rate: binanceBid}},
+						Asks:  []common.PriceEntry{{Quantity: 1, Rate: binanceAsk}},
+					},
+					"huobi": common.ExchangePrice{
+						Valid: true,
+						Bids:  []common.PriceEntry{{Quantity: 1, Rate: huobiBid}},
+						Asks:  []common.PriceEntry{{Quantity: 1, Rate: huobiAsk}},
+					},
+				},
+			},
+		}, timepoint)
+		if err != nil {
+			t.Fatalf("Couldn't store price %v", err)
+		}
+	}
+	// two books in the first minute and one in the second minute
+	store(60000, 10, 12, 9, 11)
+	store(90000, 12, 14, 11, 13)
+	store(120000, 8, 10, 7, 9)
+
+	candles, err := storage.GetCandles(pair, "binance", "1m", 0, 200000)
+	if err != nil {
+		t.Fatalf("Couldn't get candles %v", err)
+	}
+	if len(candles) != 2 || candles[0].Timestamp != 60000 || candles[0].Samples != 2 {
+		t.Fatalf("Expected 2 minute candles, got %+v", candles)
+	}
+	if mid := candles[0].Mid; mid.Open != 11 || mid.High != 13 || mid.Low != 11 || mid.Close != 13 {
+		t.Fatalf("Unexpected mid OHLC %+v", mid)
+	}
+	hourly, _ := storage.GetCandles(pair, common.CONSOLIDATED_EXCHANGE, "1h", 0, 200000)
+	// consolidated top is the best bid and the best ask across exchanges
+	if len(hourly) != 1 || hourly[0].Samples != 3 || hourly[0].Bid.Open != 10 || hourly[0].Ask.Open != 11 {
+		t.Fatalf("Unexpected consolidated hourly candles %+v", hourly)
+	}
+	if _, err = storage.GetCandles(pair, "binance", "5m", 0, 200000); err == nil {
+		t.Fatalf("Expected unsupported resolution to be rejected")
+	}
+}
+
+func TestMetricSourcesBoltStorage(t *testing.T) {
+	boltFile := "test_bolt_metrics.db"
+	os.Remove(boltFile)
+	defer os.Remove(boltFile)
+	storage, err := NewBoltStorage(boltFile)
+	if err != nil {
+		t.Fatalf("Couldn't init bolt storage %v", err)
+	}
+	store := func(timestamp uint64, source string, afpMid float64) {
+		err := storage.StoreMetric(&metric.MetricEntry{
+			Timestamp: timestamp,
+			Source:    source,
+			Data:      map[string]metric.TokenMetric{"OMG": {AfpMid: afpMid, Spread: 1}},
+		}, timestamp)
+		if err != nil {
+			t.Fatalf("Couldn't store metric %v", err)
+		}
+	}
+	// entries without source are the ones posted before sources were recorded
+	store(100, "", 1)
+	store(200, metric.ANALYTICS_SOURCE, 2)
+	store(200, metric.CORE_SOURCE, 3)
+	store(300, metric.CORE_SOURCE, 4)
+
+	tokens := []common.Token{{ID: "OMG"}}
+	result, err := storage.GetMetric(tokens, 100, 200)
+	if err != nil {
+		t.Fatalf("Couldn't get metric %v", err)
+	}
+	metrics := result["OMG"]
+	if len(metrics) != 3 {
+		t.Fatalf("Expected 3 metrics, got %+v", metrics)
+	}
+	if metrics[0].Source != metric.ANALYTICS_SOURCE || metrics[1].Source != metric.ANALYTICS_SOURCE || metrics[1].AfpMid != 2 {
+		t.Fatalf("Expected analytics metrics first, got %+v", metrics)
+	}
+	if metrics[2].Source != metric.CORE_SOURCE || metrics[2].Timestamp != 200 || metrics[2].AfpMid != 3 {
+		t.Fatalf("Expected core metric at the same timestamp, got %+v", metrics[2])
+	}
+}
+
+func TestTokenListingBoltStorage(t *testing.T) {
+	boltFile := "test_bolt_listing.db"
+	os.Remove(boltFile)
+	defer os.Remove(boltFile)
+	storage, err := NewBoltStorage(boltFile)
+	if err != nil {
+		t.Fatalf("Couldn't init bolt storage %v", err)
+	}
+	list := func(id string, status string) {
+		if err := storage.StorePendingTokenListing(common.TokenListing{ID: id}); err != nil {
+			t.Fatalf("Couldn't store token listing %v", err)
+		}
+		listing, err := storage.GetTokenListing()
+		if err != nil || listing.Data.ID != id {
+			t.Fatalf("Expected listing of %s, got %+v (%v)", id, listing, err)
+		}
+		listing.Status = status
+		if err := storage.UpdateTokenListing(listing); err != nil {
+			t.Fatalf("Couldn't update token listing %v", err)
+		}
+	}
+	// listed tokens are kept when the next token is proposed, failed ones
+	// are replaced
+	list("KNC", common.LISTING_LISTED)
+	list("OMG", common.LISTING_FAILED)
+	list("EOS", common.LISTING_LISTED)
+	list("SNT", common.LISTING_PENDING)
+	listed, err := storage.GetListedTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 || listed[0].Data.ID != "KNC" || listed[1].Data.ID != "EOS" {
+		t.Fatalf("Unexpected listed tokens %+v", listed)
+	}
+	if err = storage.RemoveTokenListing(); err != nil {
+		t.Fatal(err)
+	}
+	if listing, err := storage.GetTokenListing(); err != nil || listing.Data.ID != "EOS" {
+		t.Fatalf("Expected last listing to be EOS after removing the pending one, got %+v (%v)", listing, err)
+	}
+}
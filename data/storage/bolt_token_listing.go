@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/boltdb/bolt"
+)
+
+// StorePendingTokenListing stores a token proposed to be listed until it is
+// confirmed or rejected. It replaces the last listing unless that one is
+// still pending or in progress, or listed as listed tokens are kept to be
+// registered again at startup.
+func (self *BoltStorage) StorePendingTokenListing(data common.TokenListing) error {
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TOKEN_LISTING))
+		k, v := b.Cursor().Last()
+		if v != nil {
+			var last metric.TokenListing
+			if err = json.Unmarshal(v, &last); err != nil {
+				return err
+			}
+			if last.Status == common.LISTING_PENDING || last.Status == common.LISTING_IN_PROGRESS {
+				err = errors.New("There is another token listing pending or in progress, please wait for it, confirm or reject it first")
+				return err
+			}
+			if last.Status != common.LISTING_LISTED {
+				if err = b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		listing := metric.TokenListing{
+			ID:         common.GetTimepoint(),
+			Data:       data,
+			Status:     common.LISTING_PENDING,
+			Activities: []common.ActivityID{},
+		}
+		// a listed token stored in the same milisecond is kept
+		if k != nil && listing.ID <= bytesToUint64(k) {
+			listing.ID = bytesToUint64(k) + 1
+		}
+		var dataJson []byte
+		dataJson, err = json.Marshal(listing)
+		if err != nil {
+			return err
+		}
+		err = b.Put(uint64ToBytes(listing.ID), dataJson)
+		return err
+	})
+	return err
+}
+
+// UpdateTokenListing stores the progress of listing, it fails if the
+// listing has been removed.
+func (self *BoltStorage) UpdateTokenListing(listing metric.TokenListing) error {
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TOKEN_LISTING))
+		k := uint64ToBytes(listing.ID)
+		if b.Get(k) == nil {
+			err = errors.New("The token listing has been removed")
+			return err
+		}
+		var dataJson []byte
+		dataJson, err = json.Marshal(listing)
+		if err != nil {
+			return err
+		}
+		err = b.Put(k, dataJson)
+		return err
+	})
+	return err
+}
+
+// GetTokenListing returns the last token listing.
+func (self *BoltStorage) GetTokenListing() (metric.TokenListing, error) {
+	var err error
+	var result metric.TokenListing
+	self.db.View(func(tx *bolt.Tx) error {
+		_, v := tx.Bucket([]byte(TOKEN_LISTING)).Cursor().Last()
+		if v == nil {
+			err = errors.New("There is no token listing")
+			return err
+		}
+		err = json.Unmarshal(v, &result)
+		return err
+	})
+	return result, err
+}
+
+// GetListedTokens returns listings of tokens listed successfully, oldest
+// first.
+func (self *BoltStorage) GetListedTokens() ([]metric.TokenListing, error) {
+	var err error
+	result := []metric.TokenListing{}
+	self.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(TOKEN_LISTING)).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			listing := metric.TokenListing{}
+			if err = json.Unmarshal(v, &listing); err != nil {
+				return err
+			}
+			if listing.Status == common.LISTING_LISTED {
+				result = append(result, listing)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// RemoveTokenListing removes the last token listing.
+func (self *BoltStorage) RemoveTokenListing() error {
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TOKEN_LISTING))
+		k, _ := b.Cursor().Last()
+		if k == nil {
+			err = errors.New("There is no token listing")
+			return err
+		}
+		err = b.Delete(k)
+		return err
+	})
+	return err
+}
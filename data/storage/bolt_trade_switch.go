@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/boltdb/bolt"
+)
+
+// StorePendingTradeSwitch stores a request to disable or enable trading
+// until it is confirmed or rejected, there can only be one at a time.
+func (self *BoltStorage) StorePendingTradeSwitch(data metric.TradeSwitch) error {
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(PENDING_TRADE_SWITCH))
+		_, v := b.Cursor().First()
+		if v != nil {
+			err = errors.New("There is another pending trade switch, please confirm or reject it first")
+			return err
+		}
+		data.ID = common.GetTimepoint()
+		var dataJson []byte
+		dataJson, err = json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		err = b.Put(uint64ToBytes(data.ID), dataJson)
+		return err
+	})
+	return err
+}
+
+func (self *BoltStorage) GetPendingTradeSwitch() (metric.TradeSwitch, error) {
+	var err error
+	var result metric.TradeSwitch
+	self.db.View(func(tx *bolt.Tx) error {
+		_, v := tx.Bucket([]byte(PENDING_TRADE_SWITCH)).Cursor().First()
+		if v == nil {
+			err = errors.New("There is no pending trade switch")
+			return err
+		}
+		err = json.Unmarshal(v, &result)
+		return err
+	})
+	return result, err
+}
+
+func (self *BoltStorage) RemovePendingTradeSwitch() error {
+	var err error
+	self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(PENDING_TRADE_SWITCH))
+		k, _ := b.Cursor().First()
+		if k == nil {
+			err = errors.New("There is no pending trade switch")
+			return err
+		}
+		err = b.Delete(k)
+		return err
+	})
+	return err
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"encoding/json"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/migration"
+	"github.com/boltdb/bolt"
+)
+
+// initialBuckets are the buckets of databases created before schema
+// versioning, every schema version has them.
+var initialBuckets = []string{
+	PRICE_BUCKET,
+	RATE_BUCKET,
+	ORDER_BUCKET,
+	ACTIVITY_BUCKET,
+	PENDING_ACTIVITY_BUCKET,
+	BITTREX_DEPOSIT_HISTORY,
+	AUTH_DATA_BUCKET,
+	METRIC_BUCKET,
+	METRIC_TARGET_QUANTITY,
+	PENDING_TARGET_QUANTITY,
+	TRADE_HISTORY,
+	ENABLE_REBALANCE,
+	SETRATE_CONTROL,
+	PENDING_PWI_EQUATION,
+	PWI_EQUATION,
+}
+
+// Migrations are the schema changes of the storage in order. They are
+// applied when the storage is opened or by db migrate command. Append
+// new migrations at the end and never change a released one.
+var Migrations = []migration.Migration{
+	{
+		Version:     1,
+		Description: "create initial buckets",
+		Migrate: func(tx *bolt.Tx) error {
+			for _, bucket := range initialBuckets {
+				if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "add expired activities bucket",
+		Migrate: func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(EXPIRED_ACTIVITY_BUCKET))
+			return err
+		},
+	},
+	{
+		Version:     3,
+		Description: "keep trade history per exchange and pair instead of the latest snapshot",
+		Migrate: func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte(TRADE_HISTORY))
+			snapshots := []common.AllTradeHistory{}
+			keys := [][]byte{}
+			err := b.ForEach(func(k, v []byte) error {
+				if v == nil {
+					return nil
+				}
+				snapshot := common.AllTradeHistory{}
+				if err := json.Unmarshal(v, &snapshot); err != nil {
+					return err
+				}
+				snapshots = append(snapshots, snapshot)
+				keys = append(keys, append([]byte{}, k...))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, k := range keys {
+				if err = b.Delete(k); err != nil {
+					return err
+				}
+			}
+			for _, snapshot := range snapshots {
+				if _, err = putTradeHistory(b, snapshot); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     4,
+		Description: "add candles bucket and build candles from stored prices",
+		Migrate: func(tx *bolt.Tx) error {
+			if _, err := tx.CreateBucketIfNotExists([]byte(CANDLE_BUCKET)); err != nil {
+				return err
+			}
+			return tx.Bucket([]byte(PRICE_BUCKET)).ForEach(func(k, v []byte) error {
+				data := common.AllPriceEntry{}
+				if err := json.Unmarshal(v, &data); err != nil {
+					return err
+				}
+				return updateCandles(tx, data, bytesToUint64(k))
+			})
+		},
+	},
+	{
+		Version:     5,
+		Description: "add reconciliation reports bucket",
+		Migrate: func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(RECONCILIATION_BUCKET))
+			return err
+		},
+	},
+	{
+		Version:     6,
+		Description: "add accounting snapshots bucket",
+		Migrate: func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(ACCOUNTING_BUCKET))
+			return err
+		},
+	},
+	{
+		Version:     7,
+		Description: "add rate health bucket",
+		Migrate: func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(RATE_HEALTH_BUCKET))
+			return err
+		},
+	},
+	{
+		Version:     8,
+		Description: "add pending trade switch bucket",
+		Migrate: func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(PENDING_TRADE_SWITCH))
+			return err
+		},
+	},
+	{
+		Version:     9,
+		Description: "add pending step functions bucket",
+		Migrate: func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(PENDING_STEP_FUNCTIONS))
+			return err
+		},
+	},
+	{
+		Version:     10,
+		Description: "add token listing bucket",
+		Migrate: func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(TOKEN_LISTING))
+			return err
+		},
+	},
+}
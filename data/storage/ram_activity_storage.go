@@ -3,6 +3,7 @@ package storage
 import (
 	"container/list"
 	"errors"
+	"fmt"
 	"sort"
 	"strconv"
 	"sync"
@@ -15,11 +16,12 @@ type RamActivityStorage struct {
 	version        int64
 	records        *list.List
 	pendingRecords *list.List
+	expiredRecords *list.List
 }
 
 func NewRamActivityStorage() *RamActivityStorage {
 	return &RamActivityStorage{
-		sync.RWMutex{}, 0, list.New(), list.New(),
+		sync.RWMutex{}, 0, list.New(), list.New(), list.New(),
 	}
 }
 
@@ -50,16 +52,10 @@ func (self *RamActivityStorage) StoreNewData(
 	defer self.mu.Unlock()
 	version := self.version + 1
 	self.version = version
-	record := common.ActivityRecord{
-		Action:         action,
-		ID:             id,
-		Destination:    destination,
-		Params:         params,
-		Result:         result,
-		ExchangeStatus: estatus,
-		MiningStatus:   mstatus,
-		Timestamp:      common.Timestamp(strconv.FormatUint(timepoint, 10)),
-	}
+	record := common.NewActivityRecord(
+		action, id, destination, params, result, estatus, mstatus,
+		common.Timestamp(strconv.FormatUint(timepoint, 10)),
+	)
 
 	self.records.PushBack(&record)
 	// all other pending set rates should be staled now
@@ -82,8 +78,8 @@ func (self *RamActivityStorage) StoreNewData(
 }
 
 func (self *RamActivityStorage) UpdateActivity(id common.ActivityID, activity common.ActivityRecord) error {
-	self.mu.RLock()
-	defer self.mu.RUnlock()
+	self.mu.Lock()
+	defer self.mu.Unlock()
 	ele := self.pendingRecords.Back()
 	var updated bool = false
 	for {
@@ -95,9 +91,13 @@ func (self *RamActivityStorage) UpdateActivity(id common.ActivityID, activity co
 				updated = true
 				oldAct.ExchangeStatus = activity.ExchangeStatus
 				oldAct.MiningStatus = activity.MiningStatus
+				oldAct.History = activity.History
 				if !oldAct.IsPending() {
 					self.pendingRecords.Remove(ele)
 				}
+				if oldAct.IsExpired() {
+					self.expiredRecords.PushBack(oldAct)
+				}
 			}
 			ele = ele.Prev()
 		}
@@ -168,6 +168,47 @@ func (self *RamActivityStorage) GetPendingRecords() ([]common.ActivityRecord, er
 	return activitiesFromList(self.pendingRecords), nil
 }
 
+func (self *RamActivityStorage) GetExpiredRecords() ([]common.ActivityRecord, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return activitiesFromList(self.expiredRecords), nil
+}
+
+// ResolveActivity lets an operator finish a stuck activity as done or failed.
+// The activity is removed from pending and expired activities.
+func (self *RamActivityStorage) ResolveActivity(id common.ActivityID, status string, reason string, timepoint uint64) (common.ActivityRecord, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	var record *common.ActivityRecord
+	for ele := self.records.Back(); ele != nil; ele = ele.Prev() {
+		if ele.Value.(*common.ActivityRecord).ID == id {
+			record = ele.Value.(*common.ActivityRecord)
+			break
+		}
+	}
+	if record == nil {
+		return common.ActivityRecord{}, errors.New(fmt.Sprintf("Activity %s is not found", id))
+	}
+	err := record.Resolve(status, reason, common.Timestamp(strconv.FormatUint(timepoint, 10)))
+	if err != nil {
+		return *record, err
+	}
+	removeActivity(self.pendingRecords, id)
+	removeActivity(self.expiredRecords, id)
+	return *record, nil
+}
+
+func removeActivity(l *list.List, id common.ActivityID) {
+	ele := l.Back()
+	for ele != nil {
+		prev := ele.Prev()
+		if ele.Value.(*common.ActivityRecord).ID == id {
+			l.Remove(ele)
+		}
+		ele = prev
+	}
+}
+
 func (self *RamActivityStorage) HasPendingDeposit(token common.Token, exchange common.Exchange) bool {
 	self.mu.RLock()
 	defer self.mu.RUnlock()
@@ -108,6 +108,14 @@ func (self *RamStorage) GetPendingActivities() ([]common.ActivityRecord, error)
 	return self.activity.GetPendingRecords()
 }
 
+func (self *RamStorage) GetExpiredActivities() ([]common.ActivityRecord, error) {
+	return self.activity.GetExpiredRecords()
+}
+
+func (self *RamStorage) ResolveActivity(id common.ActivityID, status string, reason string, timepoint uint64) (common.ActivityRecord, error) {
+	return self.activity.ResolveActivity(id, status, reason, timepoint)
+}
+
 func (self *RamStorage) PendingSetrate(minedNonce uint64) (*common.ActivityRecord, error) {
 	pendings, err := self.GetPendingActivities()
 	if err != nil {
@@ -145,8 +153,12 @@ func (self *RamStorage) StoreTradeLog(stat common.TradeLog, timepoint uint64) er
 	return self.log.StoreTradeLog(stat, timepoint)
 }
 
-func (self *RamStorage) GetTradeHistory(timepoint uint64) (common.AllTradeHistory, error) {
-	return self.tradeHistory.GetTradeHistory(timepoint)
+func (self *RamStorage) GetTradeHistory(fromTime, toTime uint64, exchangeID common.ExchangeID, pairID common.TokenPairID) (common.AllTradeHistory, error) {
+	return self.tradeHistory.GetTradeHistory(fromTime, toTime, exchangeID, pairID)
+}
+
+func (self *RamStorage) GetLastTradeHistory(exchangeID common.ExchangeID) (map[common.TokenPairID]common.TradeHistory, error) {
+	return self.tradeHistory.GetLastTradeHistory(exchangeID)
 }
 
 func (self *RamStorage) StoreTradeHistory(data common.AllTradeHistory, timepoint uint64) error {
@@ -37,3 +37,39 @@ func TestHasPendingDepositRamStorage(t *testing.T) {
 		t.Fatalf("Expected ram storage to return true when there is pending deposit")
 	}
 }
+
+func TestExpiredActivityRamStorage(t *testing.T) {
+	storage := NewRamStorage()
+	id := common.ActivityID{Timepoint: 1000, EID: "1"}
+	storage.Record(
+		"deposit", id, "binance",
+		map[string]interface{}{}, map[string]interface{}{},
+		"", "submitted", 1000)
+	activities, _ := storage.GetPendingActivities()
+	activity := activities[0]
+	if err := activity.Expire(common.TimeoutSource, common.Timestamp("2001")); err != nil {
+		t.Fatalf("Expected pending activity to be expired, got error: %v", err)
+	}
+	if err := storage.UpdateActivity(id, activity); err != nil {
+		t.Fatalf("Expected expired activity to be updated, got error: %v", err)
+	}
+	pendings, _ := storage.GetPendingActivities()
+	expired, _ := storage.GetExpiredActivities()
+	if len(pendings) != 0 || len(expired) != 1 || expired[0].ID != id {
+		t.Fatalf("Expected activity to be moved from pending to expired, got pending %+v, expired %+v", pendings, expired)
+	}
+	record, err := storage.ResolveActivity(id, "failed", "tx dropped", 3000)
+	if err != nil {
+		t.Fatalf("Expected expired activity to be resolved, got error: %v", err)
+	}
+	if record.MiningStatus != "failed" {
+		t.Fatalf("Expected resolved activity to be failed, got %s", record.MiningStatus)
+	}
+	expired, _ = storage.GetExpiredActivities()
+	if len(expired) != 0 {
+		t.Fatalf("Expected resolved activity not to be expired anymore, got %+v", expired)
+	}
+	if _, err := storage.ResolveActivity(common.ActivityID{Timepoint: 2000, EID: "2"}, "done", "credited", 3000); err == nil {
+		t.Fatalf("Expected resolving an unknown activity to be rejected")
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/KyberNetwork/reserve-data/common"
@@ -8,25 +9,79 @@ import (
 
 type RamTradeStorage struct {
 	mu   sync.RWMutex
-	data common.AllTradeHistory
+	data map[common.ExchangeID]map[common.TokenPairID]map[string]common.TradeHistory
 }
 
 func NewRamTradeStorage() *RamTradeStorage {
 	return &RamTradeStorage{
 		mu:   sync.RWMutex{},
-		data: common.AllTradeHistory{},
+		data: map[common.ExchangeID]map[common.TokenPairID]map[string]common.TradeHistory{},
 	}
 }
 
-func (self *RamTradeStorage) GetTradeHistory(timepoint uint64) (common.AllTradeHistory, error) {
+func (self *RamTradeStorage) GetTradeHistory(fromTime, toTime uint64, exchangeID common.ExchangeID, pairID common.TokenPairID) (common.AllTradeHistory, error) {
 	self.mu.RLock()
 	defer self.mu.RUnlock()
-	return self.data, nil
+	result := common.AllTradeHistory{
+		Timestamp: common.GetTimestamp(),
+		Data:      map[common.ExchangeID]common.ExchangeTradeHistory{},
+	}
+	for eid, pairs := range self.data {
+		if exchangeID != "" && eid != exchangeID {
+			continue
+		}
+		exchangeHistory := common.ExchangeTradeHistory{}
+		for pid, trades := range pairs {
+			if pairID != "" && pid != pairID {
+				continue
+			}
+			history := []common.TradeHistory{}
+			for _, trade := range trades {
+				if trade.Timestamp >= fromTime && trade.Timestamp <= toTime {
+					history = append(history, trade)
+				}
+			}
+			if len(history) > 0 {
+				sort.Slice(history, func(i, j int) bool { return history[i].Timestamp < history[j].Timestamp })
+				exchangeHistory[pid] = history
+			}
+		}
+		if len(exchangeHistory) > 0 {
+			result.Data[eid] = exchangeHistory
+		}
+	}
+	return result, nil
+}
+
+func (self *RamTradeStorage) GetLastTradeHistory(exchangeID common.ExchangeID) (map[common.TokenPairID]common.TradeHistory, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	result := map[common.TokenPairID]common.TradeHistory{}
+	for pid, trades := range self.data[exchangeID] {
+		for _, trade := range trades {
+			if last, found := result[pid]; !found || trade.Timestamp > last.Timestamp {
+				result[pid] = trade
+			}
+		}
+	}
+	return result, nil
 }
 
 func (self *RamTradeStorage) StoreTradeHistory(data common.AllTradeHistory, timepoint uint64) error {
 	self.mu.Lock()
 	defer self.mu.Unlock()
-	self.data = data
+	for eid, exchangeHistory := range data.Data {
+		if self.data[eid] == nil {
+			self.data[eid] = map[common.TokenPairID]map[string]common.TradeHistory{}
+		}
+		for pid, trades := range exchangeHistory {
+			if self.data[eid][pid] == nil {
+				self.data[eid][pid] = map[string]common.TradeHistory{}
+			}
+			for _, trade := range trades {
+				self.data[eid][pid][trade.ID] = trade
+			}
+		}
+	}
 	return nil
 }
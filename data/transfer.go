@@ -0,0 +1,41 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// GetTransfer returns the timeline of a deposit or withdraw activity.
+func (self ReserveData) GetTransfer(id common.ActivityID) (common.TransferTimeline, error) {
+	activity, err := self.storage.GetActivity(id)
+	if err != nil {
+		return common.TransferTimeline{}, err
+	}
+	timeline, ok := common.NewTransferTimeline(activity)
+	if !ok {
+		return timeline, errors.New(fmt.Sprintf("Activity %s is a %s, not a transfer", id, activity.Action))
+	}
+	return timeline, nil
+}
+
+// GetTransfers returns timelines of deposits and withdrawals requested
+// between fromTime and toTime (miliseconds).
+func (self ReserveData) GetTransfers(fromTime, toTime uint64) ([]common.TransferTimeline, error) {
+	activities, err := self.storage.GetAllRecords(fromTime*1000000, toTime*1000000)
+	if err != nil {
+		return []common.TransferTimeline{}, err
+	}
+	return common.GetTransferTimelines(activities), nil
+}
+
+// GetTransferLatencies returns credit latencies of each exchange for
+// transfers requested between fromTime and toTime (miliseconds).
+func (self ReserveData) GetTransferLatencies(fromTime, toTime uint64) (map[string]map[string]common.TransferLatency, error) {
+	timelines, err := self.GetTransfers(fromTime, toTime)
+	if err != nil {
+		return map[string]map[string]common.TransferLatency{}, err
+	}
+	return common.GetTransferLatencies(timelines), nil
+}
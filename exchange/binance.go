@@ -22,6 +22,7 @@ type Binance struct {
 	addresses    *common.ExchangeAddresses
 	exchangeInfo *common.ExchangeInfo
 	fees         common.ExchangeFees
+	mu           sync.RWMutex
 }
 
 func (self *Binance) TokenAddresses() map[string]ethereum.Address {
@@ -109,7 +110,7 @@ func (self *Binance) UpdatePairsPrecision() {
 		log.Printf("Get exchange info failed: %s\n", err)
 	} else {
 		symbols := exchangeInfo.Symbols
-		for _, pair := range self.pairs {
+		for _, pair := range self.TokenPairs() {
 			self.UpdatePrecisionLimit(pair, symbols)
 		}
 	}
@@ -133,9 +134,26 @@ func (self *Binance) ID() common.ExchangeID {
 }
 
 func (self *Binance) TokenPairs() []common.TokenPair {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
 	return self.pairs
 }
 
+// AddTokenPair adds pair to the pairs of the exchange and updates their
+// precision.
+func (self *Binance) AddTokenPair(pair common.TokenPair) {
+	self.mu.Lock()
+	pairs := []common.TokenPair{}
+	for _, p := range self.pairs {
+		if p.PairID() != pair.PairID() {
+			pairs = append(pairs, p)
+		}
+	}
+	self.pairs = append(pairs, pair)
+	self.mu.Unlock()
+	self.UpdatePairsPrecision()
+}
+
 func (self *Binance) Name() string {
 	return "binance"
 }
@@ -151,8 +169,14 @@ func (self *Binance) QueryOrder(symbol string, id uint64, timepoint uint64) (don
 	}
 }
 
-func (self *Binance) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error) {
-	result, err := self.interf.Trade(tradeType, base, quote, rate, amount, timepoint)
+func (self *Binance) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, params common.OrderParams, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error) {
+	err = params.CheckSupported(self.ID(), rate, []string{
+		common.ORDER_LIMIT, common.ORDER_MARKET, common.ORDER_IOC, common.ORDER_FOK, common.ORDER_POST_ONLY,
+	}, true)
+	if err != nil {
+		return "", 0, 0, false, err
+	}
+	result, err := self.interf.Trade(tradeType, base, quote, rate, amount, params, timepoint)
 	symbol := base.ID + quote.ID
 
 	if err != nil {
@@ -240,7 +264,7 @@ func (self *Binance) FetchOnePairData(
 func (self *Binance) FetchPriceData(timepoint uint64) (map[common.TokenPairID]common.ExchangePrice, error) {
 	wait := sync.WaitGroup{}
 	data := sync.Map{}
-	pairs := self.pairs
+	pairs := self.TokenPairs()
 	for _, pair := range pairs {
 		wait.Add(1)
 		go self.FetchOnePairData(&wait, pair, &data, timepoint)
@@ -271,7 +295,7 @@ func (self *Binance) OpenOrdersForOnePair(
 			orgQty, _ := strconv.ParseFloat(order.OrigQty, 64)
 			executedQty, _ := strconv.ParseFloat(order.ExecutedQty, 64)
 			orders = append(orders, common.Order{
-				ID:          fmt.Sprintf("%s_%s%s", order.OrderId, strings.ToUpper(pair.Base.ID), strings.ToUpper(pair.Quote.ID)),
+				ID:          fmt.Sprintf("%d_%s%s", order.OrderId, strings.ToUpper(pair.Base.ID), strings.ToUpper(pair.Quote.ID)),
 				Base:        strings.ToUpper(pair.Base.ID),
 				Quote:       strings.ToUpper(pair.Quote.ID),
 				OrderId:     fmt.Sprintf("%d", order.OrderId),
@@ -289,6 +313,7 @@ func (self *Binance) OpenOrdersForOnePair(
 		data.Store(pair.PairID(), orders)
 	} else {
 		log.Printf("Unsuccessful response from Binance: %s", err)
+		data.Store(pair.PairID(), err)
 	}
 }
 
@@ -300,7 +325,7 @@ func (self *Binance) FetchOrderData(timepoint uint64) (common.OrderEntry, error)
 
 	wait := sync.WaitGroup{}
 	data := sync.Map{}
-	pairs := self.pairs
+	pairs := self.TokenPairs()
 	for _, pair := range pairs {
 		wait.Add(1)
 		go self.OpenOrdersForOnePair(&wait, pair, &data, timepoint)
@@ -310,8 +335,13 @@ func (self *Binance) FetchOrderData(timepoint uint64) (common.OrderEntry, error)
 	result.ReturnTime = common.GetTimestamp()
 
 	data.Range(func(key, value interface{}) bool {
-		orders := value.([]common.Order)
-		result.Data = append(result.Data, orders...)
+		switch orders := value.(type) {
+		case []common.Order:
+			result.Data = append(result.Data, orders...)
+		case error:
+			result.Valid = false
+			result.Error = orders.Error()
+		}
 		return true
 	})
 	return result, nil
@@ -336,7 +366,7 @@ func (self *Binance) FetchEBalanceData(timepoint uint64) (common.EBalanceEntry,
 		} else {
 			for _, b := range resp_data.Balances {
 				tokenID := b.Asset
-				_, exist := common.SupportedTokens[tokenID]
+				_, exist := common.GetSupportedTokens()[tokenID]
 				if exist {
 					avai, _ := strconv.ParseFloat(b.Free, 64)
 					locked, _ := strconv.ParseFloat(b.Locked, 64)
@@ -354,11 +384,17 @@ func (self *Binance) FetchOnePairTradeHistory(
 	wait *sync.WaitGroup,
 	data *sync.Map,
 	pair common.TokenPair,
+	lastTrade common.TradeHistory,
 	timepoint uint64) {
 
 	defer wait.Done()
 	result := []common.TradeHistory{}
-	resp, err := self.interf.GetAccountTradeHistory(pair.Base, pair.Quote, 0, timepoint)
+	var fromID uint64
+	if lastTrade.ID != "" {
+		lastID, _ := strconv.ParseUint(lastTrade.ID, 10, 64)
+		fromID = lastID + 1
+	}
+	resp, err := self.interf.GetAccountTradeHistory(pair.Base, pair.Quote, fromID, timepoint)
 	if err != nil {
 		log.Printf("Cannot fetch data for pair %s%s: %s", pair.Base.ID, pair.Quote.ID, err.Error())
 	}
@@ -376,20 +412,21 @@ func (self *Binance) FetchOnePairTradeHistory(
 			quantity,
 			historyType,
 			trade.Time,
+			strconv.FormatUint(trade.OrderID, 10),
 		}
 		result = append(result, tradeHistory)
 	}
 	data.Store(pairString, result)
 }
 
-func (self *Binance) FetchTradeHistory(timepoint uint64) (map[common.TokenPairID][]common.TradeHistory, error) {
+func (self *Binance) FetchTradeHistory(timepoint uint64, lastTrades map[common.TokenPairID]common.TradeHistory) (map[common.TokenPairID][]common.TradeHistory, error) {
 	result := map[common.TokenPairID][]common.TradeHistory{}
 	data := sync.Map{}
-	pairs := self.pairs
+	pairs := self.TokenPairs()
 	wait := sync.WaitGroup{}
 	for _, pair := range pairs {
 		wait.Add(1)
-		go self.FetchOnePairTradeHistory(&wait, &data, pair, timepoint)
+		go self.FetchOnePairTradeHistory(&wait, &data, pair, lastTrades[pair.PairID()], timepoint)
 	}
 	wait.Wait()
 	data.Range(func(key, value interface{}) bool {
@@ -468,13 +505,39 @@ func (self *Binance) OrderStatus(id common.ActivityID, timepoint uint64) (string
 	}
 }
 
-func NewBinance(addressConfig map[string]string, feeConfig common.ExchangeFees, interf BinanceInterface) *Binance {
-	pairs, fees := getExchangePairsAndFeesFromConfig(addressConfig, feeConfig, "binance")
+// OrderFill returns filled and remaining quantity of the order of a trade
+// activity and the average price of its fills, 0 when nothing is filled.
+func (self *Binance) OrderFill(id common.ActivityID, timepoint uint64) (float64, float64, float64, error) {
+	parts := strings.Split(id.EID, "_")
+	if len(parts) != 2 {
+		return 0, 0, 0, errors.New(fmt.Sprintf("Invalid trade id %s", id.EID))
+	}
+	orderID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	result, err := self.interf.OrderStatus(parts[1], orderID, timepoint)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	done, _ := strconv.ParseFloat(result.ExecutedQty, 64)
+	total, _ := strconv.ParseFloat(result.OrigQty, 64)
+	quote, _ := strconv.ParseFloat(result.QuoteQty, 64)
+	price := 0.0
+	if done > 0 && quote > 0 {
+		price = quote / done
+	}
+	return done, total - done, price, nil
+}
+
+func NewBinance(addressConfig map[string]string, feeConfig common.ExchangeFees, pairs []common.TokenPair, interf BinanceInterface) *Binance {
+	fees := getExchangeFeesFromConfig(addressConfig, feeConfig, "binance")
 	return &Binance{
 		interf,
 		pairs,
 		common.NewExchangeAddresses(),
 		common.NewExchangeInfo(),
 		fees,
+		sync.RWMutex{},
 	}
 }
@@ -120,27 +120,47 @@ func (self *BinanceEndpoint) GetDepthOnePair(
 // Relevant params:
 // symbol ("%s%s", base, quote)
 // side (BUY/SELL)
-// type (LIMIT/MARKET)
-// timeInForce (GTC/IOC)
+// type (LIMIT/MARKET/LIMIT_MAKER)
+// timeInForce (GTC/IOC/FOK)
 // quantity
 // price
+// newClientOrderId
 //
-// In this version, we only support LIMIT order which means only buy/sell with acceptable price,
-// and GTC time in force which means that the order will be active until it's implicitly canceled
-func (self *BinanceEndpoint) Trade(tradeType string, base, quote common.Token, rate, amount float64, timepoint uint64) (exchange.Binatrade, error) {
+// Order types map to LIMIT with GTC (limit, the default), IOC (ioc) or FOK
+// (fok) time in force, LIMIT_MAKER (post_only) and MARKET (market) without
+// time in force nor price.
+func (self *BinanceEndpoint) Trade(tradeType string, base, quote common.Token, rate, amount float64, orderParams common.OrderParams, timepoint uint64) (exchange.Binatrade, error) {
 	result := exchange.Binatrade{}
 	symbol := base.ID + quote.ID
 	orderType := "LIMIT"
+	timeInForce := "GTC"
+	switch orderParams.Type() {
+	case common.ORDER_MARKET:
+		orderType = "MARKET"
+		timeInForce = ""
+	case common.ORDER_IOC:
+		timeInForce = "IOC"
+	case common.ORDER_FOK:
+		timeInForce = "FOK"
+	case common.ORDER_POST_ONLY:
+		orderType = "LIMIT_MAKER"
+		timeInForce = ""
+	}
 	params := map[string]string{
-		"symbol":      symbol,
-		"side":        strings.ToUpper(tradeType),
-		"type":        orderType,
-		"timeInForce": "GTC",
-		"quantity":    strconv.FormatFloat(amount, 'f', -1, 64),
+		"symbol":   symbol,
+		"side":     strings.ToUpper(tradeType),
+		"type":     orderType,
+		"quantity": strconv.FormatFloat(amount, 'f', -1, 64),
+	}
+	if timeInForce != "" {
+		params["timeInForce"] = timeInForce
 	}
-	if orderType == "LIMIT" {
+	if orderType != "MARKET" {
 		params["price"] = strconv.FormatFloat(rate, 'f', -1, 64)
 	}
+	if orderParams.ClientOrderID != "" {
+		params["newClientOrderId"] = orderParams.ClientOrderID
+	}
 	resp_body, err := self.GetResponse(
 		"POST",
 		self.interf.AuthenticatedEndpoint()+"/api/v3/order",
@@ -86,6 +86,7 @@ type Binaorder struct {
 	Price         string `json:"price"`
 	OrigQty       string `json:"origQty"`
 	ExecutedQty   string `json:"executedQty"`
+	QuoteQty      string `json:"cummulativeQuoteQty"`
 	Status        string `json:"status"`
 	TimeInForce   string `json:"timeInForce"`
 	Type          string `json:"type"`
@@ -32,6 +32,7 @@ type BinanceInterface interface {
 		tradeType string,
 		base, quote common.Token,
 		rate, amount float64,
+		params common.OrderParams,
 		timepoint uint64) (Binatrade, error)
 
 	CancelOrder(symbol string, id uint64) (Binacancel, error)
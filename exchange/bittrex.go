@@ -23,6 +23,7 @@ type Bittrex struct {
 	storage      BittrexStorage
 	exchangeInfo *common.ExchangeInfo
 	fees         common.ExchangeFees
+	mu           sync.RWMutex
 }
 
 func (self *Bittrex) TokenAddresses() map[string]ethereum.Address {
@@ -88,7 +89,7 @@ func (self *Bittrex) UpdatePairsPrecision() {
 	exchangeInfo, err := self.interf.GetExchangeInfo()
 	if err == nil {
 		symbols := exchangeInfo.Pairs
-		for _, pair := range self.pairs {
+		for _, pair := range self.TokenPairs() {
 			self.UpdatePrecisionLimit(pair, symbols)
 		}
 	} else {
@@ -101,9 +102,26 @@ func (self *Bittrex) ID() common.ExchangeID {
 }
 
 func (self *Bittrex) TokenPairs() []common.TokenPair {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
 	return self.pairs
 }
 
+// AddTokenPair adds pair to the pairs of the exchange and updates their
+// precision.
+func (self *Bittrex) AddTokenPair(pair common.TokenPair) {
+	self.mu.Lock()
+	pairs := []common.TokenPair{}
+	for _, p := range self.pairs {
+		if p.PairID() != pair.PairID() {
+			pairs = append(pairs, p)
+		}
+	}
+	self.pairs = append(pairs, pair)
+	self.mu.Unlock()
+	self.UpdatePairsPrecision()
+}
+
 func (self *Bittrex) Name() string {
 	return "bittrex"
 }
@@ -119,7 +137,11 @@ func (self *Bittrex) QueryOrder(uuid string, timepoint uint64) (float64, float64
 	}
 }
 
-func (self *Bittrex) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, timepoint uint64) (string, float64, float64, bool, error) {
+func (self *Bittrex) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, params common.OrderParams, timepoint uint64) (string, float64, float64, bool, error) {
+	// buylimit and selllimit take no other option
+	if err := params.CheckSupported(self.ID(), rate, []string{common.ORDER_LIMIT}, false); err != nil {
+		return "", 0, 0, false, err
+	}
 	result, err := self.interf.Trade(tradeType, base, quote, rate, amount, timepoint)
 
 	if err != nil {
@@ -307,7 +329,7 @@ func (self *Bittrex) FetchOnePairData(wq *sync.WaitGroup, pair common.TokenPair,
 func (self *Bittrex) FetchPriceData(timepoint uint64) (map[common.TokenPairID]common.ExchangePrice, error) {
 	wait := sync.WaitGroup{}
 	data := sync.Map{}
-	pairs := self.pairs
+	pairs := self.TokenPairs()
 	for _, pair := range pairs {
 		wait.Add(1)
 		go self.FetchOnePairData(&wait, pair, &data, timepoint)
@@ -321,6 +343,79 @@ func (self *Bittrex) FetchPriceData(timepoint uint64) (map[common.TokenPairID]co
 	return result, nil
 }
 
+func (self *Bittrex) OpenOrdersForOnePair(
+	wg *sync.WaitGroup,
+	pair common.TokenPair,
+	data *sync.Map,
+	timepoint uint64) {
+
+	defer wg.Done()
+
+	result, err := self.interf.OpenOrders(pair.Base, pair.Quote, timepoint)
+	if err != nil {
+		log.Printf("Unsuccessful response from Bittrex: %s", err)
+		data.Store(pair.PairID(), err)
+		return
+	}
+	orders := []common.Order{}
+	for _, order := range result.Result {
+		side := "sell"
+		if order.OrderType == "LIMIT_BUY" {
+			side = "buy"
+		}
+		timeInForce := "GTC"
+		if order.ImmediateOrCancel {
+			timeInForce = "IOC"
+		}
+		// bittrex timestamps are in UTC without zone
+		t, _ := time.Parse("2006-01-02T15:04:05", order.Opened)
+		orders = append(orders, common.Order{
+			ID:          order.OrderUuid,
+			Base:        strings.ToUpper(pair.Base.ID),
+			Quote:       strings.ToUpper(pair.Quote.ID),
+			OrderId:     order.OrderUuid,
+			Price:       order.Limit,
+			OrigQty:     order.Quantity,
+			ExecutedQty: order.Quantity - order.QuantityRemaining,
+			TimeInForce: timeInForce,
+			Type:        "LIMIT",
+			Side:        side,
+			Time:        common.TimeToTimepoint(t),
+		})
+	}
+	data.Store(pair.PairID(), orders)
+}
+
+func (self *Bittrex) FetchOrderData(timepoint uint64) (common.OrderEntry, error) {
+	result := common.OrderEntry{}
+	result.Timestamp = common.Timestamp(fmt.Sprintf("%d", timepoint))
+	result.Valid = true
+	result.Data = []common.Order{}
+
+	wait := sync.WaitGroup{}
+	data := sync.Map{}
+	pairs := self.TokenPairs()
+	for _, pair := range pairs {
+		wait.Add(1)
+		go self.OpenOrdersForOnePair(&wait, pair, &data, timepoint)
+	}
+	wait.Wait()
+
+	result.ReturnTime = common.GetTimestamp()
+
+	data.Range(func(key, value interface{}) bool {
+		switch orders := value.(type) {
+		case []common.Order:
+			result.Data = append(result.Data, orders...)
+		case error:
+			result.Valid = false
+			result.Error = orders.Error()
+		}
+		return true
+	})
+	return result, nil
+}
+
 func (self *Bittrex) FetchEBalanceData(timepoint uint64) (common.EBalanceEntry, error) {
 	result := common.EBalanceEntry{}
 	result.Timestamp = common.Timestamp(fmt.Sprintf("%d", timepoint))
@@ -337,7 +432,7 @@ func (self *Bittrex) FetchEBalanceData(timepoint uint64) (common.EBalanceEntry,
 		if resp_data.Success {
 			for _, b := range resp_data.Result {
 				tokenID := b.Currency
-				_, exist := common.SupportedTokens[tokenID]
+				_, exist := common.GetSupportedTokens()[tokenID]
 				if exist {
 					result.AvailableBalance[tokenID] = b.Available
 					result.DepositBalance[tokenID] = b.Pending
@@ -356,6 +451,7 @@ func (self *Bittrex) FetchOnePairTradeHistory(
 	wait *sync.WaitGroup,
 	data *sync.Map,
 	pair common.TokenPair,
+	lastTrade common.TradeHistory,
 	timepoint uint64) {
 
 	defer wait.Done()
@@ -365,17 +461,26 @@ func (self *Bittrex) FetchOnePairTradeHistory(
 		log.Printf("Cannot fetch data for pair %s%s: %s", pair.Base.ID, pair.Quote.ID, err.Error())
 	}
 	for _, trade := range resp.Result {
-		t, _ := time.Parse("2014-07-09T04:01:00.667", trade.TimeStamp)
+		// bittrex timestamps are in UTC without zone, e.g. 2014-07-09T04:01:00.667
+		t, _ := time.Parse("2006-01-02T15:04:05", trade.TimeStamp)
+		// bittrex has no filter on order history, skip trades which are
+		// stored already
+		if common.TimeToTimepoint(t) < lastTrade.Timestamp {
+			continue
+		}
 		historyType := "sell"
 		if trade.OrderType == "LIMIT_BUY" {
 			historyType = "buy"
 		}
+		// order history has one entry per order, Price is the total
+		// paid or received for the filled quantity
 		tradeHistory := common.TradeHistory{
 			trade.OrderUuid,
-			trade.Price,
-			trade.Quantity,
+			trade.PricePerUnit,
+			trade.Quantity - trade.QuantityRemaining,
 			historyType,
 			common.TimeToTimepoint(t),
+			trade.OrderUuid,
 		}
 		result = append(result, tradeHistory)
 	}
@@ -383,14 +488,14 @@ func (self *Bittrex) FetchOnePairTradeHistory(
 	data.Store(pairString, result)
 }
 
-func (self *Bittrex) FetchTradeHistory(timepoint uint64) (map[common.TokenPairID][]common.TradeHistory, error) {
+func (self *Bittrex) FetchTradeHistory(timepoint uint64, lastTrades map[common.TokenPairID]common.TradeHistory) (map[common.TokenPairID][]common.TradeHistory, error) {
 	result := map[common.TokenPairID][]common.TradeHistory{}
 	data := sync.Map{}
-	pairs := self.pairs
+	pairs := self.TokenPairs()
 	wait := sync.WaitGroup{}
 	for _, pair := range pairs {
 		wait.Add(1)
-		go self.FetchOnePairTradeHistory(&wait, &data, pair, timepoint)
+		go self.FetchOnePairTradeHistory(&wait, &data, pair, lastTrades[pair.PairID()], timepoint)
 	}
 	wait.Wait()
 	data.Range(func(key, value interface{}) bool {
@@ -400,8 +505,24 @@ func (self *Bittrex) FetchTradeHistory(timepoint uint64) (map[common.TokenPairID
 	return result, nil
 }
 
-func NewBittrex(addressConfig map[string]string, feeConfig common.ExchangeFees, interf BittrexInterface, storage BittrexStorage) *Bittrex {
-	pairs, fees := getExchangePairsAndFeesFromConfig(addressConfig, feeConfig, "bittrex")
+// OrderFill returns filled and remaining quantity of the order of a trade
+// activity and the average price of its fills, 0 when nothing is filled.
+func (self *Bittrex) OrderFill(id common.ActivityID, timepoint uint64) (float64, float64, float64, error) {
+	result, err := self.interf.OrderStatus(id.EID, timepoint)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	remaining := result.Result.QuantityRemaining
+	done := result.Result.Quantity - remaining
+	price := 0.0
+	if done > 0 {
+		price = result.Result.PricePerUnit
+	}
+	return done, remaining, price, nil
+}
+
+func NewBittrex(addressConfig map[string]string, feeConfig common.ExchangeFees, pairs []common.TokenPair, interf BittrexInterface, storage BittrexStorage) *Bittrex {
+	fees := getExchangeFeesFromConfig(addressConfig, feeConfig, "bittrex")
 	return &Bittrex{
 		interf,
 		pairs,
@@ -409,5 +530,6 @@ func NewBittrex(addressConfig map[string]string, feeConfig common.ExchangeFees,
 		storage,
 		common.NewExchangeInfo(),
 		fees,
+		sync.RWMutex{},
 	}
 }
@@ -293,6 +293,25 @@ func (self *BittrexEndpoint) GetAccountTradeHistory(base, quote common.Token, ti
 	return result, err
 }
 
+func (self *BittrexEndpoint) OpenOrders(base, quote common.Token, timepoint uint64) (exchange.BittOpenOrders, error) {
+	result := exchange.BittOpenOrders{}
+	resp_body, err := self.GetResponse(
+		addPath(self.interf.MarketEndpoint(timepoint), "getopenorders"),
+		map[string]string{
+			"market": fmt.Sprintf("%s-%s", quote.ID, base.ID),
+		},
+		true,
+		timepoint,
+	)
+	if err == nil {
+		json.Unmarshal(resp_body, &result)
+		if !result.Success {
+			return result, errors.New(fmt.Sprintf("Cannot get open orders: %s", result.Message))
+		}
+	}
+	return result, err
+}
+
 func NewBittrexEndpoint(signer Signer, interf Interface) *BittrexEndpoint {
 	return &BittrexEndpoint{signer, interf}
 }
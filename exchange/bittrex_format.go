@@ -132,7 +132,23 @@ type BittTradeHistory struct {
 		QuantityRemaining float64 `json:"QuantityRemaining"`
 		Commission        float64 `json:"Commission"`
 		Price             float64 `json:"Price"`
+		PricePerUnit      float64 `json:"PricePerUnit"`
 		IsConditional     bool    `json:"IsConditional"`
 		ImmediateOrCancel bool    `json:"ImmediateOrCancel"`
 	} `json:"result"`
 }
+
+type BittOpenOrders struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Result  []struct {
+		OrderUuid         string  `json:"OrderUuid"`
+		Exchange          string  `json:"Exchange"`
+		OrderType         string  `json:"OrderType"`
+		Quantity          float64 `json:"Quantity"`
+		QuantityRemaining float64 `json:"QuantityRemaining"`
+		Limit             float64 `json:"Limit"`
+		Opened            string  `json:"Opened"`
+		ImmediateOrCancel bool    `json:"ImmediateOrCancel"`
+	} `json:"result"`
+}
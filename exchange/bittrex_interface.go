@@ -19,6 +19,8 @@ type BittrexInterface interface {
 
 	GetAccountTradeHistory(base, quote common.Token, timepoint uint64) (BittTradeHistory, error)
 
+	OpenOrders(base, quote common.Token, timepoint uint64) (BittOpenOrders, error)
+
 	Withdraw(
 		token common.Token,
 		amount *big.Int,
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"sync"
 	"testing"
 
 	"github.com/KyberNetwork/reserve-data/common"
@@ -58,6 +59,10 @@ func (self testBittrexInterface) GetAccountTradeHistory(base, quote common.Token
 	return BittTradeHistory{}, nil
 }
 
+func (self testBittrexInterface) OpenOrders(base, quote common.Token, timepoint uint64) (BittOpenOrders, error) {
+	return BittOpenOrders{}, nil
+}
+
 func (self testBittrexInterface) GetDepositAddress(currency string) (BittrexDepositAddress, error) {
 	return BittrexDepositAddress{}, nil
 }
@@ -83,6 +88,7 @@ func getTestBittrex(depositHistory string, registered bool) *Bittrex {
 		&testBittrexStorage{registered},
 		&common.ExchangeInfo{},
 		common.ExchangeFees{},
+		sync.RWMutex{},
 	}
 }
 
@@ -21,6 +21,7 @@ type Huobi struct {
 	addresses    *common.ExchangeAddresses
 	exchangeInfo *common.ExchangeInfo
 	fees         common.ExchangeFees
+	mu           sync.RWMutex
 }
 
 func (self *Huobi) MarshalText() (text []byte, err error) {
@@ -70,7 +71,7 @@ func (self *Huobi) UpdatePairsPrecision() {
 	if err != nil {
 		log.Printf("Get exchange info failed: %s\n", err)
 	} else {
-		for _, pair := range self.pairs {
+		for _, pair := range self.TokenPairs() {
 			self.UpdatePrecisionLimit(pair, exchangeInfo)
 		}
 	}
@@ -94,9 +95,26 @@ func (self *Huobi) ID() common.ExchangeID {
 }
 
 func (self *Huobi) TokenPairs() []common.TokenPair {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
 	return self.pairs
 }
 
+// AddTokenPair adds pair to the pairs of the exchange and updates their
+// precision.
+func (self *Huobi) AddTokenPair(pair common.TokenPair) {
+	self.mu.Lock()
+	pairs := []common.TokenPair{}
+	for _, p := range self.pairs {
+		if p.PairID() != pair.PairID() {
+			pairs = append(pairs, p)
+		}
+	}
+	self.pairs = append(pairs, pair)
+	self.mu.Unlock()
+	self.UpdatePairsPrecision()
+}
+
 func (self *Huobi) Name() string {
 	return "huobi"
 }
@@ -112,8 +130,14 @@ func (self *Huobi) QueryOrder(symbol string, id uint64, timepoint uint64) (done
 	}
 }
 
-func (self *Huobi) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error) {
-	result, err := self.interf.Trade(tradeType, base, quote, rate, amount, timepoint)
+func (self *Huobi) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, params common.OrderParams, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error) {
+	err = params.CheckSupported(self.ID(), rate, []string{
+		common.ORDER_LIMIT, common.ORDER_MARKET, common.ORDER_IOC, common.ORDER_POST_ONLY,
+	}, true)
+	if err != nil {
+		return "", 0, 0, false, err
+	}
+	result, err := self.interf.Trade(tradeType, base, quote, rate, amount, params, timepoint)
 	symbol := base.ID + quote.ID
 
 	if err != nil {
@@ -209,7 +233,7 @@ func (self *Huobi) FetchOnePairData(
 func (self *Huobi) FetchPriceData(timepoint uint64) (map[common.TokenPairID]common.ExchangePrice, error) {
 	wait := sync.WaitGroup{}
 	data := sync.Map{}
-	pairs := self.pairs
+	pairs := self.TokenPairs()
 	for _, pair := range pairs {
 		wait.Add(1)
 		go self.FetchOnePairData(&wait, pair, &data, timepoint)
@@ -229,11 +253,41 @@ func (self *Huobi) OpenOrdersForOnePair(
 	data *sync.Map,
 	timepoint uint64) {
 
-	// defer wg.Done()
-
-	// result, err := self.interf.OpenOrdersForOnePair(pair, timepoint)
+	defer wg.Done()
 
-	//TODO: complete open orders for one pair
+	result, err := self.interf.OpenOrdersForOnePair(pair, timepoint)
+	if err != nil {
+		log.Printf("Unsuccessful response from Huobi: %s", err)
+		data.Store(pair.PairID(), err)
+		return
+	}
+	symbol := strings.ToUpper(pair.Base.ID + pair.Quote.ID)
+	orders := []common.Order{}
+	for _, order := range result.Data {
+		price, _ := strconv.ParseFloat(order.Price, 64)
+		orgQty, _ := strconv.ParseFloat(order.OrigQty, 64)
+		executedQty, _ := strconv.ParseFloat(order.ExecutedQty, 64)
+		// types are e.g. buy-limit, sell-market
+		typeParts := strings.Split(order.Type, "-")
+		orderType := ""
+		if len(typeParts) > 1 {
+			orderType = strings.ToUpper(typeParts[1])
+		}
+		orders = append(orders, common.Order{
+			ID:          fmt.Sprintf("%d_%s", order.OrderID, symbol),
+			Base:        strings.ToUpper(pair.Base.ID),
+			Quote:       strings.ToUpper(pair.Quote.ID),
+			OrderId:     fmt.Sprintf("%d", order.OrderID),
+			Price:       price,
+			OrigQty:     orgQty,
+			ExecutedQty: executedQty,
+			TimeInForce: "GTC",
+			Type:        orderType,
+			Side:        typeParts[0],
+			Time:        order.CreatedAt,
+		})
+	}
+	data.Store(pair.PairID(), orders)
 }
 
 func (self *Huobi) FetchOrderData(timepoint uint64) (common.OrderEntry, error) {
@@ -244,7 +298,7 @@ func (self *Huobi) FetchOrderData(timepoint uint64) (common.OrderEntry, error) {
 
 	wait := sync.WaitGroup{}
 	data := sync.Map{}
-	pairs := self.pairs
+	pairs := self.TokenPairs()
 	for _, pair := range pairs {
 		wait.Add(1)
 		go self.OpenOrdersForOnePair(&wait, pair, &data, timepoint)
@@ -254,8 +308,13 @@ func (self *Huobi) FetchOrderData(timepoint uint64) (common.OrderEntry, error) {
 	result.ReturnTime = common.GetTimestamp()
 
 	data.Range(func(key, value interface{}) bool {
-		orders := value.([]common.Order)
-		result.Data = append(result.Data, orders...)
+		switch orders := value.(type) {
+		case []common.Order:
+			result.Data = append(result.Data, orders...)
+		case error:
+			result.Valid = false
+			result.Error = orders.Error()
+		}
 		return true
 	})
 	return result, nil
@@ -281,7 +340,7 @@ func (self *Huobi) FetchEBalanceData(timepoint uint64) (common.EBalanceEntry, er
 			balances := resp_data.Data.List
 			for _, b := range balances {
 				tokenID := strings.ToUpper(b.Currency)
-				_, exist := common.SupportedTokens[tokenID]
+				_, exist := common.GetSupportedTokens()[tokenID]
 				if exist {
 					balance, _ := strconv.ParseFloat(b.Balance, 64)
 					if b.Type == "trade" {
@@ -302,20 +361,28 @@ func (self *Huobi) FetchOnePairTradeHistory(
 	wait *sync.WaitGroup,
 	data *sync.Map,
 	pair common.TokenPair,
+	lastTrade common.TradeHistory,
 	timepoint uint64) {
 
 	defer wait.Done()
 	result := []common.TradeHistory{}
-	resp, err := self.interf.GetAccountTradeHistory(pair.Base, pair.Quote, timepoint)
+	resp, err := self.interf.GetAccountTradeHistory(pair.Base, pair.Quote, lastTrade.Timestamp, timepoint)
 	if err != nil {
 		log.Printf("Cannot fetch data for pair %s%s: %s", pair.Base.ID, pair.Quote.ID, err.Error())
 	}
 	pairString := pair.PairID()
 	for _, trade := range resp.Data {
-		price, _ := strconv.ParseFloat(trade.Price, 64)
-		quantity, _ := strconv.ParseFloat(trade.Amount, 64)
+		// amount of a market buy is in quote token, the filled amount is
+		// in base token for every order type and the price is the average
+		// of fills, market orders have no price
+		quantity, _ := strconv.ParseFloat(trade.Filled, 64)
+		if quantity <= 0 {
+			continue
+		}
+		cash, _ := strconv.ParseFloat(trade.FilledCash, 64)
+		price := cash / quantity
 		historyType := "sell"
-		if trade.Type == "buy-limit" {
+		if strings.HasPrefix(trade.Type, "buy-") {
 			historyType = "buy"
 		}
 		tradeHistory := common.TradeHistory{
@@ -324,20 +391,21 @@ func (self *Huobi) FetchOnePairTradeHistory(
 			quantity,
 			historyType,
 			trade.Timestamp,
+			strconv.FormatUint(trade.ID, 10),
 		}
 		result = append(result, tradeHistory)
 	}
 	data.Store(pairString, result)
 }
 
-func (self *Huobi) FetchTradeHistory(timepoint uint64) (map[common.TokenPairID][]common.TradeHistory, error) {
+func (self *Huobi) FetchTradeHistory(timepoint uint64, lastTrades map[common.TokenPairID]common.TradeHistory) (map[common.TokenPairID][]common.TradeHistory, error) {
 	result := map[common.TokenPairID][]common.TradeHistory{}
 	data := sync.Map{}
-	pairs := self.pairs
+	pairs := self.TokenPairs()
 	wait := sync.WaitGroup{}
 	for _, pair := range pairs {
 		wait.Add(1)
-		go self.FetchOnePairTradeHistory(&wait, &data, pair, timepoint)
+		go self.FetchOnePairTradeHistory(&wait, &data, pair, lastTrades[pair.PairID()], timepoint)
 	}
 	wait.Wait()
 	data.Range(func(key, value interface{}) bool {
@@ -396,23 +464,42 @@ func (self *Huobi) OrderStatus(id common.ActivityID, timepoint uint64) (string,
 	if err != nil {
 		return "", err
 	}
-	if order.Data.State == "pre-submitted" || order.Data.State == "submitting" || order.Data.State == "submitted" || order.Data.State == "partial-filled" || order.Data.State == "partial-canceled" {
+	if order.Data.State == "pre-submitted" || order.Data.State == "submitting" || order.Data.State == "submitted" || order.Data.State == "partial-filled" {
 		return "", nil
 	} else {
 		return "done", nil
 	}
 }
 
-func NewHuobi(interf HuobiInterface) *Huobi {
+// OrderFill returns filled and remaining quantity of the order of a trade
+// activity and the average price of its fills, 0 when nothing is filled.
+func (self *Huobi) OrderFill(id common.ActivityID, timepoint uint64) (float64, float64, float64, error) {
+	parts := strings.Split(id.EID, "_")
+	if len(parts) != 2 {
+		return 0, 0, 0, errors.New(fmt.Sprintf("Invalid trade id %s", id.EID))
+	}
+	orderID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	result, err := self.interf.OrderStatus(parts[1], orderID, timepoint)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	done, _ := strconv.ParseFloat(result.Data.ExecutedQty, 64)
+	total, _ := strconv.ParseFloat(result.Data.OrigQty, 64)
+	cash, _ := strconv.ParseFloat(result.Data.CashAmount, 64)
+	price := 0.0
+	if done > 0 && cash > 0 {
+		price = cash / done
+	}
+	return done, total - done, price, nil
+}
+
+func NewHuobi(pairs []common.TokenPair, interf HuobiInterface) *Huobi {
 	return &Huobi{
 		interf,
-		[]common.TokenPair{
-			common.MustCreateTokenPair("OMG", "ETH"),
-			// common.MustCreateTokenPair("EOS", "ETH"),
-			// common.MustCreateTokenPair("KNC", "ETH"),
-			// common.MustCreateTokenPair("SNT", "ETH"),
-			// common.MustCreateTokenPair("SALT", "ETH"),
-		},
+		pairs,
 		common.NewExchangeAddresses(),
 		common.NewExchangeInfo(),
 		common.NewExchangeFee(
@@ -437,5 +524,6 @@ func NewHuobi(interf HuobiInterface) *Huobi {
 				},
 			),
 		),
+		sync.RWMutex{},
 	}
 }
@@ -128,10 +128,18 @@ func (self *HuobiEndpoint) GetDepthOnePair(
 	}
 }
 
-func (self *HuobiEndpoint) Trade(tradeType string, base, quote common.Token, rate, amount float64, timepoint uint64) (exchange.HuobiTrade, error) {
+func (self *HuobiEndpoint) Trade(tradeType string, base, quote common.Token, rate, amount float64, orderParams common.OrderParams, timepoint uint64) (exchange.HuobiTrade, error) {
 	result := exchange.HuobiTrade{}
 	symbol := strings.ToLower(base.ID) + strings.ToLower(quote.ID)
 	orderType := tradeType + "-limit"
+	switch orderParams.Type() {
+	case common.ORDER_MARKET:
+		orderType = tradeType + "-market"
+	case common.ORDER_IOC:
+		orderType = tradeType + "-ioc"
+	case common.ORDER_POST_ONLY:
+		orderType = tradeType + "-limit-maker"
+	}
 	accounts, _ := self.GetAccounts()
 	if len(accounts.Data) == 0 {
 		return result, errors.New("Cannot get account")
@@ -142,7 +150,17 @@ func (self *HuobiEndpoint) Trade(tradeType string, base, quote common.Token, rat
 		"source":     "api",
 		"type":       orderType,
 		"amount":     strconv.FormatFloat(amount, 'f', -1, 64),
-		"price":      strconv.FormatFloat(rate, 'f', -1, 64),
+	}
+	if orderParams.Type() == common.ORDER_MARKET {
+		// amount of market buy orders is in quote token
+		if tradeType == "buy" {
+			params["amount"] = strconv.FormatFloat(amount*rate, 'f', -1, 64)
+		}
+	} else {
+		params["price"] = strconv.FormatFloat(rate, 'f', -1, 64)
+	}
+	if orderParams.ClientOrderID != "" {
+		params["client-order-id"] = orderParams.ClientOrderID
 	}
 	resp_body, err := self.GetResponse(
 		"POST",
@@ -292,18 +310,29 @@ func (self *HuobiEndpoint) GetInfo(timepoint uint64) (exchange.HuobiInfo, error)
 	return result, err
 }
 
+// GetAccountTradeHistory returns filled and partially filled then
+// cancelled orders of a pair. Huobi only filters by date so orders since
+// the day before since (miliseconds) are returned, since 0 means all
+// orders.
 func (self *HuobiEndpoint) GetAccountTradeHistory(
 	base, quote common.Token,
+	since uint64,
 	timepoint uint64) (exchange.HuobiTradeHistory, error) {
 	result := exchange.HuobiTradeHistory{}
 	symbol := strings.ToUpper(fmt.Sprintf("%s%s", base.ID, quote.ID))
+	params := map[string]string{
+		"symbol": symbol,
+		"states": "filled,partial-canceled",
+	}
+	if since != 0 {
+		// a day earlier to cover the timezone of huobi dates
+		startDate := common.TimepointToTime(since).Add(-24 * time.Hour)
+		params["start-date"] = startDate.UTC().Format("2006-01-02")
+	}
 	resp_body, err := self.GetResponse(
 		"GET",
 		self.interf.AuthenticatedEndpoint()+"/v1/order/orders",
-		map[string]string{
-			"symbol": symbol,
-			"states": "filled",
-		},
+		params,
 		true,
 		timepoint,
 	)
@@ -314,24 +343,25 @@ func (self *HuobiEndpoint) GetAccountTradeHistory(
 }
 
 func (self *HuobiEndpoint) OpenOrdersForOnePair(
-	pair common.TokenPair, timepoint uint64) (exchange.HuobiOrder, error) {
-	// TODO: check again if use
-	result := exchange.HuobiOrder{}
+	pair common.TokenPair, timepoint uint64) (exchange.HuobiOpenOrders, error) {
+	result := exchange.HuobiOpenOrders{}
 	resp_body, err := self.GetResponse(
 		"GET",
-		self.interf.AuthenticatedEndpoint()+"/", // TODO: check again if available
+		self.interf.AuthenticatedEndpoint()+"/v1/order/orders",
 		map[string]string{
-			"symbol": pair.Base.ID + pair.Quote.ID,
+			"symbol": strings.ToLower(pair.Base.ID) + strings.ToLower(pair.Quote.ID),
+			"states": "pre-submitted,submitted,partial-filled",
 		},
 		true,
 		timepoint,
 	)
-	if err != nil {
-		return result, err
-	} else {
+	if err == nil {
 		json.Unmarshal(resp_body, &result)
-		return result, nil
+		if result.Status != "ok" {
+			err = errors.New(fmt.Sprintf("Get open orders failed: %s", result.Reason))
+		}
 	}
+	return result, err
 }
 
 func (self *HuobiEndpoint) GetDepositAddress(asset string) (exchange.HuobiDepositAddress, error) {
@@ -98,6 +98,22 @@ type HuobiOrder struct {
 		Type        string `json:"type"`
 		State       string `json:"state"`
 		ExecutedQty string `json:"field-amount"`
+		CashAmount  string `json:"field-cash-amount"`
+	} `json:"data"`
+	Reason string `json:"err-msg"`
+}
+
+type HuobiOpenOrders struct {
+	Status string `json:"status"`
+	Data   []struct {
+		OrderID     uint64 `json:"id"`
+		Symbol      string `json:"symbol"`
+		OrigQty     string `json:"amount"`
+		Price       string `json:"price"`
+		Type        string `json:"type"`
+		State       string `json:"state"`
+		ExecutedQty string `json:"field-amount"`
+		CreatedAt   uint64 `json:"created-at"`
 	} `json:"data"`
 	Reason string `json:"err-msg"`
 }
@@ -128,6 +144,8 @@ type HuobiTradeHistory struct {
 		ID         uint64 `json:"id"`
 		Symbol     string `json:"symbol"`
 		Amount     string `json:"amount"`
+		Filled     string `json:"field-amount"`
+		FilledCash string `json:"field-cash-amount"`
 		Price      string `json:"price"`
 		Timestamp  uint64 `json:"created-at"`
 		Type       string `json:"type"`
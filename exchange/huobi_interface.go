@@ -12,7 +12,7 @@ type HuobiInterface interface {
 		pair common.TokenPair, timepoint uint64) (HuobiDepth, error)
 
 	OpenOrdersForOnePair(
-		pair common.TokenPair, timepoint uint64) (HuobiOrder, error)
+		pair common.TokenPair, timepoint uint64) (HuobiOpenOrders, error)
 
 	GetInfo(timepoint uint64) (HuobiInfo, error)
 
@@ -20,7 +20,7 @@ type HuobiInterface interface {
 
 	GetDepositAddress(token string) (HuobiDepositAddress, error)
 
-	GetAccountTradeHistory(base, quote common.Token, timepoint uint64) (HuobiTradeHistory, error)
+	GetAccountTradeHistory(base, quote common.Token, since uint64, timepoint uint64) (HuobiTradeHistory, error)
 
 	Withdraw(
 		token common.Token,
@@ -32,6 +32,7 @@ type HuobiInterface interface {
 		tradeType string,
 		base, quote common.Token,
 		rate, amount float64,
+		params common.OrderParams,
 		timepoint uint64) (HuobiTrade, error)
 
 	CancelOrder(symbol string, id uint64) (HuobiCancel, error)
@@ -0,0 +1,61 @@
+package exchange
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// testHuobiInterface returns TradeHistoryMock as the trade history of
+// every pair, other calls are not expected
+type testHuobiInterface struct {
+	HuobiInterface
+	TradeHistoryMock string
+}
+
+func (self testHuobiInterface) GetAccountTradeHistory(base, quote common.Token, since uint64, timepoint uint64) (HuobiTradeHistory, error) {
+	result := HuobiTradeHistory{}
+	err := json.Unmarshal([]byte(self.TradeHistoryMock), &result)
+	return result, err
+}
+
+func TestHuobiReconcilePartiallyCancelledOrder(t *testing.T) {
+	eth := common.Token{ID: "ETH", Decimal: 18}
+	knc := common.Token{ID: "KNC", Decimal: 18}
+	// a market buy of 1 ETH of KNC filled 400 KNC for 0.9 ETH before it
+	// was cancelled and a cancelled limit sell without fills
+	huobi := NewHuobi([]common.TokenPair{{Base: knc, Quote: eth}}, testHuobiInterface{TradeHistoryMock: `{
+		"status": "ok",
+		"data": [
+			{"id": 123, "symbol": "knceth", "amount": "1", "field-amount": "400", "field-cash-amount": "0.9", "price": "0", "created-at": 1000, "type": "buy-market", "state": "partial-canceled"},
+			{"id": 124, "symbol": "knceth", "amount": "100", "field-amount": "0", "field-cash-amount": "0", "price": "0.003", "created-at": 1001, "type": "sell-limit", "state": "partial-canceled"}
+		]
+	}`})
+	history, err := huobi.FetchTradeHistory(2000, map[common.TokenPairID]common.TradeHistory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trades := history["KNC-ETH"]
+	if len(trades) != 1 || trades[0].OrderID != "123" || trades[0].Type != "buy" || trades[0].Qty != 400 || math.Abs(trades[0].Price-0.00225) > 1e-12 {
+		t.Fatalf("Unexpected trade history %+v", trades)
+	}
+	activities := []common.ActivityRecord{{
+		Action:         "trade",
+		ID:             common.ActivityID{Timepoint: 1000 * 1000000, EID: "123_KNCETH"},
+		Destination:    "huobi",
+		Params:         map[string]interface{}{"type": "buy", "amount": "500", "rate": "0.0025"},
+		ExchangeStatus: "done",
+	}}
+	all := common.AllTradeHistory{Data: map[common.ExchangeID]common.ExchangeTradeHistory{"huobi": history}}
+	report := common.Reconcile(activities, all, 0, 2000)
+	// only the filled part is reported, at its average fill price
+	if report.Matched != 0 || len(report.Issues) != 1 {
+		t.Fatalf("Unexpected reconciliation report %+v", report)
+	}
+	issue := report.Issues[0]
+	if issue.Kind != common.RECONCILIATION_QUANTITY_MISMATCH || issue.FilledQty != 400 || math.Abs(issue.FillPrice-0.00225) > 1e-12 {
+		t.Fatalf("Unexpected reconciliation issue %+v", issue)
+	}
+}
@@ -55,7 +55,10 @@ func (self *Liqui) Name() string {
 	return "liqui"
 }
 
-func (self *Liqui) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error) {
+func (self *Liqui) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, params common.OrderParams, timepoint uint64) (id string, done float64, remaining float64, finished bool, err error) {
+	if err := params.CheckSupported(self.ID(), rate, []string{common.ORDER_LIMIT}, false); err != nil {
+		return "", 0, 0, false, err
+	}
 	return self.interf.Trade(tradeType, base, quote, rate, amount, timepoint)
 }
 
@@ -126,7 +129,7 @@ func (self *Liqui) FetchEBalanceData(timepoint uint64) (common.EBalanceEntry, er
 			result.AvailableBalance = map[string]float64{}
 			result.LockedBalance = map[string]float64{}
 			result.DepositBalance = map[string]float64{}
-			for tokenID, _ := range common.SupportedTokens {
+			for tokenID, _ := range common.GetSupportedTokens() {
 				result.AvailableBalance[tokenID] = balances[strings.ToLower(tokenID)]
 				// TODO: need to take open order into account
 				result.LockedBalance[tokenID] = 0
@@ -245,21 +248,10 @@ func (self *Liqui) OrderStatus(id common.ActivityID, timepoint uint64) (string,
 	}
 }
 
-func NewLiqui(interf LiquiInterface) *Liqui {
+func NewLiqui(pairs []common.TokenPair, interf LiquiInterface) *Liqui {
 	return &Liqui{
 		interf,
-		[]common.TokenPair{
-			common.MustCreateTokenPair("OMG", "ETH"),
-			common.MustCreateTokenPair("DGD", "ETH"),
-			common.MustCreateTokenPair("CVC", "ETH"),
-			common.MustCreateTokenPair("MCO", "ETH"),
-			common.MustCreateTokenPair("GNT", "ETH"),
-			common.MustCreateTokenPair("ADX", "ETH"),
-			common.MustCreateTokenPair("EOS", "ETH"),
-			common.MustCreateTokenPair("PAY", "ETH"),
-			common.MustCreateTokenPair("BAT", "ETH"),
-			common.MustCreateTokenPair("KNC", "ETH"),
-		},
+		pairs,
 		map[string]ethereum.Address{},
 	}
 }
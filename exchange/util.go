@@ -4,12 +4,11 @@ import (
 	"github.com/KyberNetwork/reserve-data/common"
 )
 
-func getExchangePairsAndFeesFromConfig(
+func getExchangeFeesFromConfig(
 	addressConfig map[string]string,
 	feeConfig common.ExchangeFees,
-	exchange string) ([]common.TokenPair, common.ExchangeFees) {
+	exchange string) common.ExchangeFees {
 
-	pairs := []common.TokenPair{}
 	fees := common.ExchangeFees{
 		feeConfig.Trading,
 		common.FundingFee{
@@ -18,10 +17,6 @@ func getExchangePairsAndFeesFromConfig(
 		},
 	}
 	for tokenID := range addressConfig {
-		if tokenID != "ETH" {
-			pair := common.MustCreateTokenPair(tokenID, "ETH")
-			pairs = append(pairs, pair)
-		}
 		if _, exist := feeConfig.Funding.Withdraw[tokenID]; exist {
 			fees.Funding.Withdraw[tokenID] = feeConfig.Funding.Withdraw[tokenID] * 2
 		} else {
@@ -33,5 +28,5 @@ func getExchangePairsAndFeesFromConfig(
 			panic(tokenID + " is not found in " + exchange + " binance deposit fee config file")
 		}
 	}
-	return pairs, fees
+	return fees
 }
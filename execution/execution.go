@@ -0,0 +1,489 @@
+package execution
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+)
+
+const (
+	// POLL_INTERVAL is the time between two checks of a child order
+	POLL_INTERVAL time.Duration = 5 * time.Second
+	// SETTLE_ATTEMPTS is the number of checks of a cancelled child order
+	// before it is given up
+	SETTLE_ATTEMPTS int = 10
+	// SOURCE is the source of status transitions of parent orders
+	SOURCE string = "execution"
+
+	epsilon float64 = 1e-9
+)
+
+// Core places and cancels child orders.
+type Core interface {
+	Trade(
+		exchange common.Exchange,
+		tradeType string,
+		base common.Token,
+		quote common.Token,
+		rate float64,
+		amount float64,
+		params common.OrderParams,
+		timestamp uint64) (id common.ActivityID, done float64, remaining float64, finished bool, err error)
+	CancelOrder(id common.ActivityID, exchange common.Exchange) error
+}
+
+// Exchange is an exchange child orders can be tracked on.
+type Exchange interface {
+	common.Exchange
+	OrderStatus(id common.ActivityID, timepoint uint64) (string, error)
+	OrderFill(id common.ActivityID, timepoint uint64) (done float64, remaining float64, price float64, err error)
+}
+
+// Storage keeps parent orders as activities.
+type Storage interface {
+	Record(
+		action string,
+		id common.ActivityID,
+		destination string,
+		params map[string]interface{},
+		result map[string]interface{},
+		estatus string,
+		mstatus string,
+		timepoint uint64) error
+	UpdateActivity(id common.ActivityID, activity common.ActivityRecord) error
+	GetActivity(id common.ActivityID) (common.ActivityRecord, error)
+	GetPendingActivities() ([]common.ActivityRecord, error)
+}
+
+// ControlStorage tells if rebalancing, parent orders included, is enabled.
+type ControlStorage interface {
+	GetRebalanceControl() (metric.RebalanceControl, error)
+}
+
+type runningOrder struct {
+	order  ParentOrder
+	record common.ActivityRecord
+	cancel chan bool
+	done   chan bool
+}
+
+// Execution runs parent orders, each one in its own goroutine, and keeps
+// their progress in their activities.
+type Execution struct {
+	core      Core
+	storage   Storage
+	control   ControlStorage
+	exchanges map[common.ExchangeID]Exchange
+	poll      time.Duration
+	mu        sync.Mutex
+	running   map[common.ActivityID]*runningOrder
+}
+
+func NewExecution(core Core, storage Storage, control ControlStorage, exchanges []Exchange) *Execution {
+	exchangeMap := map[common.ExchangeID]Exchange{}
+	for _, exchange := range exchanges {
+		exchangeMap[exchange.ID()] = exchange
+	}
+	return &Execution{
+		core:      core,
+		storage:   storage,
+		control:   control,
+		exchanges: exchangeMap,
+		poll:      POLL_INTERVAL,
+		running:   map[common.ActivityID]*runningOrder{},
+	}
+}
+
+func (self *Execution) validate(order ParentOrder) error {
+	if _, found := self.exchanges[order.Exchange]; !found {
+		return errors.New(fmt.Sprintf("Exchange %s doesn't support parent orders", order.Exchange))
+	}
+	if order.Type != "buy" && order.Type != "sell" {
+		return errors.New(fmt.Sprintf("Trade type of %s is not supported.", order.Type))
+	}
+	if order.Amount <= 0 || order.Rate <= 0 {
+		return errors.New("Amount and rate must be positive")
+	}
+	switch order.Algo {
+	case TWAP:
+		if order.Slices <= 0 || order.Interval == 0 {
+			return errors.New("TWAP needs positive slices and interval")
+		}
+	case ICEBERG:
+		if order.Visible <= 0 {
+			return errors.New("Iceberg needs positive visible amount")
+		}
+	default:
+		return errors.New(fmt.Sprintf("Algorithm %s is not supported", order.Algo))
+	}
+	return nil
+}
+
+// Submit records the parent order and starts executing it, the returned
+// order has its id.
+func (self *Execution) Submit(order ParentOrder) (ParentOrder, error) {
+	if err := self.validate(order); err != nil {
+		return order, err
+	}
+	base, err := common.GetPairToken(order.Base)
+	if err != nil {
+		return order, err
+	}
+	quote, err := common.GetPairToken(order.Quote)
+	if err != nil {
+		return order, err
+	}
+	timepoint := common.GetTimepoint()
+	order.ID = common.NewActivityID(uint64(time.Now().UnixNano()), fmt.Sprintf("%s_%s_%s", order.Algo, base.ID, quote.ID))
+	order.Status = string(common.ActivityStateSubmitted)
+	order.Children = []ChildOrder{}
+	order.Timestamp = timepoint
+	if err = self.storage.Record(
+		"parent_order",
+		order.ID,
+		string(order.Exchange),
+		order.params(),
+		order.result(),
+		order.Status,
+		"",
+		timepoint,
+	); err != nil {
+		return order, err
+	}
+	record, err := self.storage.GetActivity(order.ID)
+	if err != nil {
+		return order, err
+	}
+	running := &runningOrder{
+		order:  order,
+		record: record,
+		cancel: make(chan bool, 1),
+		done:   make(chan bool),
+	}
+	self.mu.Lock()
+	self.running[order.ID] = running
+	self.mu.Unlock()
+	log.Printf("Execution: started %s order %s on %s: %s %f %s at %f", order.Algo, order.ID, order.Exchange, order.Type, order.Amount, order.Base, order.Rate)
+	go self.execute(running, self.exchanges[order.Exchange], base, quote)
+	return order, nil
+}
+
+// Cancel stops a running parent order, cancels its open child order and
+// returns the order once it is finished.
+func (self *Execution) Cancel(id common.ActivityID) (ParentOrder, error) {
+	self.mu.Lock()
+	running, found := self.running[id]
+	self.mu.Unlock()
+	if !found {
+		return ParentOrder{}, errors.New(fmt.Sprintf("Parent order %s is not running", id))
+	}
+	select {
+	case running.cancel <- true:
+	default:
+	}
+	<-running.done
+	return self.GetParentOrder(id)
+}
+
+// GetParentOrder returns the parent order of id with its latest progress.
+func (self *Execution) GetParentOrder(id common.ActivityID) (ParentOrder, error) {
+	activity, err := self.storage.GetActivity(id)
+	if err != nil {
+		return ParentOrder{}, err
+	}
+	if activity.Action != "parent_order" {
+		return ParentOrder{}, errors.New(fmt.Sprintf("Activity %s is not a parent order", id))
+	}
+	return NewParentOrder(activity), nil
+}
+
+// GetRunningOrders returns parent orders which are not finished yet.
+func (self *Execution) GetRunningOrders() ([]ParentOrder, error) {
+	result := []ParentOrder{}
+	pendings, err := self.storage.GetPendingActivities()
+	if err != nil {
+		return result, err
+	}
+	for _, activity := range pendings {
+		if activity.Action == "parent_order" {
+			result = append(result, NewParentOrder(activity))
+		}
+	}
+	return result, nil
+}
+
+// save persists progress of the running order
+func (self *Execution) save(running *runningOrder) {
+	running.record.Result = running.order.result()
+	if err := self.storage.UpdateActivity(running.order.ID, running.record); err != nil {
+		log.Printf("Execution: saving parent order %s failed: %s", running.order.ID, err)
+	}
+}
+
+// finish moves the running order to its final status
+func (self *Execution) finish(running *runningOrder, status common.ActivityState, reason string) {
+	running.order.Error = reason
+	running.order.Status = string(status)
+	running.record.Result = running.order.result()
+	if err := running.record.UpdateExchangeStatus(string(status), SOURCE, common.GetTimestamp()); err != nil {
+		log.Printf("Execution: %s", err)
+	}
+	if err := self.storage.UpdateActivity(running.order.ID, running.record); err != nil {
+		log.Printf("Execution: saving parent order %s failed: %s", running.order.ID, err)
+	}
+	self.mu.Lock()
+	delete(self.running, running.order.ID)
+	self.mu.Unlock()
+	close(running.done)
+	log.Printf("Execution: %s order %s is %s, filled %f at %f %s", running.order.Algo, running.order.ID, status, running.order.Filled, running.order.AvgPrice, reason)
+}
+
+// progress sums fills of children into the parent order, at their fill
+// price or at their limit rate when the exchange doesn't report it.
+func (self *Execution) progress(order *ParentOrder) {
+	filled, cost := 0.0, 0.0
+	for _, child := range order.Children {
+		price := child.Price
+		if price <= 0 {
+			price = child.Rate
+		}
+		filled += child.Filled
+		cost += child.Filled * price
+	}
+	order.Filled = filled
+	if filled > 0 {
+		order.AvgPrice = cost / filled
+	}
+}
+
+// place trades amount as a new child order of the running order, it
+// returns false without trading if rebalancing is held.
+func (self *Execution) place(running *runningOrder, exchange Exchange, base, quote common.Token, amount float64) (bool, error) {
+	control, err := self.control.GetRebalanceControl()
+	if err != nil {
+		return false, err
+	}
+	if !control.Status {
+		return false, nil
+	}
+	order := &running.order
+	timepoint := common.GetTimepoint()
+	id, done, _, finished, err := self.core.Trade(
+		exchange, order.Type, base, quote, order.Rate, amount,
+		common.OrderParams{}, timepoint)
+	if err != nil {
+		return false, err
+	}
+	child := ChildOrder{
+		ID:     id,
+		Amount: amount,
+		Filled: done,
+		Rate:   order.Rate,
+		Done:   finished,
+	}
+	if done > 0 {
+		if _, _, price, err := exchange.OrderFill(id, timepoint); err == nil {
+			child.Price = price
+		} else {
+			log.Printf("Execution: getting fill price of %s failed: %s", id, err)
+		}
+	}
+	order.Children = append(order.Children, child)
+	self.progress(order)
+	self.save(running)
+	return true, nil
+}
+
+// refresh updates fill of the last child order of the running order
+func (self *Execution) refresh(running *runningOrder, exchange Exchange) error {
+	order := &running.order
+	child := &order.Children[len(order.Children)-1]
+	if child.Done {
+		return nil
+	}
+	timepoint := common.GetTimepoint()
+	status, err := exchange.OrderStatus(child.ID, timepoint)
+	if err != nil {
+		return err
+	}
+	filled, _, price, err := exchange.OrderFill(child.ID, timepoint)
+	if err != nil {
+		return err
+	}
+	if filled != child.Filled || price != child.Price || status == "done" {
+		child.Filled = filled
+		child.Price = price
+		child.Done = status == "done"
+		self.progress(order)
+		self.save(running)
+	}
+	return nil
+}
+
+// settle cancels the last child order of the running order and waits
+// until its final fill is known.
+func (self *Execution) settle(running *runningOrder, exchange Exchange) error {
+	child := running.order.Children[len(running.order.Children)-1]
+	if child.Done {
+		return nil
+	}
+	if err := self.core.CancelOrder(child.ID, exchange); err != nil {
+		log.Printf("Execution: cancelling child order %s failed: %s", child.ID, err)
+	}
+	for i := 0; i < SETTLE_ATTEMPTS; i++ {
+		if err := self.refresh(running, exchange); err != nil {
+			log.Printf("Execution: checking child order %s failed: %s", child.ID, err)
+		}
+		if running.order.Children[len(running.order.Children)-1].Done {
+			return nil
+		}
+		time.Sleep(self.poll)
+	}
+	return errors.New(fmt.Sprintf("Child order %s is not finished after cancel", child.ID))
+}
+
+// wait polls the last child order until deadline, or until it is done if
+// untilDone is set. It returns false if the order is cancelled.
+func (self *Execution) wait(running *runningOrder, exchange Exchange, deadline time.Time, untilDone bool) bool {
+	for {
+		if untilDone && running.order.Children[len(running.order.Children)-1].Done {
+			return true
+		}
+		sleep := self.poll
+		if !deadline.IsZero() {
+			left := deadline.Sub(time.Now())
+			if left <= 0 {
+				return true
+			}
+			if left < sleep {
+				sleep = left
+			}
+		}
+		select {
+		case <-running.cancel:
+			return false
+		case <-time.After(sleep):
+		}
+		if err := self.refresh(running, exchange); err != nil {
+			log.Printf("Execution: checking child order failed: %s", err)
+		}
+	}
+}
+
+// execute places child orders of the running order until it is filled,
+// cancelled or rebalancing is held, which cancels it too.
+func (self *Execution) execute(running *runningOrder, exchange Exchange, base, quote common.Token) {
+	order := &running.order
+	cancelled, held, placed := false, false, false
+	var err error
+	switch order.Algo {
+	case TWAP:
+		interval := time.Duration(order.Interval) * time.Millisecond
+		start := time.Now()
+		for i := 1; i <= order.Slices && !cancelled; i++ {
+			// unfilled part of previous slices is carried to this one
+			amount := order.Amount*float64(i)/float64(order.Slices) - order.Filled
+			if amount > epsilon {
+				if placed, err = self.place(running, exchange, base, quote, amount); err != nil {
+					break
+				}
+				if held = !placed; held {
+					break
+				}
+			}
+			cancelled = !self.wait(running, exchange, start.Add(time.Duration(i)*interval), false)
+			if len(order.Children) > 0 {
+				if err = self.settle(running, exchange); err != nil {
+					break
+				}
+			}
+		}
+	case ICEBERG:
+		for order.Amount-order.Filled > epsilon && !cancelled {
+			amount := order.Amount - order.Filled
+			if amount > order.Visible {
+				amount = order.Visible
+			}
+			if placed, err = self.place(running, exchange, base, quote, amount); err != nil {
+				break
+			}
+			if held = !placed; held {
+				break
+			}
+			cancelled = !self.wait(running, exchange, time.Time{}, true)
+			if cancelled {
+				err = self.settle(running, exchange)
+			} else if order.Children[len(order.Children)-1].Filled <= 0 {
+				err = errors.New(fmt.Sprintf("Child order %s finished without fill", order.Children[len(order.Children)-1].ID))
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+	switch {
+	case err != nil:
+		self.finish(running, common.ActivityStateFailed, err.Error())
+	case held:
+		self.finish(running, common.ActivityStateCancelled, "rebalance is held")
+	case cancelled:
+		self.finish(running, common.ActivityStateCancelled, "")
+	default:
+		self.finish(running, common.ActivityStateDone, "")
+	}
+}
+
+// Run fails parent orders left running by a previous process and cancels
+// their open child orders, parent orders are not resumed.
+func (self *Execution) Run() error {
+	orders, err := self.GetRunningOrders()
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		exchange, found := self.exchanges[order.Exchange]
+		for _, child := range order.Children {
+			if found && !child.Done {
+				if err := self.core.CancelOrder(child.ID, exchange); err != nil {
+					log.Printf("Execution: cancelling child order %s failed: %s", child.ID, err)
+				}
+			}
+		}
+		record, err := self.storage.GetActivity(order.ID)
+		if err != nil {
+			return err
+		}
+		if record.Result == nil {
+			record.Result = map[string]interface{}{}
+		}
+		record.Result["error"] = "interrupted"
+		if err = record.UpdateExchangeStatus(string(common.ActivityStateFailed), SOURCE, common.GetTimestamp()); err != nil {
+			return err
+		}
+		if err = self.storage.UpdateActivity(order.ID, record); err != nil {
+			return err
+		}
+		log.Printf("Execution: parent order %s is interrupted, filled %s", order.ID, strconv.FormatFloat(order.Filled, 'f', -1, 64))
+	}
+	return nil
+}
+
+// Stop cancels every running parent order.
+func (self *Execution) Stop() error {
+	self.mu.Lock()
+	ids := []common.ActivityID{}
+	for id := range self.running {
+		ids = append(ids, id)
+	}
+	self.mu.Unlock()
+	for _, id := range ids {
+		self.Cancel(id)
+	}
+	return nil
+}
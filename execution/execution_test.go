@@ -0,0 +1,283 @@
+package execution
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+)
+
+type testOrder struct {
+	amount, filled, price float64
+	done                  bool
+}
+
+// testExchange fills fillRatio of each order when it is placed, 10% better
+// than its rate, orders which are not fully filled stay open until they
+// are cancelled.
+type testExchange struct {
+	common.TestExchange
+	mu        sync.Mutex
+	fillRatio float64
+	orders    map[string]*testOrder
+}
+
+func newTestExchange(fillRatio float64) *testExchange {
+	return &testExchange{fillRatio: fillRatio, orders: map[string]*testOrder{}}
+}
+
+func (self *testExchange) Trade(tradeType string, base common.Token, quote common.Token, rate float64, amount float64, params common.OrderParams, timepoint uint64) (string, float64, float64, bool, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	id := fmt.Sprintf("%d", len(self.orders)+1)
+	order := &testOrder{amount: amount, filled: amount * self.fillRatio, price: rate * 0.9}
+	if tradeType == "sell" {
+		order.price = rate * 1.1
+	}
+	order.done = order.filled >= amount
+	self.orders[id] = order
+	return id, order.filled, amount - order.filled, order.done, nil
+}
+
+func (self *testExchange) CancelOrder(id common.ActivityID) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.orders[id.EID].done = true
+	return nil
+}
+
+func (self *testExchange) OrderStatus(id common.ActivityID, timepoint uint64) (string, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.orders[id.EID].done {
+		return "done", nil
+	}
+	return "", nil
+}
+
+func (self *testExchange) OrderFill(id common.ActivityID, timepoint uint64) (float64, float64, float64, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	order := self.orders[id.EID]
+	if order.filled == 0 {
+		return 0, order.amount, 0, nil
+	}
+	return order.filled, order.amount - order.filled, order.price, nil
+}
+
+type testCore struct{}
+
+func (self testCore) Trade(exchange common.Exchange, tradeType string, base common.Token, quote common.Token, rate float64, amount float64, params common.OrderParams, timestamp uint64) (common.ActivityID, float64, float64, bool, error) {
+	id, done, remaining, finished, err := exchange.Trade(tradeType, base, quote, rate, amount, params, timestamp)
+	return common.NewActivityID(uint64(time.Now().UnixNano()), id), done, remaining, finished, err
+}
+
+func (self testCore) CancelOrder(id common.ActivityID, exchange common.Exchange) error {
+	return exchange.CancelOrder(id)
+}
+
+// testStorage keeps activities encoded as the bolt storage does
+type testStorage struct {
+	mu         sync.Mutex
+	activities map[common.ActivityID][]byte
+}
+
+func (self *testStorage) Record(action string, id common.ActivityID, destination string, params map[string]interface{}, result map[string]interface{}, estatus string, mstatus string, timepoint uint64) error {
+	record := common.NewActivityRecord(action, id, destination, params, result, estatus, mstatus, common.Timestamp(fmt.Sprintf("%d", timepoint)))
+	return self.UpdateActivity(id, record)
+}
+
+func (self *testStorage) UpdateActivity(id common.ActivityID, activity common.ActivityRecord) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	data, err := json.Marshal(activity)
+	self.activities[id] = data
+	return err
+}
+
+func (self *testStorage) GetActivity(id common.ActivityID) (common.ActivityRecord, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	record := common.ActivityRecord{}
+	data, found := self.activities[id]
+	if !found {
+		return record, errors.New("Activity is not found")
+	}
+	err := json.Unmarshal(data, &record)
+	return record, err
+}
+
+func (self *testStorage) GetPendingActivities() ([]common.ActivityRecord, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	result := []common.ActivityRecord{}
+	for _, data := range self.activities {
+		record := common.ActivityRecord{}
+		json.Unmarshal(data, &record)
+		if record.IsPending() {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+// testControl holds rebalancing when enabled is false
+type testControl struct {
+	mu      sync.Mutex
+	enabled bool
+}
+
+func (self *testControl) GetRebalanceControl() (metric.RebalanceControl, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return metric.RebalanceControl{Status: self.enabled}, nil
+}
+
+func (self *testControl) hold() {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.enabled = false
+}
+
+func newTestExecution(exchange *testExchange) *Execution {
+	common.SupportedTokens = map[string]common.Token{
+		"ETH": common.Token{ID: "ETH", Decimal: 18},
+		"OMG": common.Token{ID: "OMG", Decimal: 18},
+	}
+	execution := NewExecution(testCore{}, &testStorage{activities: map[common.ActivityID][]byte{}}, &testControl{enabled: true}, []Exchange{exchange})
+	execution.poll = time.Millisecond
+	return execution
+}
+
+// waitFinished waits until the parent order is not pending anymore
+func waitFinished(t *testing.T, execution *Execution, id common.ActivityID) ParentOrder {
+	for i := 0; i < 1000; i++ {
+		order, err := execution.GetParentOrder(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order.Status != string(common.ActivityStateSubmitted) {
+			return order
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Parent order %s is not finished", id)
+	return ParentOrder{}
+}
+
+func TestTWAPCarriesUnfilledAmount(t *testing.T) {
+	exchange := newTestExchange(0.5)
+	execution := newTestExecution(exchange)
+	order, err := execution.Submit(ParentOrder{
+		Algo: TWAP, Exchange: exchange.ID(), Type: "buy", Base: "OMG", Quote: "ETH",
+		Rate: 0.01, Amount: 10, Slices: 2, Interval: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	order = waitFinished(t, execution, order.ID)
+	// first slice 5 fills 2.5, second slice 10 - 2.5 fills 3.75, both at
+	// their fill price below the rate
+	if order.Status != "done" || len(order.Children) != 2 || order.Children[1].Amount != 7.5 || order.Filled != 6.25 || math.Abs(order.AvgPrice-0.009) > 1e-12 {
+		t.Fatalf("Unexpected TWAP order %+v", order)
+	}
+	for _, child := range order.Children {
+		if !child.Done {
+			t.Fatalf("Child order %s is not settled", child.ID)
+		}
+	}
+}
+
+func TestIcebergRefills(t *testing.T) {
+	exchange := newTestExchange(1)
+	execution := newTestExecution(exchange)
+	order, err := execution.Submit(ParentOrder{
+		Algo: ICEBERG, Exchange: exchange.ID(), Type: "sell", Base: "OMG", Quote: "ETH",
+		Rate: 0.01, Amount: 10, Visible: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	order = waitFinished(t, execution, order.ID)
+	if order.Status != "done" || len(order.Children) != 4 || order.Children[3].Amount != 1 || order.Filled != 10 || math.Abs(order.AvgPrice-0.011) > 1e-12 {
+		t.Fatalf("Unexpected iceberg order %+v", order)
+	}
+}
+
+func TestCancelParentOrder(t *testing.T) {
+	exchange := newTestExchange(0)
+	execution := newTestExecution(exchange)
+	order, err := execution.Submit(ParentOrder{
+		Algo: ICEBERG, Exchange: exchange.ID(), Type: "sell", Base: "OMG", Quote: "ETH",
+		Rate: 0.01, Amount: 10, Visible: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, err := execution.GetRunningOrders()
+	if err != nil || len(running) != 1 {
+		t.Fatalf("Expected 1 running order, got %+v (%v)", running, err)
+	}
+	order, err = execution.Cancel(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "cancelled" || len(order.Children) != 1 || !order.Children[0].Done || order.Filled != 0 {
+		t.Fatalf("Unexpected cancelled order %+v", order)
+	}
+	if _, err = execution.Cancel(order.ID); err == nil {
+		t.Fatal("Expected error cancelling a finished order")
+	}
+}
+
+func TestRebalanceHeldCancelsParentOrder(t *testing.T) {
+	exchange := newTestExchange(0.5)
+	execution := newTestExecution(exchange)
+	order, err := execution.Submit(ParentOrder{
+		Algo: TWAP, Exchange: exchange.ID(), Type: "buy", Base: "OMG", Quote: "ETH",
+		Rate: 0.01, Amount: 9, Slices: 3, Interval: 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first slice is placed before rebalancing is held
+	for i := 0; i < 1000; i++ {
+		exchange.mu.Lock()
+		placed := len(exchange.orders)
+		exchange.mu.Unlock()
+		if placed > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	execution.control.(*testControl).hold()
+	order = waitFinished(t, execution, order.ID)
+	if order.Status != "cancelled" || order.Error != "rebalance is held" || len(order.Children) != 1 || !order.Children[0].Done || order.Filled != 1.5 {
+		t.Fatalf("Unexpected order after rebalance is held %+v", order)
+	}
+	if len(exchange.orders) != 1 {
+		t.Fatalf("Unexpected orders placed while rebalance is held %+v", exchange.orders)
+	}
+}
+
+func TestSubmitValidation(t *testing.T) {
+	exchange := newTestExchange(1)
+	execution := newTestExecution(exchange)
+	invalids := []ParentOrder{
+		{Algo: TWAP, Exchange: "unknown", Type: "buy", Base: "OMG", Quote: "ETH", Rate: 0.01, Amount: 1, Slices: 1, Interval: 1},
+		{Algo: TWAP, Exchange: exchange.ID(), Type: "buy", Base: "OMG", Quote: "ETH", Rate: 0.01, Amount: 1},
+		{Algo: ICEBERG, Exchange: exchange.ID(), Type: "buy", Base: "OMG", Quote: "ETH", Rate: 0.01, Amount: 1},
+		{Algo: "vwap", Exchange: exchange.ID(), Type: "buy", Base: "OMG", Quote: "ETH", Rate: 0.01, Amount: 1},
+	}
+	for _, order := range invalids {
+		if _, err := execution.Submit(order); err == nil {
+			t.Fatalf("Expected error submitting %+v", order)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package execution
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// algorithms of parent orders
+const (
+	// TWAP splits the amount evenly over Slices intervals, the part of a
+	// slice not filled by the end of its interval is carried to the next
+	TWAP string = "twap"
+	// ICEBERG shows at most Visible on the book and refills it when the
+	// shown order is filled
+	ICEBERG string = "iceberg"
+)
+
+// ChildOrder is one trade activity placed for a parent order. Rate is its
+// limit rate, Price the average price of its fills, Done is set once the
+// order is finished on the exchange.
+type ChildOrder struct {
+	ID     common.ActivityID
+	Amount float64
+	Filled float64
+	Rate   float64
+	Price  float64
+	Done   bool
+}
+
+// ParentOrder is an order executed by an algorithm as child trades on one
+// exchange. Filled and AvgPrice are the progress of its children, Status
+// is the exchange status of its activity.
+type ParentOrder struct {
+	ID        common.ActivityID
+	Algo      string
+	Exchange  common.ExchangeID
+	Type      string
+	Base      string
+	Quote     string
+	Rate      float64
+	Amount    float64
+	Slices    int
+	Interval  uint64
+	Visible   float64
+	Status    string
+	Filled    float64
+	AvgPrice  float64
+	Children  []ChildOrder
+	Error     string
+	Timestamp uint64
+}
+
+func (self ParentOrder) params() map[string]interface{} {
+	return map[string]interface{}{
+		"algo":     self.Algo,
+		"type":     self.Type,
+		"base":     self.Base,
+		"quote":    self.Quote,
+		"rate":     self.Rate,
+		"amount":   strconv.FormatFloat(self.Amount, 'f', -1, 64),
+		"slices":   self.Slices,
+		"interval": self.Interval,
+		"visible":  self.Visible,
+	}
+}
+
+func (self ParentOrder) result() map[string]interface{} {
+	return map[string]interface{}{
+		"filled":   self.Filled,
+		"avgPrice": self.AvgPrice,
+		"children": self.Children,
+		"error":    self.Error,
+	}
+}
+
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case string:
+		result, _ := strconv.ParseFloat(v, 64)
+		return result
+	}
+	return 0
+}
+
+func toString(value interface{}) string {
+	if v, ok := value.(string); ok {
+		return v
+	}
+	return ""
+}
+
+// NewParentOrder reads a parent order from its activity.
+func NewParentOrder(activity common.ActivityRecord) ParentOrder {
+	result := ParentOrder{
+		ID:        activity.ID,
+		Algo:      toString(activity.Params["algo"]),
+		Exchange:  common.ExchangeID(activity.Destination),
+		Type:      toString(activity.Params["type"]),
+		Base:      toString(activity.Params["base"]),
+		Quote:     toString(activity.Params["quote"]),
+		Rate:      toFloat(activity.Params["rate"]),
+		Amount:    toFloat(activity.Params["amount"]),
+		Slices:    int(toFloat(activity.Params["slices"])),
+		Interval:  uint64(toFloat(activity.Params["interval"])),
+		Visible:   toFloat(activity.Params["visible"]),
+		Status:    activity.ExchangeStatus,
+		Filled:    toFloat(activity.Result["filled"]),
+		AvgPrice:  toFloat(activity.Result["avgPrice"]),
+		Children:  []ChildOrder{},
+		Error:     toString(activity.Result["error"]),
+		Timestamp: activity.Timestamp.ToUint64(),
+	}
+	switch children := activity.Result["children"].(type) {
+	case []ChildOrder:
+		result.Children = append(result.Children, children...)
+	case []interface{}:
+		// children of a stored activity are decoded as generic values
+		if data, err := json.Marshal(children); err == nil {
+			json.Unmarshal(data, &result.Children)
+		}
+	}
+	return result
+}
@@ -0,0 +1,501 @@
+package hedging
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// HEDGE_INTERVAL is the time between two checks of exposure
+	HEDGE_INTERVAL time.Duration = 30 * time.Second
+	// DEFAULT_WINDOW is the window exposure is netted over (miliseconds)
+	// when it is not configured
+	DEFAULT_WINDOW uint64 = 60 * 60 * 1000
+	// MAX_WINDOW is the longest window trade logs can be read over
+	MAX_WINDOW uint64 = 24 * 60 * 60 * 1000
+	// MAX_HEDGES is the number of latest hedges shown in status
+	MAX_HEDGES int    = 100
+	DAY        uint64 = 24 * 60 * 60 * 1000
+)
+
+// Storage is the data storage hedging reads books from.
+type Storage interface {
+	CurrentPriceVersion(timepoint uint64) (common.Version, error)
+	GetOnePrice(common.TokenPairID, common.Version) (common.OnePrice, error)
+}
+
+// StatStorage is the stat storage hedging reads trades of the reserve
+// from.
+type StatStorage interface {
+	GetTradeLogs(fromTime uint64, toTime uint64) ([]common.TradeLog, error)
+}
+
+// ControlStorage tells if rebalancing, hedging included, is enabled.
+type ControlStorage interface {
+	GetRebalanceControl() (metric.RebalanceControl, error)
+}
+
+// Core places hedging trades.
+type Core interface {
+	Trade(
+		exchange common.Exchange,
+		tradeType string,
+		base common.Token,
+		quote common.Token,
+		rate float64,
+		amount float64,
+		params common.OrderParams,
+		timestamp uint64) (id common.ActivityID, done float64, remaining float64, finished bool, err error)
+}
+
+// Hedge is a trade placed to offset Exposure of Token. Only its Filled
+// quantity offsets exposure, netted against the trades it offsets in
+// Offsets. Hedges with Error don't offset exposure.
+type Hedge struct {
+	Token     string
+	Side      string
+	Amount    float64
+	Rate      float64
+	Exposure  float64
+	TradeID   common.ActivityID
+	Error     string
+	Timestamp uint64
+	Filled    float64
+	Finished  bool
+	Offsets   []HedgeOffset
+}
+
+// HedgeOffset is the part of a hedge fill netted against Trade logged at
+// Timestamp, it stops offsetting exposure when the trade leaves the
+// window. Fills exceeding trades in the window have no Trade and are
+// timestamped with the hedge.
+type HedgeOffset struct {
+	Trade     string
+	Timestamp uint64
+	Amount    float64
+}
+
+// netted returns how much of the hedge fill is netted against trades
+func (self Hedge) netted() float64 {
+	result := 0.0
+	for _, offset := range self.Offsets {
+		result += math.Abs(offset.Amount)
+	}
+	return result
+}
+
+// inWindow returns true if the hedge offsets exposure from fromTime
+func (self Hedge) inWindow(fromTime uint64) bool {
+	for _, offset := range self.Offsets {
+		if offset.Timestamp >= fromTime {
+			return true
+		}
+	}
+	return false
+}
+
+// Exchange is an exchange hedges can be placed on, it reports status and
+// fills of orders so open hedges are followed until they finish.
+type Exchange interface {
+	common.Exchange
+	OrderStatus(id common.ActivityID, timepoint uint64) (string, error)
+	OrderFill(id common.ActivityID, timepoint uint64) (done float64, remaining float64, price float64, err error)
+}
+
+// tradeExposure is what a trade logged at timestamp left to hedge of a
+// token.
+type tradeExposure struct {
+	key       string
+	timestamp uint64
+	remaining float64
+}
+
+func tradeKey(l common.TradeLog) string {
+	return fmt.Sprintf("%s_%d", l.TransactionHash.Hex(), l.TransactionIndex)
+}
+
+// offsetTrades nets amount filled by a hedge against the oldest trades
+// it offsets, a sell (negative amount) offsets what the reserve received.
+func offsetTrades(trades []*tradeExposure, amount float64, timestamp uint64) []HedgeOffset {
+	result := []HedgeOffset{}
+	for _, trade := range trades {
+		if amount == 0 {
+			break
+		}
+		if trade.remaining*amount >= 0 {
+			continue
+		}
+		take := amount
+		if math.Abs(take) > math.Abs(trade.remaining) {
+			take = -trade.remaining
+		}
+		trade.remaining += take
+		amount -= take
+		result = append(result, HedgeOffset{Trade: trade.key, Timestamp: trade.timestamp, Amount: take})
+	}
+	if amount != 0 {
+		result = append(result, HedgeOffset{Timestamp: timestamp, Amount: amount})
+	}
+	return result
+}
+
+// TokenExposure is the exposure of a token in the window: Traded is what
+// the reserve received (positive) or paid (negative) in trades, Hedged is
+// what hedges offset, Pending is what open hedges may still offset and
+// Exposure is what is left to hedge.
+type TokenExposure struct {
+	Enabled     bool
+	Traded      float64
+	Hedged      float64
+	Pending     float64
+	Exposure    float64
+	HedgedToday float64
+}
+
+type Status struct {
+	Exchange         common.ExchangeID
+	Window           uint64
+	RebalanceEnabled bool
+	Timestamp        uint64
+	Exposures        map[string]TokenExposure
+	Hedges           []Hedge
+}
+
+type Hedging struct {
+	config      common.HedgingConfig
+	window      uint64
+	exchange    Exchange
+	core        Core
+	storage     Storage
+	statStorage StatStorage
+	control     ControlStorage
+	reserve     ethereum.Address
+	started     uint64
+	mu          sync.Mutex
+	hedges      []Hedge
+	status      Status
+	ticker      *time.Ticker
+}
+
+// NewHedging returns hedging on exchange, which must report fills of its
+// orders.
+func NewHedging(config common.HedgingConfig, exchange common.Exchange, core Core, storage Storage, statStorage StatStorage, control ControlStorage, reserve ethereum.Address) (*Hedging, error) {
+	hedgingExchange, ok := exchange.(Exchange)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Exchange %s doesn't report order fills", exchange.ID()))
+	}
+	window := config.Window * 1000
+	if window == 0 {
+		window = DEFAULT_WINDOW
+	}
+	if window > MAX_WINDOW {
+		window = MAX_WINDOW
+	}
+	return &Hedging{
+		config:      config,
+		window:      window,
+		exchange:    hedgingExchange,
+		core:        core,
+		storage:     storage,
+		statStorage: statStorage,
+		control:     control,
+		reserve:     reserve,
+		started:     common.GetTimepoint(),
+		hedges:      []Hedge{},
+	}, nil
+}
+
+func getSupportedToken(address ethereum.Address) (common.Token, bool) {
+	for _, token := range common.GetSupportedTokens() {
+		if strings.ToLower(token.Address) == strings.ToLower(address.Hex()) {
+			return token, true
+		}
+	}
+	return common.Token{}, false
+}
+
+// exposures returns exposure of each token in trades of the reserve from
+// fromTime to timepoint and the hedge fills netted against them. Fills not
+// netted yet are netted against the trades they offset first.
+func (self *Hedging) exposures(fromTime, timepoint uint64) (map[string]TokenExposure, error) {
+	result := map[string]TokenExposure{}
+	for tokenID, config := range self.config.Tokens {
+		result[tokenID] = TokenExposure{Enabled: config.Enabled}
+	}
+	logs, err := self.statStorage.GetTradeLogs(fromTime, timepoint)
+	if err != nil {
+		return result, err
+	}
+	trades := map[string][]*tradeExposure{}
+	tradesByKey := map[string]*tradeExposure{}
+	add := func(token common.Token, l common.TradeLog, amount float64) {
+		if token.IsETH() {
+			return
+		}
+		exposure := result[token.ID]
+		exposure.Traded += amount
+		result[token.ID] = exposure
+		key := token.ID + "_" + tradeKey(l)
+		trade, found := tradesByKey[key]
+		if !found {
+			trade = &tradeExposure{key: tradeKey(l), timestamp: l.Timestamp}
+			tradesByKey[key] = trade
+			trades[token.ID] = append(trades[token.ID], trade)
+		}
+		trade.remaining += amount
+	}
+	for _, l := range logs {
+		if l.ReserveAddress != self.reserve || l.SrcAmount == nil || l.DestAmount == nil {
+			continue
+		}
+		src, srcFound := getSupportedToken(l.SrcAddress)
+		dest, destFound := getSupportedToken(l.DestAddress)
+		if !srcFound || !destFound {
+			continue
+		}
+		// the reserve receives source token and pays destination token
+		add(src, l, common.BigToFloat(l.SrcAmount, src.Decimal))
+		add(dest, l, -common.BigToFloat(l.DestAmount, dest.Decimal))
+	}
+	for _, tokenTrades := range trades {
+		sort.SliceStable(tokenTrades, func(i, j int) bool { return tokenTrades[i].timestamp < tokenTrades[j].timestamp })
+	}
+	hedged := func(tokenID string, offset HedgeOffset) {
+		if offset.Timestamp < fromTime {
+			return
+		}
+		exposure := result[tokenID]
+		exposure.Hedged += offset.Amount
+		result[tokenID] = exposure
+	}
+	for _, hedge := range self.hedges {
+		for _, offset := range hedge.Offsets {
+			hedged(hedge.Token, offset)
+			if trade, found := tradesByKey[hedge.Token+"_"+offset.Trade]; found && offset.Trade != "" {
+				trade.remaining += offset.Amount
+			}
+		}
+	}
+	dayStart := timepoint - timepoint%DAY
+	for i := range self.hedges {
+		hedge := &self.hedges[i]
+		if amount := hedge.Filled - hedge.netted(); amount > 0 {
+			if hedge.Side == "sell" {
+				amount = -amount
+			}
+			for _, offset := range offsetTrades(trades[hedge.Token], amount, hedge.Timestamp) {
+				hedge.Offsets = append(hedge.Offsets, offset)
+				hedged(hedge.Token, offset)
+			}
+		}
+		if hedge.Timestamp >= dayStart {
+			// open orders count at their amount as they may still fill
+			exposure := result[hedge.Token]
+			if hedge.Finished {
+				exposure.HedgedToday += hedge.Filled
+			} else {
+				exposure.HedgedToday += hedge.Amount
+			}
+			result[hedge.Token] = exposure
+		}
+		if !hedge.Finished {
+			// the unfilled rest of an open order may still offset trades
+			pending := hedge.Amount - hedge.Filled
+			if hedge.Side == "sell" {
+				pending = -pending
+			}
+			exposure := result[hedge.Token]
+			exposure.Pending += pending
+			result[hedge.Token] = exposure
+		}
+	}
+	for tokenID, exposure := range result {
+		exposure.Exposure = exposure.Traded + exposure.Hedged + exposure.Pending
+		result[tokenID] = exposure
+	}
+	return result, nil
+}
+
+// refreshFills updates fills of open hedges from the exchange, a hedge is
+// finished when its order is done or fully filled.
+func (self *Hedging) refreshFills(timepoint uint64) {
+	for i := range self.hedges {
+		hedge := &self.hedges[i]
+		if hedge.Finished {
+			continue
+		}
+		done, remaining, _, err := self.exchange.OrderFill(hedge.TradeID, timepoint)
+		if err != nil {
+			log.Printf("Hedging: couldn't get fill of %s: %s", hedge.TradeID, err)
+			continue
+		}
+		status, err := self.exchange.OrderStatus(hedge.TradeID, timepoint)
+		if err != nil {
+			log.Printf("Hedging: couldn't get status of %s: %s", hedge.TradeID, err)
+			continue
+		}
+		hedge.Filled = done
+		hedge.Finished = remaining == 0 || status == "done"
+	}
+}
+
+// rate returns the rate to hedge token at, the best rate of the book
+// crossed by slippage.
+func (self *Hedging) rate(token common.Token, side string, timepoint uint64) (float64, error) {
+	version, err := self.storage.CurrentPriceVersion(timepoint)
+	if err != nil {
+		return 0, err
+	}
+	price, err := self.storage.GetOnePrice(common.NewTokenPairID(token.ID, "ETH"), version)
+	if err != nil {
+		return 0, err
+	}
+	book, found := price[self.exchange.ID()]
+	if !found || !book.Valid {
+		return 0, errors.New(fmt.Sprintf("No valid %s-ETH book on %s", token.ID, self.exchange.ID()))
+	}
+	if side == "sell" {
+		if len(book.Bids) == 0 {
+			return 0, errors.New(fmt.Sprintf("No bid of %s-ETH on %s", token.ID, self.exchange.ID()))
+		}
+		return book.Bids[0].Rate * (1 - self.config.Slippage), nil
+	}
+	if len(book.Asks) == 0 {
+		return 0, errors.New(fmt.Sprintf("No ask of %s-ETH on %s", token.ID, self.exchange.ID()))
+	}
+	return book.Asks[0].Rate * (1 + self.config.Slippage), nil
+}
+
+// hedgeToken places a trade offsetting exposure of token within its limits
+func (self *Hedging) hedgeToken(token common.Token, config common.HedgingTokenConfig, exposure TokenExposure, timepoint uint64) {
+	amount := math.Abs(exposure.Exposure)
+	if config.Threshold <= 0 || amount < config.Threshold {
+		return
+	}
+	if config.MaxTrade > 0 && amount > config.MaxTrade {
+		amount = config.MaxTrade
+	}
+	if config.DailyLimit > 0 && amount > config.DailyLimit-exposure.HedgedToday {
+		amount = config.DailyLimit - exposure.HedgedToday
+	}
+	if amount <= 0 {
+		log.Printf("Hedging: daily limit of %s is reached, exposure %f is not hedged", token.ID, exposure.Exposure)
+		return
+	}
+	hedge := Hedge{
+		Token:     token.ID,
+		Side:      "buy",
+		Amount:    amount,
+		Exposure:  exposure.Exposure,
+		Timestamp: timepoint,
+	}
+	// the reserve received token, sell it back
+	if exposure.Exposure > 0 {
+		hedge.Side = "sell"
+	}
+	var err error
+	hedge.Rate, err = self.rate(token, hedge.Side, timepoint)
+	if err == nil {
+		hedge.TradeID, hedge.Filled, _, hedge.Finished, err = self.core.Trade(
+			self.exchange, hedge.Side, token, common.MustGetToken("ETH"),
+			hedge.Rate, amount, common.OrderParams{}, timepoint)
+	}
+	if err != nil {
+		hedge.Error = err.Error()
+		hedge.Filled = 0
+		hedge.Finished = true
+	}
+	log.Printf("Hedging: %s %f %s at %f on %s for exposure %f: %s", hedge.Side, amount, token.ID, hedge.Rate, self.exchange.ID(), exposure.Exposure, hedge.Error)
+	self.hedges = append(self.hedges, hedge)
+}
+
+// Hedge nets exposure of tokens at timepoint and, if rebalancing is
+// enabled, places trades hedging enabled tokens over their threshold. Only
+// trades after hedging started are netted so a restart doesn't hedge them
+// again. Hedges are kept while they offset trades in the window or count
+// to the daily limit or are open.
+func (self *Hedging) Hedge(timepoint uint64) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	fromTime := timepoint - self.window
+	if timepoint < self.window || fromTime < self.started {
+		fromTime = self.started
+	}
+	self.refreshFills(timepoint)
+	exposures, err := self.exposures(fromTime, timepoint)
+	if err != nil {
+		return err
+	}
+	control, err := self.control.GetRebalanceControl()
+	if err != nil {
+		return err
+	}
+	if control.Status {
+		for tokenID, config := range self.config.Tokens {
+			token, err := common.GetToken(tokenID)
+			if err != nil || !config.Enabled || token.IsETH() {
+				continue
+			}
+			self.hedgeToken(token, config, exposures[tokenID], timepoint)
+		}
+		if exposures, err = self.exposures(fromTime, timepoint); err != nil {
+			return err
+		}
+	}
+	dayStart := timepoint - timepoint%DAY
+	hedges := []Hedge{}
+	for _, hedge := range self.hedges {
+		if !hedge.Finished || hedge.Timestamp >= fromTime || hedge.Timestamp >= dayStart || hedge.inWindow(fromTime) {
+			hedges = append(hedges, hedge)
+		}
+	}
+	self.hedges = hedges
+	if len(hedges) > MAX_HEDGES {
+		hedges = hedges[len(hedges)-MAX_HEDGES:]
+	}
+	self.status = Status{
+		Exchange:         self.exchange.ID(),
+		Window:           self.window,
+		RebalanceEnabled: control.Status,
+		Timestamp:        timepoint,
+		Exposures:        exposures,
+		Hedges:           append([]Hedge{}, hedges...),
+	}
+	return nil
+}
+
+// GetStatus returns exposures and latest hedges of the last check.
+func (self *Hedging) GetStatus() Status {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.status
+}
+
+func (self *Hedging) Run() error {
+	self.ticker = time.NewTicker(HEDGE_INTERVAL)
+	go func() {
+		for t := range self.ticker.C {
+			if err := self.Hedge(common.TimeToTimepoint(t)); err != nil {
+				log.Printf("Hedging: %s", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (self *Hedging) Stop() error {
+	if self.ticker != nil {
+		self.ticker.Stop()
+	}
+	return nil
+}
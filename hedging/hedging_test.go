@@ -0,0 +1,261 @@
+package hedging
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+type testStorage struct {
+	logs []common.TradeLog
+}
+
+func (self *testStorage) CurrentPriceVersion(timepoint uint64) (common.Version, error) {
+	return 1, nil
+}
+
+func (self *testStorage) GetOnePrice(pair common.TokenPairID, version common.Version) (common.OnePrice, error) {
+	return common.OnePrice{
+		"binance": {
+			Valid: true,
+			Bids:  []common.PriceEntry{{Quantity: 1000, Rate: 0.002}},
+			Asks:  []common.PriceEntry{{Quantity: 1000, Rate: 0.003}},
+		},
+	}, nil
+}
+
+func (self *testStorage) GetTradeLogs(fromTime uint64, toTime uint64) ([]common.TradeLog, error) {
+	result := []common.TradeLog{}
+	for _, l := range self.logs {
+		if l.Timestamp >= fromTime && l.Timestamp <= toTime {
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
+
+type testControl struct {
+	enabled bool
+}
+
+func (self testControl) GetRebalanceControl() (metric.RebalanceControl, error) {
+	return metric.RebalanceControl{Status: self.enabled}, nil
+}
+
+type testTrade struct {
+	side   string
+	amount float64
+	rate   float64
+}
+
+type testOrder struct {
+	done      float64
+	remaining float64
+	open      bool
+}
+
+// testCore fills fill of the amount of each trade and cancels the rest,
+// or leaves the rest open if open is set
+type testCore struct {
+	fill   float64
+	open   bool
+	trades []testTrade
+	orders map[common.ActivityID]*testOrder
+}
+
+func (self *testCore) Trade(exchange common.Exchange, tradeType string, base common.Token, quote common.Token, rate float64, amount float64, params common.OrderParams, timestamp uint64) (common.ActivityID, float64, float64, bool, error) {
+	self.trades = append(self.trades, testTrade{tradeType, amount, rate})
+	id := common.NewActivityID(timestamp, "trade")
+	done := amount * self.fill
+	if self.orders == nil {
+		self.orders = map[common.ActivityID]*testOrder{}
+	}
+	self.orders[id] = &testOrder{done: done, remaining: amount - done, open: self.open}
+	return id, done, 0, !self.open, nil
+}
+
+// testExchange reports orders placed by core
+type testExchange struct {
+	common.TestExchange
+	core *testCore
+}
+
+func (self testExchange) OrderStatus(id common.ActivityID, timepoint uint64) (string, error) {
+	if self.core.orders[id].open {
+		return "", nil
+	}
+	return "done", nil
+}
+
+func (self testExchange) OrderFill(id common.ActivityID, timepoint uint64) (float64, float64, float64, error) {
+	order := self.core.orders[id]
+	return order.done, order.remaining, 0, nil
+}
+
+func ether(amount int64) *big.Int {
+	return big.NewInt(0).Mul(big.NewInt(amount), big.NewInt(1000000000000000000))
+}
+
+func TestHedgeExposure(t *testing.T) {
+	eth := common.Token{ID: "ETH", Address: "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", Decimal: 18}
+	knc := common.Token{ID: "KNC", Address: "0x1111111111111111111111111111111111111111", Decimal: 18}
+	omg := common.Token{ID: "OMG", Address: "0x3333333333333333333333333333333333333333", Decimal: 18}
+	common.SupportedTokens = map[string]common.Token{"ETH": eth, "KNC": knc, "OMG": omg}
+	reserve := ethereum.HexToAddress("0x2222222222222222222222222222222222222222")
+	storage := &testStorage{logs: []common.TradeLog{
+		// users sold 700 KNC and 50 OMG to the reserve
+		{ReserveAddress: reserve, SrcAddress: ethereum.HexToAddress(knc.Address), DestAddress: ethereum.HexToAddress(eth.Address), SrcAmount: ether(700), DestAmount: ether(2)},
+		{ReserveAddress: reserve, SrcAddress: ethereum.HexToAddress(omg.Address), DestAddress: ethereum.HexToAddress(eth.Address), SrcAmount: ether(50), DestAmount: ether(1)},
+		// trade of another reserve is ignored
+		{ReserveAddress: ethereum.Address{}, SrcAddress: ethereum.HexToAddress(eth.Address), DestAddress: ethereum.HexToAddress(knc.Address), SrcAmount: ether(1), DestAmount: ether(500)},
+	}}
+	config := common.HedgingConfig{
+		Exchange: "binance",
+		Slippage: 0.01,
+		Tokens: map[string]common.HedgingTokenConfig{
+			"KNC": {Enabled: true, Threshold: 100, MaxTrade: 300, DailyLimit: 500},
+			"OMG": {Enabled: false, Threshold: 10},
+		},
+	}
+	core := &testCore{fill: 1}
+	control := &testControl{enabled: false}
+	hedging, err := NewHedging(config, testExchange{core: core}, core, storage, storage, control, reserve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timepoint := hedging.started + 1000
+	for i := range storage.logs {
+		storage.logs[i].Timestamp = timepoint
+	}
+
+	// nothing is traded while rebalancing is held
+	if err := hedging.Hedge(timepoint); err != nil {
+		t.Fatal(err)
+	}
+	if len(core.trades) != 0 || hedging.GetStatus().Exposures["KNC"].Exposure != 700 {
+		t.Fatalf("Unexpected hedging while rebalancing is held: %+v %+v", core.trades, hedging.GetStatus())
+	}
+
+	control.enabled = true
+	for i := uint64(1); i <= 3; i++ {
+		if err := hedging.Hedge(timepoint + i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 300 then 200 more KNC sold until the daily limit of 500, OMG is not
+	// enabled
+	if len(core.trades) != 2 || core.trades[0] != (testTrade{"sell", 300, 0.002 * 0.99}) || core.trades[1].amount != 200 {
+		t.Fatalf("Unexpected hedges %+v", core.trades)
+	}
+	status := hedging.GetStatus()
+	if status.Exposures["KNC"].Exposure != 200 || status.Exposures["KNC"].HedgedToday != 500 || status.Exposures["OMG"].Exposure != 50 || len(status.Hedges) != 2 {
+		t.Fatalf("Unexpected status %+v", status)
+	}
+}
+
+func TestHedgeWindow(t *testing.T) {
+	eth := common.Token{ID: "ETH", Address: "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", Decimal: 18}
+	knc := common.Token{ID: "KNC", Address: "0x1111111111111111111111111111111111111111", Decimal: 18}
+	common.SupportedTokens = map[string]common.Token{"ETH": eth, "KNC": knc}
+	reserve := ethereum.HexToAddress("0x2222222222222222222222222222222222222222")
+	config := common.HedgingConfig{
+		Exchange: "binance",
+		Window:   60,
+		Tokens: map[string]common.HedgingTokenConfig{
+			"KNC": {Enabled: true, Threshold: 100},
+		},
+	}
+	storage := &testStorage{}
+	core := &testCore{fill: 0.5}
+	hedging, err := NewHedging(config, testExchange{core: core}, core, storage, storage, &testControl{enabled: true}, reserve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timepoint := hedging.started + 1000
+	// users sold 800 KNC to the reserve
+	storage.logs = []common.TradeLog{
+		{Timestamp: timepoint, TransactionHash: ethereum.HexToHash("0x01"), ReserveAddress: reserve, SrcAddress: ethereum.HexToAddress(knc.Address), DestAddress: ethereum.HexToAddress(eth.Address), SrcAmount: ether(800), DestAmount: ether(2)},
+	}
+
+	// only half of each hedge is filled, the rest is hedged again, hedges
+	// are placed half a window after the trade
+	for i := uint64(1); i <= 2; i++ {
+		if err := hedging.Hedge(timepoint + hedging.window/2 + i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(core.trades) != 2 || core.trades[0].amount != 800 || core.trades[1].amount != 400 {
+		t.Fatalf("Unexpected hedges %+v", core.trades)
+	}
+	if exposure := hedging.GetStatus().Exposures["KNC"]; exposure.Exposure != 200 || exposure.Hedged != -600 {
+		t.Fatalf("Unexpected exposure %+v", exposure)
+	}
+
+	// the trade and its hedges leave the window together
+	timepoint += hedging.window + 10
+	if err := hedging.Hedge(timepoint); err != nil {
+		t.Fatal(err)
+	}
+	status := hedging.GetStatus()
+	if len(core.trades) != 2 || status.Exposures["KNC"].Exposure != 0 || status.Exposures["KNC"].Hedged != 0 {
+		t.Fatalf("Unexpected hedging after trade left the window: %+v %+v", core.trades, status)
+	}
+}
+
+func TestHedgeOpenOrder(t *testing.T) {
+	eth := common.Token{ID: "ETH", Address: "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", Decimal: 18}
+	knc := common.Token{ID: "KNC", Address: "0x1111111111111111111111111111111111111111", Decimal: 18}
+	common.SupportedTokens = map[string]common.Token{"ETH": eth, "KNC": knc}
+	reserve := ethereum.HexToAddress("0x2222222222222222222222222222222222222222")
+	config := common.HedgingConfig{
+		Exchange: "binance",
+		Tokens: map[string]common.HedgingTokenConfig{
+			"KNC": {Enabled: true, Threshold: 100},
+		},
+	}
+	storage := &testStorage{}
+	core := &testCore{fill: 0.25, open: true}
+	hedging, err := NewHedging(config, testExchange{core: core}, core, storage, storage, &testControl{enabled: true}, reserve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timepoint := hedging.started + 1000
+	// users sold 800 KNC to the reserve
+	storage.logs = []common.TradeLog{
+		{Timestamp: timepoint, TransactionHash: ethereum.HexToHash("0x01"), ReserveAddress: reserve, SrcAddress: ethereum.HexToAddress(knc.Address), DestAddress: ethereum.HexToAddress(eth.Address), SrcAmount: ether(800), DestAmount: ether(2)},
+	}
+
+	// a quarter of the hedge is filled and the rest is open, it is not
+	// hedged again while the order is open
+	for i := uint64(1); i <= 2; i++ {
+		if err := hedging.Hedge(timepoint + i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(core.trades) != 1 || core.trades[0].amount != 800 {
+		t.Fatalf("Unexpected hedges %+v", core.trades)
+	}
+	if exposure := hedging.GetStatus().Exposures["KNC"]; exposure.Exposure != 0 || exposure.Hedged != -200 || exposure.Pending != -600 {
+		t.Fatalf("Unexpected exposure %+v", exposure)
+	}
+
+	// the rest is hedged again once the order is cancelled
+	for _, order := range core.orders {
+		order.open = false
+	}
+	if err := hedging.Hedge(timepoint + 3); err != nil {
+		t.Fatal(err)
+	}
+	if len(core.trades) != 2 || core.trades[1].amount != 600 {
+		t.Fatalf("Unexpected hedges after cancel %+v", core.trades)
+	}
+}
+
+func TestHedgeUnsupportedExchange(t *testing.T) {
+	if _, err := NewHedging(common.HedgingConfig{}, common.TestExchange{}, &testCore{}, &testStorage{}, &testStorage{}, &testControl{}, ethereum.Address{}); err == nil {
+		t.Fatal("Expected error for exchange not reporting order fills")
+	}
+}
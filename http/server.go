@@ -1,19 +1,25 @@
 package http
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"net/http"
 	"net/url"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/KyberNetwork/reserve-data"
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/execution"
 	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/KyberNetwork/reserve-data/router"
 	ethereum "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	raven "github.com/getsentry/raven-go"
@@ -26,6 +32,10 @@ type HTTPServer struct {
 	app         reserve.ReserveData
 	core        reserve.ReserveCore
 	stat        reserve.ReserveStats
+	accounting  reserve.ReserveAccounting
+	execution   reserve.ReserveExecution
+	router      reserve.ReserveRouter
+	hedging     reserve.ReserveHedging
 	metric      metric.MetricStorage
 	host        string
 	authEnabled bool
@@ -231,6 +241,84 @@ func (self *HTTPServer) Price(c *gin.Context) {
 	}
 }
 
+// ConsolidatedPrice returns the book of a pair merged across exchanges at
+// the price version of "version" or "timestamp" query. With fee=true rates
+// are adjusted by taker fees of exchanges, with qty VWAPs to buy and sell
+// qty base tokens are returned.
+func (self *HTTPServer) ConsolidatedPrice(c *gin.Context) {
+	pair, err := common.NewTokenPair(c.Param("base"), c.Param("quote"))
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": "Token pair is not supported"},
+		)
+		return
+	}
+	version, _ := strconv.ParseUint(c.Query("version"), 10, 64)
+	var quantity float64
+	if qtyParam := c.Query("qty"); qtyParam != "" {
+		quantity, err = strconv.ParseFloat(qtyParam, 64)
+		if err != nil || quantity < 0 {
+			c.JSON(
+				http.StatusOK,
+				gin.H{"success": false, "reason": fmt.Sprintf("Invalid quantity %s", qtyParam)},
+			)
+			return
+		}
+	}
+	var fees map[common.ExchangeID]float64
+	if c.Query("fee") == "true" {
+		fees = map[common.ExchangeID]float64{}
+		for id, exchange := range common.SupportedExchanges {
+			fees[id] = exchange.GetFee().Trading["taker"]
+		}
+	}
+	data, err := self.app.GetConsolidatedPrice(pair.PairID(), common.Version(version), getTimePoint(c, true), fees, quantity)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{"success": true, "data": data},
+	)
+}
+
+// Candles returns OHLC candles of mid, best bid, best ask and spread of a
+// pair on an exchange, default to the book consolidated across exchanges.
+func (self *HTTPServer) Candles(c *gin.Context) {
+	pair, err := common.NewTokenPair(c.Param("base"), c.Param("quote"))
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": "Token pair is not supported"},
+		)
+		return
+	}
+	exchangeID := common.ExchangeID(c.DefaultQuery("exchange", string(common.CONSOLIDATED_EXCHANGE)))
+	resolution := c.DefaultQuery("resolution", "1m")
+	fromTime, _ := strconv.ParseUint(c.Query("fromTime"), 10, 64)
+	toTime, _ := strconv.ParseUint(c.Query("toTime"), 10, 64)
+	if toTime == 0 {
+		toTime = common.GetTimepoint()
+	}
+	data, err := self.app.GetCandles(pair.PairID(), exchangeID, resolution, fromTime, toTime)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{"success": true, "data": data},
+	)
+}
+
 func (self *HTTPServer) AuthDataVersion(c *gin.Context) {
 	log.Printf("Getting current auth data snapshot version")
 	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
@@ -349,31 +437,21 @@ func (self *HTTPServer) SetRate(c *gin.Context) {
 			tokens = append(tokens, token)
 		}
 	}
-	bigBuys := []*big.Int{}
-	for _, rate := range strings.Split(buys, "-") {
-		r, err := hexutil.DecodeBig(rate)
-		if err != nil {
-			c.JSON(
-				http.StatusOK,
-				gin.H{"success": false, "reason": err.Error()},
-			)
-			return
-		} else {
-			bigBuys = append(bigBuys, r)
-		}
+	bigBuys, err := hexRates(buys)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
 	}
-	bigSells := []*big.Int{}
-	for _, rate := range strings.Split(sells, "-") {
-		r, err := hexutil.DecodeBig(rate)
-		if err != nil {
-			c.JSON(
-				http.StatusOK,
-				gin.H{"success": false, "reason": err.Error()},
-			)
-			return
-		} else {
-			bigSells = append(bigSells, r)
-		}
+	bigSells, err := hexRates(sells)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
 	}
 	intBlock, err := strconv.ParseInt(block, 10, 64)
 	if err != nil {
@@ -383,35 +461,96 @@ func (self *HTTPServer) SetRate(c *gin.Context) {
 		)
 		return
 	}
-	bigAfpMid := []*big.Int{}
-	for _, rate := range strings.Split(afpMid, "-") {
+	bigAfpMid, err := hexRates(afpMid)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	id, err := self.core.SetRates(tokens, bigBuys, bigSells, big.NewInt(intBlock), bigAfpMid)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	} else {
+		c.JSON(
+			http.StatusOK,
+			gin.H{
+				"success": true,
+				"id":      id,
+			},
+		)
+	}
+}
+
+// hexRates parses rates in little endian hex separated by "-".
+func hexRates(rates string) ([]*big.Int, error) {
+	result := []*big.Int{}
+	for _, rate := range strings.Split(rates, "-") {
 		r, err := hexutil.DecodeBig(rate)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// GetCompactRatePlan returns whether rates in the same format as SetRate
+// would be set as compact rates or with new base rates, without setting
+// them.
+func (self *HTTPServer) GetCompactRatePlan(c *gin.Context) {
+	params, ok := self.Authenticated(c, []string{"tokens", "buys", "sells"}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	tokens := []common.Token{}
+	for _, tok := range strings.Split(params.Get("tokens"), "-") {
+		token, err := common.GetToken(tok)
 		if err != nil {
 			c.JSON(
 				http.StatusOK,
 				gin.H{"success": false, "reason": err.Error()},
 			)
 			return
-		} else {
-			bigAfpMid = append(bigAfpMid, r)
 		}
+		tokens = append(tokens, token)
 	}
-	id, err := self.core.SetRates(tokens, bigBuys, bigSells, big.NewInt(intBlock), bigAfpMid)
+	buys, err := hexRates(params.Get("buys"))
 	if err != nil {
 		c.JSON(
 			http.StatusOK,
 			gin.H{"success": false, "reason": err.Error()},
 		)
 		return
-	} else {
+	}
+	sells, err := hexRates(params.Get("sells"))
+	if err != nil {
 		c.JSON(
 			http.StatusOK,
-			gin.H{
-				"success": true,
-				"id":      id,
-			},
+			gin.H{"success": false, "reason": err.Error()},
 		)
+		return
+	}
+	plan, err := self.core.PlanCompactRates(tokens, buys, sells)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
 	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    plan,
+		},
+	)
 }
 
 func (self *HTTPServer) Trade(c *gin.Context) {
@@ -435,7 +574,7 @@ func (self *HTTPServer) Trade(c *gin.Context) {
 		)
 		return
 	}
-	base, err := common.GetToken(baseTokenParam)
+	base, err := common.GetPairToken(baseTokenParam)
 	if err != nil {
 		c.JSON(
 			http.StatusOK,
@@ -443,7 +582,7 @@ func (self *HTTPServer) Trade(c *gin.Context) {
 		)
 		return
 	}
-	quote, err := common.GetToken(quoteTokenParam)
+	quote, err := common.GetPairToken(quoteTokenParam)
 	if err != nil {
 		c.JSON(
 			http.StatusOK,
@@ -475,8 +614,19 @@ func (self *HTTPServer) Trade(c *gin.Context) {
 		)
 		return
 	}
+	params := common.OrderParams{
+		OrderType:     postForm.Get("order_type"),
+		ClientOrderID: postForm.Get("client_order_id"),
+	}
+	if err = params.Validate(rate); err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
 	id, done, remaining, finished, err := self.core.Trade(
-		exchange, typeParam, base, quote, rate, amount, getTimePoint(c, false))
+		exchange, typeParam, base, quote, rate, amount, params, getTimePoint(c, false))
 	if err != nil {
 		c.JSON(
 			http.StatusOK,
@@ -538,6 +688,79 @@ func (self *HTTPServer) CancelOrder(c *gin.Context) {
 	)
 }
 
+// CancelAllOrders cancels open orders of the latest auth data and pending
+// trade activities, optionally filtered by exchange, pair and side. With
+// kill_switch=true every filter is ignored, rebalancing is held and
+// running parent orders are cancelled before orders are cancelled.
+func (self *HTTPServer) CancelAllOrders(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{}, []Permission{RebalancePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	killSwitch := postForm.Get("kill_switch") == "true"
+	filter := common.OrderFilter{}
+	if killSwitch {
+		log.Printf("Kill switch: holding rebalance and cancelling all orders")
+		if err := self.metric.StoreRebalanceControl(false); err != nil {
+			c.JSON(
+				http.StatusOK,
+				gin.H{"success": false, "reason": err.Error()},
+			)
+			return
+		}
+		// parent orders would place new child orders until they stop
+		if self.execution != nil {
+			self.execution.Stop()
+		}
+	} else {
+		filter = common.OrderFilter{
+			Exchange: common.ExchangeID(postForm.Get("exchange")),
+			Base:     postForm.Get("base"),
+			Quote:    postForm.Get("quote"),
+			Side:     postForm.Get("side"),
+		}
+	}
+	authData, err := self.app.GetAuthData(common.GetTimepoint())
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	orders := common.GetOpenOrders(authData.Data.ExchangeOrders, authData.Data.PendingActivities, filter)
+	failed := 0
+	for i, order := range orders {
+		exchange, err := common.GetExchange(string(order.Exchange))
+		if err == nil {
+			log.Printf("Cancel order id: %s from %s\n", order.ID, order.Exchange)
+			err = self.core.CancelOrder(order.ID, exchange)
+		}
+		if err != nil {
+			orders[i].Error = err.Error()
+			failed++
+		}
+	}
+	if failed > 0 {
+		c.JSON(
+			http.StatusOK,
+			gin.H{
+				"success": false,
+				"reason":  fmt.Sprintf("Cancelling %d of %d orders failed", failed, len(orders)),
+				"data":    orders,
+			},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    orders,
+		},
+	)
+}
+
 func (self *HTTPServer) Withdraw(c *gin.Context) {
 	postForm, ok := self.Authenticated(c, []string{"token", "amount"}, []Permission{RebalancePermission})
 	if !ok {
@@ -740,6 +963,63 @@ func (self *HTTPServer) ImmediatePendingActivities(c *gin.Context) {
 	}
 }
 
+func (self *HTTPServer) ExpiredActivities(c *gin.Context) {
+	log.Printf("Getting all expired activity records \n")
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+
+	data, err := self.app.GetExpiredActivities()
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+	} else {
+		c.JSON(
+			http.StatusOK,
+			gin.H{
+				"success": true,
+				"data":    data,
+			},
+		)
+	}
+}
+
+func (self *HTTPServer) ResolveActivity(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"activity_id", "status", "reason"}, []Permission{RebalancePermission})
+	if !ok {
+		return
+	}
+	id, err := common.StringToActivityID(postForm.Get("activity_id"))
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	status := postForm.Get("status")
+	reason := postForm.Get("reason")
+	log.Printf("Resolving activity %s as %s, reason: %s", id, status, reason)
+	data, err := self.app.ResolveActivity(id, status, reason, common.GetTimepoint())
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
 func (self *HTTPServer) Metrics(c *gin.Context) {
 	response := metric.MetricResponse{
 		Timestamp: common.GetTimepoint(),
@@ -817,6 +1097,7 @@ func (self *HTTPServer) StoreMetrics(c *gin.Context) {
 	}
 	metricEntry := metric.MetricEntry{}
 	metricEntry.Timestamp = timestamp
+	metricEntry.Source = metric.ANALYTICS_SOURCE
 	metricEntry.Data = map[string]metric.TokenMetric{}
 	// data must be in form of <token>_afpmid_spread|<token>_afpmid_spread|...
 	for _, tokenData := range strings.Split(dataParam, "|") {
@@ -1153,14 +1434,23 @@ func (self *HTTPServer) GetAddress(c *gin.Context) {
 	return
 }
 
+// GetTradeHistory returns stored trades of the reserve accounts between
+// fromTime and toTime (miliseconds), optionally only of one exchange
+// and one pair (e.g. OMG-ETH).
 func (self *HTTPServer) GetTradeHistory(c *gin.Context) {
-	timepoint := common.GetTimepoint()
 	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
 	if !ok {
 		return
 	}
+	fromTime, _ := strconv.ParseUint(c.Query("fromTime"), 10, 64)
+	toTime, err := strconv.ParseUint(c.Query("toTime"), 10, 64)
+	if err != nil || toTime == 0 {
+		toTime = common.GetTimepoint()
+	}
+	exchangeID := common.ExchangeID(c.Query("exchange"))
+	pairID := common.TokenPairID(c.Query("pair"))
 
-	data, err := self.app.GetTradeHistory(timepoint)
+	data, err := self.app.GetTradeHistory(fromTime, toTime, exchangeID, pairID)
 	if err != nil {
 		c.JSON(
 			http.StatusOK,
@@ -1180,17 +1470,213 @@ func (self *HTTPServer) GetTradeHistory(c *gin.Context) {
 	)
 }
 
-func (self *HTTPServer) GetTimeServer(c *gin.Context) {
+// GetRateHealth returns the latest check of rate validity and token state
+// in the pricing contract.
+func (self *HTTPServer) GetRateHealth(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	data, err := self.app.GetRateHealth()
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
 	c.JSON(
 		http.StatusOK,
 		gin.H{
 			"success": true,
-			"data":    common.GetTimestamp(),
+			"data":    data,
 		},
 	)
 }
 
-func (self *HTTPServer) GetRebalanceStatus(c *gin.Context) {
+// GetReconciliationReports returns reports of trade reconciliation done
+// between fromTime and toTime, only the latest one before toTime when
+// fromTime is not given.
+func (self *HTTPServer) GetReconciliationReports(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	fromTime, _ := strconv.ParseUint(c.Query("fromTime"), 10, 64)
+	toTime, err := strconv.ParseUint(c.Query("toTime"), 10, 64)
+	if err != nil || toTime == 0 {
+		toTime = common.GetTimepoint()
+	}
+	data, err := self.app.GetReconciliationReports(fromTime, toTime)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	if c.Query("fromTime") == "" && len(data) > 0 {
+		data = data[len(data)-1:]
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// GetTransfer returns the timeline of a deposit or withdraw activity
+func (self *HTTPServer) GetTransfer(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	id, err := common.StringToActivityID(c.Param("id"))
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	data, err := self.app.GetTransfer(id)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// GetTransfers returns timelines of deposits and withdrawals requested
+// between fromTime and toTime (miliseconds, at most one day apart).
+func (self *HTTPServer) GetTransfers(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	fromTime, _ := strconv.ParseUint(c.Query("fromTime"), 10, 64)
+	toTime, _ := strconv.ParseUint(c.Query("toTime"), 10, 64)
+	if toTime == 0 {
+		toTime = common.GetTimepoint()
+	}
+	data, err := self.app.GetTransfers(fromTime, toTime)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// GetTransferLatencies returns average credit latency of each exchange by
+// action for transfers requested between fromTime and toTime, the last
+// day when fromTime is not given.
+func (self *HTTPServer) GetTransferLatencies(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	toTime, _ := strconv.ParseUint(c.Query("toTime"), 10, 64)
+	if toTime == 0 {
+		toTime = common.GetTimepoint()
+	}
+	// activities can only be read one day at a time
+	day := uint64(24 * time.Hour / time.Millisecond)
+	fromTime, err := strconv.ParseUint(c.Query("fromTime"), 10, 64)
+	if err != nil && toTime > day {
+		fromTime = toTime - day
+	}
+	data, err := self.app.GetTransferLatencies(fromTime, toTime)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// GetDepositVenues ranks exchanges to deposit an amount of a token to and
+// sell it there.
+func (self *HTTPServer) GetDepositVenues(c *gin.Context) {
+	params, ok := self.Authenticated(c, []string{"token", "amount"}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	token, err := common.GetToken(params.Get("token"))
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	amount, err := strconv.ParseFloat(params.Get("amount"), 64)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": fmt.Sprintf("Invalid amount %s", params.Get("amount"))},
+		)
+		return
+	}
+	exchanges := []common.Exchange{}
+	for _, exchange := range common.SupportedExchanges {
+		exchanges = append(exchanges, exchange)
+	}
+	data, err := self.app.GetDepositVenues(token, amount, exchanges, common.GetTimepoint())
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+func (self *HTTPServer) GetTimeServer(c *gin.Context) {
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    common.GetTimestamp(),
+		},
+	)
+}
+
+func (self *HTTPServer) GetRebalanceStatus(c *gin.Context) {
 	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
 	if !ok {
 		return
@@ -1579,85 +2065,676 @@ func (self *HTTPServer) RejectPWIEquation(c *gin.Context) {
 	)
 }
 
-func (self *HTTPServer) GetCapByAddress(c *gin.Context) {
-	addr := c.Param("addr")
-	address := ethereum.HexToAddress(addr)
-	if address.Big().Cmp(ethereum.Big0) == 0 {
-		c.JSON(
-			http.StatusOK,
-			gin.H{
-				"success": false,
-				"reason":  "address is not valid",
-			},
-		)
+// tradeSwitch parses a request to disable or enable trading of the
+// reserve, or of a token when token is given.
+func tradeSwitch(params url.Values) (metric.TradeSwitch, error) {
+	result := metric.TradeSwitch{Token: params.Get("token")}
+	enable, err := strconv.ParseBool(params.Get("enable"))
+	if err != nil {
+		return result, errors.New(fmt.Sprintf("Invalid enable %s", params.Get("enable")))
+	}
+	result.Enable = enable
+	if result.Token != "" {
+		if _, err = common.GetToken(result.Token); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// GetTradeStatus returns whether trading of the reserve and of each token
+// is enabled on chain, and the trade switch waiting for confirmation.
+func (self *HTTPServer) GetTradeStatus(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
 		return
 	}
-	data, err := self.stat.GetCapByAddress(address)
+	status, err := self.core.GetTradeStatus()
 	if err != nil {
 		c.JSON(
 			http.StatusOK,
-			gin.H{
-				"success": false,
-				"reason":  err.Error(),
-			},
+			gin.H{"success": false, "reason": err.Error()},
 		)
-	} else {
+		return
+	}
+	var pending *metric.TradeSwitch
+	if data, err := self.metric.GetPendingTradeSwitch(); err == nil {
+		pending = &data
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data": gin.H{
+				"status":  status,
+				"pending": pending,
+			},
+		},
+	)
+}
+
+// SetTradeSwitch stores a request to disable or enable trading, it is only
+// sent on chain when it is confirmed.
+func (self *HTTPServer) SetTradeSwitch(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"enable"}, []Permission{RebalancePermission, ConfigurePermission})
+	if !ok {
+		return
+	}
+	data, err := tradeSwitch(postForm)
+	if err == nil {
+		err = self.metric.StorePendingTradeSwitch(data)
+	}
+	if err != nil {
 		c.JSON(
 			http.StatusOK,
-			gin.H{
-				"success": true,
-				"data":    data,
-			},
+			gin.H{"success": false, "reason": err.Error()},
 		)
+		return
 	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+		},
+	)
 }
 
-func (self *HTTPServer) GetCapByUser(c *gin.Context) {
-	user := c.Param("user")
-	data, err := self.stat.GetCapByUser(user)
+// ConfirmTradeSwitch sends the pending trade switch on chain with the
+// alerter key, token and enable must match the pending one. It stays
+// pending when sending fails so it can be confirmed again.
+func (self *HTTPServer) ConfirmTradeSwitch(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"enable"}, []Permission{ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	data, err := tradeSwitch(postForm)
 	if err != nil {
 		c.JSON(
 			http.StatusOK,
-			gin.H{
-				"success": false,
-				"reason":  err.Error(),
-			},
+			gin.H{"success": false, "reason": err.Error()},
 		)
-	} else {
+		return
+	}
+	pending, err := self.metric.GetPendingTradeSwitch()
+	if err != nil {
 		c.JSON(
 			http.StatusOK,
-			gin.H{
-				"success": true,
-				"data":    data,
-			},
+			gin.H{"success": false, "reason": err.Error()},
 		)
+		return
 	}
-}
-
-func (self *HTTPServer) GetPendingAddresses(c *gin.Context) {
-	data, err := self.stat.GetPendingAddresses()
-	if err != nil {
+	if pending.Token != data.Token || pending.Enable != data.Enable {
 		c.JSON(
 			http.StatusOK,
-			gin.H{
-				"success": false,
-				"reason":  err.Error(),
-			},
+			gin.H{"success": false, "reason": "Confirm data does not match pending data"},
 		)
-	} else {
+		return
+	}
+	id, err := self.core.SetTradeEnabled(pending.Token, pending.Enable)
+	if err != nil {
 		c.JSON(
 			http.StatusOK,
-			gin.H{
-				"success": true,
-				"data":    data,
-			},
+			gin.H{"success": false, "reason": err.Error()},
 		)
+		return
 	}
+	if err = self.metric.RemovePendingTradeSwitch(); err != nil {
+		log.Printf("Removing confirmed trade switch failed: %s", err)
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"id":      id,
+		},
+	)
 }
 
-func (self *HTTPServer) UpdateUserAddresses(c *gin.Context) {
-	var err error
-	postForm, ok := self.Authenticated(c, []string{"user", "addresses"}, []Permission{ConfirmConfPermission})
+func (self *HTTPServer) RejectTradeSwitch(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	err := self.metric.RemovePendingTradeSwitch()
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+		},
+	)
+}
+
+// stepFunctions parses step functions of tokens in data and checks they
+// can be set in the pricing contract.
+func stepFunctions(data string) (map[string]common.TokenStepFunctions, error) {
+	result := map[string]common.TokenStepFunctions{}
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return result, err
+	}
+	if len(result) == 0 {
+		return result, errors.New("No step function is given")
+	}
+	for tokenID, functions := range result {
+		token, err := common.GetToken(tokenID)
+		if err != nil {
+			return result, err
+		}
+		if token.IsETH() {
+			return result, errors.New("ETH has no step functions")
+		}
+		if err = functions.Validate(); err != nil {
+			return result, errors.New(fmt.Sprintf("%s %s", tokenID, err))
+		}
+	}
+	return result, nil
+}
+
+// stepFunctionDiffs returns step functions in data which differ from the
+// ones in the pricing contract, by token.
+func (self *HTTPServer) stepFunctionDiffs(data map[string]common.TokenStepFunctions) ([]common.StepFunctionDiff, error) {
+	tokenIDs := []string{}
+	for tokenID := range data {
+		tokenIDs = append(tokenIDs, tokenID)
+	}
+	sort.Strings(tokenIDs)
+	result := []common.StepFunctionDiff{}
+	for _, tokenID := range tokenIDs {
+		token, err := common.GetToken(tokenID)
+		if err != nil {
+			return result, err
+		}
+		current, err := self.core.GetStepFunctions(token)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, common.DiffStepFunctions(tokenID, current, data[tokenID])...)
+	}
+	return result, nil
+}
+
+// GetStepFunctions returns step functions of token in the pricing contract,
+// or of all tokens if token is not given.
+func (self *HTTPServer) GetStepFunctions(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	tokens := []common.Token{}
+	if tokenID := c.Query("token"); tokenID != "" {
+		token, err := common.GetToken(tokenID)
+		if err != nil {
+			c.JSON(
+				http.StatusOK,
+				gin.H{"success": false, "reason": err.Error()},
+			)
+			return
+		}
+		tokens = append(tokens, token)
+	} else {
+		for _, token := range common.GetSupportedTokens() {
+			if !token.IsETH() {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	result := map[string]common.TokenStepFunctions{}
+	for _, token := range tokens {
+		functions, err := self.core.GetStepFunctions(token)
+		if err != nil {
+			c.JSON(
+				http.StatusOK,
+				gin.H{"success": false, "reason": err.Error()},
+			)
+			return
+		}
+		result[token.ID] = functions
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    result,
+		},
+	)
+}
+
+// GetPendingStepFunctions returns the proposed step functions and how they
+// differ from the ones in the pricing contract.
+func (self *HTTPServer) GetPendingStepFunctions(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	pending, err := self.metric.GetPendingStepFunctions()
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	diffs, err := self.stepFunctionDiffs(pending.Data)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data": gin.H{
+				"pending": pending,
+				"diffs":   diffs,
+			},
+		},
+	)
+}
+
+// SetStepFunctions stores proposed step functions of tokens, they are only
+// sent on chain when they are confirmed. It returns how they differ from
+// the ones in the pricing contract.
+func (self *HTTPServer) SetStepFunctions(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"data"}, []Permission{ConfigurePermission})
+	if !ok {
+		return
+	}
+	data, err := stepFunctions(postForm.Get("data"))
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	diffs, err := self.stepFunctionDiffs(data)
+	if err == nil && len(diffs) == 0 {
+		err = errors.New("Step functions are the same as in the pricing contract")
+	}
+	if err == nil {
+		err = self.metric.StorePendingStepFunctions(data)
+	}
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    diffs,
+		},
+	)
+}
+
+// ConfirmStepFunctions sets the pending step functions which differ from
+// the ones in the pricing contract, data must match the pending step
+// functions. Each change is sent in its own transaction and tracked as a
+// set_step_function activity, the pending step functions are removed once
+// they are sent.
+func (self *HTTPServer) ConfirmStepFunctions(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"data"}, []Permission{ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	data, err := stepFunctions(postForm.Get("data"))
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	pending, err := self.metric.GetPendingStepFunctions()
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	if !reflect.DeepEqual(data, pending.Data) {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": "Confirm data does not match pending data"},
+		)
+		return
+	}
+	diffs, err := self.stepFunctionDiffs(pending.Data)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	ids := []common.ActivityID{}
+	errs := []string{}
+	for _, diff := range diffs {
+		token, _ := common.GetToken(diff.Token)
+		id, err := self.core.SetStepFunction(token, diff.Type, diff.ProposedBuy, diff.ProposedSell)
+		ids = append(ids, id)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s %s: %s", diff.Token, diff.Type, err))
+		}
+	}
+	if err = self.metric.RemovePendingStepFunctions(); err != nil {
+		log.Printf("Removing confirmed step functions failed: %s", err)
+	}
+	if len(errs) > 0 {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": strings.Join(errs, ", "), "ids": ids},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"ids":     ids,
+		},
+	)
+}
+
+func (self *HTTPServer) RejectStepFunctions(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	err := self.metric.RemovePendingStepFunctions()
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+		},
+	)
+}
+
+// tokenListing parses a token listing in data and checks it can be done.
+func tokenListing(data string) (common.TokenListing, error) {
+	result := common.TokenListing{}
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return result, err
+	}
+	if err := result.Validate(); err != nil {
+		return result, err
+	}
+	token := result.Token()
+	if _, err := common.GetPairToken(token.ID); err == nil {
+		return result, errors.New(fmt.Sprintf("%s is already supported", token.ID))
+	}
+	for exchangeID := range result.DepositAddresses {
+		if _, err := common.GetExchange(exchangeID); err != nil {
+			return result, err
+		}
+	}
+	for exchangeID, quotes := range result.Pairs {
+		exchange, err := common.GetExchange(exchangeID)
+		if err != nil {
+			return result, err
+		}
+		if _, ok := exchange.(common.TokenPairAdder); !ok {
+			return result, errors.New(fmt.Sprintf("Pairs can't be added to %s while it is running", exchangeID))
+		}
+		for _, quote := range quotes {
+			if _, err := common.GetPairToken(quote); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// GetTokenListing returns the last token listing, its status and the
+// list_token activities of its steps.
+func (self *HTTPServer) GetTokenListing(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	listing, err := self.metric.GetTokenListing()
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    listing,
+		},
+	)
+}
+
+// ListToken stores a token proposed to be listed, it is only listed when
+// it is confirmed.
+func (self *HTTPServer) ListToken(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"data"}, []Permission{ConfigurePermission})
+	if !ok {
+		return
+	}
+	data, err := tokenListing(postForm.Get("data"))
+	if err == nil {
+		err = self.metric.StorePendingTokenListing(data)
+	}
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+		},
+	)
+}
+
+// ConfirmListToken starts listing the pending token, data must match it.
+// The listing runs in the background, its progress is returned by
+// GetTokenListing.
+func (self *HTTPServer) ConfirmListToken(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"data"}, []Permission{ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	data, err := tokenListing(postForm.Get("data"))
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	listing, err := self.metric.GetTokenListing()
+	if err == nil && listing.Status != common.LISTING_PENDING {
+		err = errors.New(fmt.Sprintf("The token listing is %s, not pending", listing.Status))
+	}
+	if err == nil && !reflect.DeepEqual(data, listing.Data) {
+		err = errors.New("Confirm data does not match pending data")
+	}
+	if err == nil {
+		listing.Status = common.LISTING_IN_PROGRESS
+		err = self.metric.UpdateTokenListing(listing)
+	}
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	go func() {
+		ids, err := self.core.ListToken(listing.Data)
+		listing.Activities = ids
+		listing.Status = common.LISTING_LISTED
+		if err != nil {
+			listing.Status = common.LISTING_FAILED
+			listing.Error = err.Error()
+		}
+		if err = self.metric.UpdateTokenListing(listing); err != nil {
+			log.Printf("Storing token listing result failed: %s", err)
+		}
+	}()
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"id":      listing.ID,
+		},
+	)
+}
+
+// failInterruptedListing fails a token listing left in progress by a
+// previous process, its steps are not resumed. Steps done are recorded as
+// list_token activities.
+func (self *HTTPServer) failInterruptedListing() {
+	listing, err := self.metric.GetTokenListing()
+	if err != nil || listing.Status != common.LISTING_IN_PROGRESS {
+		return
+	}
+	listing.Status = common.LISTING_FAILED
+	listing.Error = "interrupted"
+	if err = self.metric.UpdateTokenListing(listing); err != nil {
+		log.Printf("Failing interrupted token listing %d failed: %s", listing.ID, err)
+		return
+	}
+	log.Printf("Token listing %d of %s is interrupted", listing.ID, listing.Data.ID)
+}
+
+// RejectListToken removes the pending token listing, listings in progress
+// or finished can't be rejected.
+func (self *HTTPServer) RejectListToken(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	listing, err := self.metric.GetTokenListing()
+	if err == nil && listing.Status != common.LISTING_PENDING {
+		err = errors.New(fmt.Sprintf("The token listing is %s, not pending", listing.Status))
+	}
+	if err == nil {
+		err = self.metric.RemoveTokenListing()
+	}
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+		},
+	)
+}
+
+func (self *HTTPServer) GetCapByAddress(c *gin.Context) {
+	addr := c.Param("addr")
+	address := ethereum.HexToAddress(addr)
+	if address.Big().Cmp(ethereum.Big0) == 0 {
+		c.JSON(
+			http.StatusOK,
+			gin.H{
+				"success": false,
+				"reason":  "address is not valid",
+			},
+		)
+		return
+	}
+	data, err := self.stat.GetCapByAddress(address)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{
+				"success": false,
+				"reason":  err.Error(),
+			},
+		)
+	} else {
+		c.JSON(
+			http.StatusOK,
+			gin.H{
+				"success": true,
+				"data":    data,
+			},
+		)
+	}
+}
+
+func (self *HTTPServer) GetCapByUser(c *gin.Context) {
+	user := c.Param("user")
+	data, err := self.stat.GetCapByUser(user)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{
+				"success": false,
+				"reason":  err.Error(),
+			},
+		)
+	} else {
+		c.JSON(
+			http.StatusOK,
+			gin.H{
+				"success": true,
+				"data":    data,
+			},
+		)
+	}
+}
+
+func (self *HTTPServer) GetPendingAddresses(c *gin.Context) {
+	data, err := self.stat.GetPendingAddresses()
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{
+				"success": false,
+				"reason":  err.Error(),
+			},
+		)
+	} else {
+		c.JSON(
+			http.StatusOK,
+			gin.H{
+				"success": true,
+				"data":    data,
+			},
+		)
+	}
+}
+
+func (self *HTTPServer) UpdateUserAddresses(c *gin.Context) {
+	var err error
+	postForm, ok := self.Authenticated(c, []string{"user", "addresses"}, []Permission{ConfirmConfPermission})
 	if !ok {
 		return
 	}
@@ -1699,11 +2776,333 @@ func (self *HTTPServer) UpdateUserAddresses(c *gin.Context) {
 	}
 }
 
+// Backup streams a consistent snapshot of data or stat database
+// while the server keeps running.
+func (self *HTTPServer) Backup(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ConfigurePermission})
+	if !ok {
+		return
+	}
+	var backup func(w io.Writer) (int64, error)
+	db := c.Param("db")
+	switch db {
+	case "data":
+		if self.app != nil {
+			backup = self.app.Backup
+		}
+	case "stat":
+		if self.stat != nil {
+			backup = self.stat.Backup
+		}
+	}
+	if backup == nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": fmt.Sprintf("Database %s is not available", db)},
+		)
+		return
+	}
+	filename := fmt.Sprintf("%s-%d.db", db, common.GetTimepoint())
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Status(http.StatusOK)
+	n, err := backup(c.Writer)
+	if err != nil {
+		log.Printf("Backing up %s database failed after %d bytes: %s", db, n, err)
+	} else {
+		log.Printf("Backed up %s database: %d bytes", db, n)
+	}
+}
+
+// GetInventory returns inventory of every token at timestamp, default to
+// now.
+func (self *HTTPServer) GetInventory(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	timepoint, err := strconv.ParseUint(c.Query("timestamp"), 10, 64)
+	if err != nil || timepoint == 0 {
+		timepoint = common.GetTimepoint()
+	}
+	data, err := self.accounting.GetInventory(timepoint)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// GetAccountingSnapshots returns daily inventory and PnL snapshots of days
+// starting between fromTime and toTime.
+func (self *HTTPServer) GetAccountingSnapshots(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	fromTime, _ := strconv.ParseUint(c.Query("fromTime"), 10, 64)
+	toTime, err := strconv.ParseUint(c.Query("toTime"), 10, 64)
+	if err != nil || toTime == 0 {
+		toTime = common.GetTimepoint()
+	}
+	data, err := self.accounting.GetSnapshots(fromTime, toTime)
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// submitParentOrder starts a parent order of algo from the posted form,
+// fields are parsed as in Trade.
+func (self *HTTPServer) submitParentOrder(c *gin.Context, algo string, required []string) {
+	postForm, ok := self.Authenticated(c, append([]string{"base", "quote", "amount", "rate", "type"}, required...), []Permission{RebalancePermission})
+	if !ok {
+		return
+	}
+	order := execution.ParentOrder{
+		Algo:     algo,
+		Exchange: common.ExchangeID(c.Param("exchangeid")),
+		Type:     postForm.Get("type"),
+		Base:     postForm.Get("base"),
+		Quote:    postForm.Get("quote"),
+	}
+	var err error
+	if order.Amount, err = strconv.ParseFloat(postForm.Get("amount"), 64); err == nil {
+		order.Rate, err = strconv.ParseFloat(postForm.Get("rate"), 64)
+	}
+	if err == nil && algo == execution.TWAP {
+		if order.Slices, err = strconv.Atoi(postForm.Get("slices")); err == nil {
+			order.Interval, err = strconv.ParseUint(postForm.Get("interval"), 10, 64)
+		}
+	}
+	if err == nil && algo == execution.ICEBERG {
+		order.Visible, err = strconv.ParseFloat(postForm.Get("visible"), 64)
+	}
+	if err == nil {
+		order, err = self.execution.Submit(order)
+	}
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"id":      order.ID,
+		},
+	)
+}
+
+// SubmitTWAP trades amount in slices equal parts, one per interval
+// (miliseconds).
+func (self *HTTPServer) SubmitTWAP(c *gin.Context) {
+	self.submitParentOrder(c, execution.TWAP, []string{"slices", "interval"})
+}
+
+// SubmitIceberg trades amount showing at most visible on the order book.
+func (self *HTTPServer) SubmitIceberg(c *gin.Context) {
+	self.submitParentOrder(c, execution.ICEBERG, []string{"visible"})
+}
+
+// GetParentOrders returns parent orders which are still running.
+func (self *HTTPServer) GetParentOrders(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	data, err := self.execution.GetRunningOrders()
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// GetParentOrder returns progress and child orders of a parent order.
+func (self *HTTPServer) GetParentOrder(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	id, err := common.StringToActivityID(c.Param("id"))
+	var data execution.ParentOrder
+	if err == nil {
+		data, err = self.execution.GetParentOrder(id)
+	}
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// CancelParentOrder stops a running parent order and cancels its open
+// child order.
+func (self *HTTPServer) CancelParentOrder(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"id"}, []Permission{RebalancePermission})
+	if !ok {
+		return
+	}
+	id, err := common.StringToActivityID(postForm.Get("id"))
+	var data execution.ParentOrder
+	if err == nil {
+		data, err = self.execution.Cancel(id)
+	}
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// routeRequest parses a route request from params
+func routeRequest(params url.Values) (router.RouteRequest, error) {
+	request := router.RouteRequest{
+		Base:  params.Get("base"),
+		Quote: params.Get("quote"),
+		Side:  params.Get("side"),
+	}
+	var err error
+	if request.Quantity, err = strconv.ParseFloat(params.Get("qty"), 64); err != nil {
+		return request, errors.New(fmt.Sprintf("Invalid quantity %s", params.Get("qty")))
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if request.LimitRate, err = strconv.ParseFloat(limit, 64); err != nil {
+			return request, errors.New(fmt.Sprintf("Invalid limit rate %s", limit))
+		}
+	}
+	return request, nil
+}
+
+// GetRoute returns the allocation of a trade across exchanges at the
+// latest books and balances without trading.
+func (self *HTTPServer) GetRoute(c *gin.Context) {
+	params, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	request, err := routeRequest(params)
+	var data router.Route
+	if err == nil {
+		data, err = self.router.Route(request, common.GetTimepoint())
+	}
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// ExecuteRoute routes a trade across exchanges and places an order of
+// each allocation.
+func (self *HTTPServer) ExecuteRoute(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"base", "quote", "side", "qty"}, []Permission{RebalancePermission})
+	if !ok {
+		return
+	}
+	request, err := routeRequest(postForm)
+	var data router.Route
+	if err == nil {
+		data, err = self.router.Execute(request, common.GetTimepoint())
+	}
+	if err != nil {
+		c.JSON(
+			http.StatusOK,
+			gin.H{"success": false, "reason": err.Error()},
+		)
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    data,
+		},
+	)
+}
+
+// GetHedgingStatus returns exposure of tokens and the latest hedges.
+func (self *HTTPServer) GetHedgingStatus(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	c.JSON(
+		http.StatusOK,
+		gin.H{
+			"success": true,
+			"data":    self.hedging.GetStatus(),
+		},
+	)
+}
+
 func (self *HTTPServer) Run() {
 	if self.core != nil && self.app != nil {
+		self.failInterruptedListing()
 		self.r.GET("/prices-version", self.AllPricesVersion)
 		self.r.GET("/prices", self.AllPrices)
 		self.r.GET("/prices/:base/:quote", self.Price)
+		self.r.GET("/prices/:base/:quote/consolidated", self.ConsolidatedPrice)
+		self.r.GET("/candles/:base/:quote", self.Candles)
 		self.r.GET("/getrates", self.GetRate)
 		self.r.GET("/get-all-rates", self.GetRates)
 
@@ -1711,20 +3110,30 @@ func (self *HTTPServer) Run() {
 		self.r.GET("/authdata", self.AuthData)
 		self.r.GET("/activities", self.GetActivities)
 		self.r.GET("/immediate-pending-activities", self.ImmediatePendingActivities)
+		self.r.GET("/expired-activities", self.ExpiredActivities)
+		self.r.POST("/resolve-activity", self.ResolveActivity)
 		self.r.GET("/metrics", self.Metrics)
 		self.r.POST("/metrics", self.StoreMetrics)
 
 		self.r.POST("/cancelorder/:exchangeid", self.CancelOrder)
+		self.r.POST("/cancelallorders", self.CancelAllOrders)
 		self.r.POST("/deposit/:exchangeid", self.Deposit)
 		self.r.POST("/withdraw/:exchangeid", self.Withdraw)
 		self.r.POST("/trade/:exchangeid", self.Trade)
 		self.r.POST("/setrates", self.SetRate)
+		self.r.GET("/compact-rate-plan", self.GetCompactRatePlan)
 		self.r.GET("/exchangeinfo", self.GetExchangeInfo)
 		self.r.GET("/exchangeinfo/:exchangeid/:base/:quote", self.GetPairInfo)
 		self.r.GET("/exchangefees", self.GetFee)
 		self.r.GET("/exchangefees/:exchangeid", self.GetExchangeFee)
 		self.r.GET("/core/addresses", self.GetAddress)
 		self.r.GET("/tradehistory", self.GetTradeHistory)
+		self.r.GET("/reconciliation", self.GetReconciliationReports)
+		self.r.GET("/rate-health", self.GetRateHealth)
+		self.r.GET("/transfers", self.GetTransfers)
+		self.r.GET("/transfers/:id", self.GetTransfer)
+		self.r.GET("/transfer-latency", self.GetTransferLatencies)
+		self.r.GET("/deposit-venues", self.GetDepositVenues)
 
 		self.r.GET("/targetqty", self.GetTargetQty)
 		self.r.GET("/pendingtargetqty", self.GetPendingTargetQty)
@@ -1747,6 +3156,44 @@ func (self *HTTPServer) Run() {
 		self.r.POST("/set-pwis-equation", self.SetPWIEquation)
 		self.r.POST("/confirm-pwis-equation", self.ConfirmPWIEquation)
 		self.r.POST("/reject-pwis-equation", self.RejectPWIEquation)
+
+		self.r.GET("/trade-status", self.GetTradeStatus)
+		self.r.POST("/set-trade-switch", self.SetTradeSwitch)
+		self.r.POST("/confirm-trade-switch", self.ConfirmTradeSwitch)
+		self.r.POST("/reject-trade-switch", self.RejectTradeSwitch)
+
+		self.r.GET("/step-functions", self.GetStepFunctions)
+		self.r.GET("/pending-step-functions", self.GetPendingStepFunctions)
+		self.r.POST("/set-step-functions", self.SetStepFunctions)
+		self.r.POST("/confirm-step-functions", self.ConfirmStepFunctions)
+		self.r.POST("/reject-step-functions", self.RejectStepFunctions)
+
+		self.r.GET("/token-listing", self.GetTokenListing)
+		self.r.POST("/list-token", self.ListToken)
+		self.r.POST("/confirm-list-token", self.ConfirmListToken)
+		self.r.POST("/reject-list-token", self.RejectListToken)
+	}
+
+	if self.accounting != nil {
+		self.r.GET("/accounting/inventory", self.GetInventory)
+		self.r.GET("/accounting/snapshots", self.GetAccountingSnapshots)
+	}
+
+	if self.execution != nil {
+		self.r.POST("/twap/:exchangeid", self.SubmitTWAP)
+		self.r.POST("/iceberg/:exchangeid", self.SubmitIceberg)
+		self.r.GET("/parent-orders", self.GetParentOrders)
+		self.r.GET("/parent-orders/:id", self.GetParentOrder)
+		self.r.POST("/cancel-parent-order", self.CancelParentOrder)
+	}
+
+	if self.router != nil {
+		self.r.GET("/route", self.GetRoute)
+		self.r.POST("/execute-route", self.ExecuteRoute)
+	}
+
+	if self.hedging != nil {
+		self.r.GET("/hedging", self.GetHedgingStatus)
 	}
 
 	if self.stat != nil {
@@ -1762,6 +3209,8 @@ func (self *HTTPServer) Run() {
 		self.r.GET("/get-pending-addresses", self.GetPendingAddresses)
 	}
 
+	self.r.GET("/backup/:db", self.Backup)
+
 	self.r.Run(self.host)
 }
 
@@ -1769,6 +3218,10 @@ func NewHTTPServer(
 	app reserve.ReserveData,
 	core reserve.ReserveCore,
 	stat reserve.ReserveStats,
+	accounting reserve.ReserveAccounting,
+	execution reserve.ReserveExecution,
+	router reserve.ReserveRouter,
+	hedging reserve.ReserveHedging,
 	metric metric.MetricStorage,
 	host string,
 	enableAuth bool,
@@ -1796,6 +3249,6 @@ func NewHTTPServer(
 	r.Use(cors.New(corsConfig))
 
 	return &HTTPServer{
-		app, core, stat, metric, host, enableAuth, authEngine, r,
+		app, core, stat, accounting, execution, router, hedging, metric, host, enableAuth, authEngine, r,
 	}
 }
@@ -1,9 +1,14 @@
 package reserve
 
 import (
+	"io"
 	"math/big"
 
+	"github.com/KyberNetwork/reserve-data/accounting"
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/execution"
+	"github.com/KyberNetwork/reserve-data/hedging"
+	"github.com/KyberNetwork/reserve-data/router"
 	ethereum "github.com/ethereum/go-ethereum/common"
 )
 
@@ -22,6 +27,9 @@ type ReserveStats interface {
 
 	UpdateUserAddresses(userID string, addresses []ethereum.Address) error
 
+	// write a consistent snapshot of stat database to w
+	Backup(w io.Writer) (int64, error)
+
 	Run() error
 	Stop() error
 }
@@ -31,6 +39,8 @@ type ReserveData interface {
 	CurrentPriceVersion(timestamp uint64) (common.Version, error)
 	GetAllPrices(timestamp uint64) (common.AllPriceResponse, error)
 	GetOnePrice(id common.TokenPairID, timestamp uint64) (common.OnePriceResponse, error)
+	GetCandles(id common.TokenPairID, exchangeID common.ExchangeID, resolution string, fromTime, toTime uint64) ([]common.Candle, error)
+	GetConsolidatedPrice(id common.TokenPairID, version common.Version, timestamp uint64, fees map[common.ExchangeID]float64, quantity float64) (common.ConsolidatedPriceResponse, error)
 
 	CurrentAuthDataVersion(timestamp uint64) (common.Version, error)
 	GetAuthData(timestamp uint64) (common.AuthDataResponse, error)
@@ -41,8 +51,49 @@ type ReserveData interface {
 
 	GetRecords(fromTime, toTime uint64) ([]common.ActivityRecord, error)
 	GetPendingActivities() ([]common.ActivityRecord, error)
+	GetExpiredActivities() ([]common.ActivityRecord, error)
+	ResolveActivity(id common.ActivityID, status string, reason string, timestamp uint64) (common.ActivityRecord, error)
+	GetTransfer(id common.ActivityID) (common.TransferTimeline, error)
+	GetTransfers(fromTime, toTime uint64) ([]common.TransferTimeline, error)
+	GetTransferLatencies(fromTime, toTime uint64) (map[string]map[string]common.TransferLatency, error)
+	GetDepositVenues(token common.Token, amount float64, exchanges []common.Exchange, timestamp uint64) (common.DepositRecommendation, error)
+
+	GetTradeHistory(fromTime, toTime uint64, exchangeID common.ExchangeID, pairID common.TokenPairID) (common.AllTradeHistory, error)
+	GetReconciliationReports(fromTime, toTime uint64) ([]common.ReconciliationReport, error)
+	GetRateHealth() (common.RateHealth, error)
+
+	// write a consistent snapshot of data database to w
+	Backup(w io.Writer) (int64, error)
+
+	Run() error
+	Stop() error
+}
+
+type ReserveAccounting interface {
+	GetInventory(timepoint uint64) (accounting.Snapshot, error)
+	GetSnapshots(fromTime, toTime uint64) ([]accounting.Snapshot, error)
+
+	Run() error
+	Stop() error
+}
 
-	GetTradeHistory(timepoint uint64) (common.AllTradeHistory, error)
+type ReserveExecution interface {
+	Submit(order execution.ParentOrder) (execution.ParentOrder, error)
+	Cancel(id common.ActivityID) (execution.ParentOrder, error)
+	GetParentOrder(id common.ActivityID) (execution.ParentOrder, error)
+	GetRunningOrders() ([]execution.ParentOrder, error)
+
+	Run() error
+	Stop() error
+}
+
+type ReserveRouter interface {
+	Route(request router.RouteRequest, timepoint uint64) (router.Route, error)
+	Execute(request router.RouteRequest, timepoint uint64) (router.Route, error)
+}
+
+type ReserveHedging interface {
+	GetStatus() hedging.Status
 
 	Run() error
 	Stop() error
@@ -57,6 +108,7 @@ type ReserveCore interface {
 		quote common.Token,
 		rate float64,
 		amount float64,
+		params common.OrderParams,
 		timestamp uint64) (id common.ActivityID, done float64, remaining float64, finished bool, err error)
 
 	Deposit(
@@ -75,6 +127,21 @@ type ReserveCore interface {
 
 	// blockchain related action
 	SetRates(tokens []common.Token, buys, sells []*big.Int, block *big.Int, afpMid []*big.Int) (common.ActivityID, error)
+	// plan set rates without sending them
+	PlanCompactRates(tokens []common.Token, buys, sells []*big.Int) (common.CompactRatePlan, error)
+
+	// disable or enable trading of the reserve, or of a token when tokenID
+	// is not empty
+	SetTradeEnabled(tokenID string, enable bool) (common.ActivityID, error)
+	GetTradeStatus() (common.TradeStatus, error)
+
+	// set buy and sell quantity or imbalance step functions of a token
+	SetStepFunction(token common.Token, functionType string, buy, sell common.StepFunction) (common.ActivityID, error)
+	GetStepFunctions(token common.Token) (common.TokenStepFunctions, error)
+
+	// list a token in the pricing contract step by step, then register it
+	// without a restart
+	ListToken(listing common.TokenListing) ([]common.ActivityID, error)
 
 	GetAddresses() *common.Addresses
 }
@@ -0,0 +1,38 @@
+package metric
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// ComputeTokenMetric computes afp mid and spread of a token from the books
+// of its pair with ETH consolidated across exchanges. The buy and sell
+// rates are volume weighted to fill depth of the token, when depth is not
+// positive the top of the book is used. AfpMid is the average of the two
+// rates and Spread is their difference in percent of AfpMid.
+func ComputeTokenMetric(price common.OnePrice, depth float64) (TokenMetric, error) {
+	consolidated := common.ConsolidatePrice(price, nil)
+	if len(consolidated.Bids) == 0 || len(consolidated.Asks) == 0 {
+		return TokenMetric{}, errors.New("Order book is empty")
+	}
+	buy := consolidated.Asks[0].Rate
+	sell := consolidated.Bids[0].Rate
+	if depth > 0 {
+		vwap := consolidated.GetVWAP(depth).Consolidated
+		if !vwap.Buy.Complete || !vwap.Sell.Complete {
+			return TokenMetric{}, errors.New(fmt.Sprintf("Order book is not deep enough to fill %f", depth))
+		}
+		buy = vwap.Buy.Rate
+		sell = vwap.Sell.Rate
+	}
+	mid := (buy + sell) / 2
+	if mid == 0 {
+		return TokenMetric{}, errors.New("Afp mid is zero")
+	}
+	return TokenMetric{
+		AfpMid: mid,
+		Spread: (buy - sell) / mid * 100,
+	}, nil
+}
@@ -0,0 +1,34 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+func TestComputeTokenMetric(t *testing.T) {
+	price := common.OnePrice{
+		"binance": common.ExchangePrice{
+			Valid: true,
+			Bids:  []common.PriceEntry{{Quantity: 1, Rate: 98}, {Quantity: 1, Rate: 96}},
+			Asks:  []common.PriceEntry{{Quantity: 1, Rate: 102}, {Quantity: 1, Rate: 104}},
+		},
+		"huobi": common.ExchangePrice{
+			Valid: true,
+			Bids:  []common.PriceEntry{{Quantity: 2, Rate: 97}},
+			Asks:  []common.PriceEntry{{Quantity: 2, Rate: 103}},
+		},
+	}
+	top, err := ComputeTokenMetric(price, 0)
+	if err != nil || top.AfpMid != 100 || top.Spread != 4 {
+		t.Fatalf("Expected top of books metric {100 4}, got %+v, %v", top, err)
+	}
+	// buying 2 fills 102 and 103, selling 2 fills 98 and 97
+	weighted, err := ComputeTokenMetric(price, 2)
+	if err != nil || weighted.AfpMid != 100 || weighted.Spread != 5 {
+		t.Fatalf("Expected depth weighted metric {100 5}, got %+v, %v", weighted, err)
+	}
+	if _, err = ComputeTokenMetric(price, 10); err == nil {
+		t.Fatalf("Expected books not deep enough to be rejected")
+	}
+}
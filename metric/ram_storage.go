@@ -16,6 +16,7 @@ type RamMetricStorage struct {
 	data             []*MetricEntry
 	pendingTargetQty TokenTargetQty
 	tokenTargetQty   TokenTargetQty
+	tradeSwitch      *TradeSwitch
 }
 
 func NewRamMetricStorage() *RamMetricStorage {
@@ -57,6 +58,7 @@ func (self *RamMetricStorage) GetMetric(tokens []common.Token, fromTime, toTime
 				if found {
 					*metricList = append(*metricList, TokenMetricResponse{
 						Timestamp: data.Timestamp,
+						Source:    data.GetSource(),
 						AfpMid:    metric.AfpMid,
 						Spread:    metric.Spread,
 					})
@@ -161,3 +163,65 @@ func (self *RamMetricStorage) GetPWIEquation() (PWIEquation, error) {
 func (self *RamMetricStorage) RemovePendingPWIEquation() error {
 	return nil
 }
+
+func (self *RamMetricStorage) StorePendingTradeSwitch(data TradeSwitch) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.tradeSwitch != nil {
+		return errors.New("There is another pending trade switch, please confirm or reject it first")
+	}
+	data.ID = common.GetTimepoint()
+	self.tradeSwitch = &data
+	return nil
+}
+
+func (self *RamMetricStorage) GetPendingTradeSwitch() (TradeSwitch, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	if self.tradeSwitch == nil {
+		return TradeSwitch{}, errors.New("There is no pending trade switch")
+	}
+	return *self.tradeSwitch, nil
+}
+
+func (self *RamMetricStorage) RemovePendingTradeSwitch() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.tradeSwitch == nil {
+		return errors.New("There is no pending trade switch")
+	}
+	self.tradeSwitch = nil
+	return nil
+}
+
+func (self *RamMetricStorage) StorePendingStepFunctions(data map[string]common.TokenStepFunctions) error {
+	return nil
+}
+
+func (self *RamMetricStorage) GetPendingStepFunctions() (PendingStepFunctions, error) {
+	return PendingStepFunctions{}, nil
+}
+
+func (self *RamMetricStorage) RemovePendingStepFunctions() error {
+	return nil
+}
+
+func (self *RamMetricStorage) StorePendingTokenListing(data common.TokenListing) error {
+	return nil
+}
+
+func (self *RamMetricStorage) UpdateTokenListing(listing TokenListing) error {
+	return nil
+}
+
+func (self *RamMetricStorage) GetTokenListing() (TokenListing, error) {
+	return TokenListing{}, nil
+}
+
+func (self *RamMetricStorage) GetListedTokens() ([]TokenListing, error) {
+	return []TokenListing{}, nil
+}
+
+func (self *RamMetricStorage) RemoveTokenListing() error {
+	return nil
+}
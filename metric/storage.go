@@ -12,6 +12,10 @@ type MetricStorage interface {
 	StoreSetrateControl(status bool) error
 	StorePendingPWIEquation(data string) error
 	StorePWIEquation(data string) error
+	StorePendingTradeSwitch(data TradeSwitch) error
+	StorePendingStepFunctions(data map[string]common.TokenStepFunctions) error
+	StorePendingTokenListing(data common.TokenListing) error
+	UpdateTokenListing(listing TokenListing) error
 
 	GetMetric(tokens []common.Token, fromTime, toTime uint64) (map[string]MetricList, error)
 	GetTokenTargetQty() (TokenTargetQty, error)
@@ -20,7 +24,14 @@ type MetricStorage interface {
 	GetSetrateControl() (SetrateControl, error)
 	GetPendingPWIEquation() (PWIEquation, error)
 	GetPWIEquation() (PWIEquation, error)
+	GetPendingTradeSwitch() (TradeSwitch, error)
+	GetPendingStepFunctions() (PendingStepFunctions, error)
+	GetTokenListing() (TokenListing, error)
+	GetListedTokens() ([]TokenListing, error)
 
 	RemovePendingTargetQty() error
 	RemovePendingPWIEquation() error
+	RemovePendingTradeSwitch() error
+	RemovePendingStepFunctions() error
+	RemoveTokenListing() error
 }
@@ -1,5 +1,17 @@
 package metric
 
+import (
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+const (
+	// ANALYTICS_SOURCE tags metrics posted by the analytics service, it is
+	// also the source of metrics stored before sources were recorded.
+	ANALYTICS_SOURCE string = "analytics"
+	// CORE_SOURCE tags metrics computed by core from stored order books.
+	CORE_SOURCE string = "core"
+)
+
 type TokenMetric struct {
 	AfpMid float64
 	Spread float64
@@ -7,16 +19,27 @@ type TokenMetric struct {
 
 type MetricEntry struct {
 	Timestamp uint64
+	Source    string
 	// data contain all token metric for all tokens
 	Data map[string]TokenMetric
 }
 
 type TokenMetricResponse struct {
 	Timestamp uint64
+	Source    string
 	AfpMid    float64
 	Spread    float64
 }
 
+// GetSource returns source of the entry, entries without source were
+// posted by the analytics service.
+func (self MetricEntry) GetSource() string {
+	if self.Source == "" {
+		return ANALYTICS_SOURCE
+	}
+	return self.Source
+}
+
 // Metric list for one token
 type MetricList []TokenMetricResponse
 
@@ -39,6 +62,31 @@ type PWIEquation struct {
 	Data string `json:"data"`
 }
 
+// TradeSwitch is a request to disable or enable trading of the reserve,
+// or of Token when it is not empty, which waits for confirmation.
+type TradeSwitch struct {
+	ID     uint64 `json:"id"`
+	Token  string `json:"token"`
+	Enable bool   `json:"enable"`
+}
+
+// PendingStepFunctions are step functions of tokens proposed to be set,
+// they are only set on chain when confirmed.
+type PendingStepFunctions struct {
+	ID   uint64                               `json:"id"`
+	Data map[string]common.TokenStepFunctions `json:"data"`
+}
+
+// TokenListing is a token proposed to be listed, with the list_token
+// activities and the error of the listing once it is confirmed.
+type TokenListing struct {
+	ID         uint64              `json:"id"`
+	Data       common.TokenListing `json:"data"`
+	Status     string              `json:"status"`
+	Activities []common.ActivityID `json:"activities"`
+	Error      string              `json:"error"`
+}
+
 type RebalanceControl struct {
 	Status bool `json:status`
 }
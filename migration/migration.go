@@ -0,0 +1,139 @@
+package migration
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	METADATA_BUCKET    string = "metadata"
+	SCHEMA_VERSION_KEY string = "schema_version"
+)
+
+// Migration changes the schema of a bolt database to Version.
+// Migrate must be idempotent because it also runs on fresh databases.
+type Migration struct {
+	Version     uint64
+	Description string
+	Migrate     func(tx *bolt.Tx) error
+}
+
+// SchemaVersion returns schema version of the database, databases
+// created before versioning was introduced are at version 0.
+func SchemaVersion(tx *bolt.Tx) uint64 {
+	b := tx.Bucket([]byte(METADATA_BUCKET))
+	if b == nil {
+		return 0
+	}
+	v := b.Get([]byte(SCHEMA_VERSION_KEY))
+	if len(v) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+func setSchemaVersion(tx *bolt.Tx, version uint64) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(METADATA_BUCKET))
+	if err != nil {
+		return err
+	}
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, version)
+	return b.Put([]byte(SCHEMA_VERSION_KEY), v)
+}
+
+// Latest returns the schema version the binary expects.
+func Latest(migrations []Migration) uint64 {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func validate(migrations []Migration) error {
+	var prev uint64
+	for _, m := range migrations {
+		if m.Version <= prev {
+			return errors.New(fmt.Sprintf("Migration versions must be increasing, got %d after %d", m.Version, prev))
+		}
+		prev = m.Version
+	}
+	return nil
+}
+
+// Check returns an error if the database schema is newer than the
+// binary, such a database must not be touched.
+func Check(tx *bolt.Tx, migrations []Migration) error {
+	current := SchemaVersion(tx)
+	if latest := Latest(migrations); current > latest {
+		return errors.New(fmt.Sprintf(
+			"Database schema version %d is newer than version %d supported by this binary", current, latest))
+	}
+	return nil
+}
+
+// Pending returns migrations which are not applied to the database yet.
+func Pending(tx *bolt.Tx, migrations []Migration) ([]Migration, error) {
+	if err := validate(migrations); err != nil {
+		return nil, err
+	}
+	if err := Check(tx, migrations); err != nil {
+		return nil, err
+	}
+	current := SchemaVersion(tx)
+	result := []Migration{}
+	for _, m := range migrations {
+		if m.Version > current {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+// Run applies pending migrations in order. Each migration and its schema
+// version bump are committed in one transaction so a failed migration
+// leaves the database at the previous version.
+func Run(db *bolt.DB, migrations []Migration) (uint64, error) {
+	var pending []Migration
+	var err error
+	db.View(func(tx *bolt.Tx) error {
+		pending, err = Pending(tx, migrations)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range pending {
+		log.Printf("Migrating %s to schema version %d: %s", db.Path(), m.Version, m.Description)
+		err = db.Update(func(tx *bolt.Tx) error {
+			if err := m.Migrate(tx); err != nil {
+				return err
+			}
+			return setSchemaVersion(tx, m.Version)
+		})
+		if err != nil {
+			return 0, errors.New(fmt.Sprintf("Migration to schema version %d failed: %s", m.Version, err))
+		}
+	}
+	var version uint64
+	db.View(func(tx *bolt.Tx) error {
+		version = SchemaVersion(tx)
+		return nil
+	})
+	return version, nil
+}
+
+// CheckBuckets returns an error if any of the named buckets is missing
+// from the database.
+func CheckBuckets(tx *bolt.Tx, names []string) error {
+	for _, bucket := range names {
+		if tx.Bucket([]byte(bucket)) == nil {
+			return errors.New(fmt.Sprintf("Bucket %s is missing", bucket))
+		}
+	}
+	return nil
+}
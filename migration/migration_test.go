@@ -0,0 +1,97 @@
+package migration
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func openTestDB(t *testing.T, path string) *bolt.DB {
+	os.Remove(path)
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("Couldn't open bolt db %v", err)
+	}
+	return db
+}
+
+func createBucket(name string) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(name))
+		return err
+	}
+}
+
+func TestRunMigrations(t *testing.T) {
+	path := "test_migration.db"
+	db := openTestDB(t, path)
+	defer os.Remove(path)
+	defer db.Close()
+	migrations := []Migration{
+		{1, "first", createBucket("first")},
+		{2, "second", createBucket("second")},
+	}
+	version, err := Run(db, migrations)
+	if err != nil || version != 2 {
+		t.Fatalf("Expected schema version 2, got %d (error: %v)", version, err)
+	}
+	db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("first")) == nil || tx.Bucket([]byte("second")) == nil {
+			t.Fatalf("Expected both migrations to be applied")
+		}
+		return nil
+	})
+	// running again applies nothing
+	applied := false
+	migrations[0].Migrate = func(tx *bolt.Tx) error {
+		applied = true
+		return nil
+	}
+	if _, err = Run(db, migrations); err != nil || applied {
+		t.Fatalf("Expected applied migrations to be skipped (error: %v)", err)
+	}
+}
+
+func TestFailedMigrationKeepsVersion(t *testing.T) {
+	path := "test_migration_failed.db"
+	db := openTestDB(t, path)
+	defer os.Remove(path)
+	defer db.Close()
+	migrations := []Migration{
+		{1, "first", createBucket("first")},
+		{2, "broken", func(tx *bolt.Tx) error {
+			createBucket("broken")(tx)
+			return errors.New("broken")
+		}},
+	}
+	if _, err := Run(db, migrations); err == nil {
+		t.Fatalf("Expected failed migration to return error")
+	}
+	db.View(func(tx *bolt.Tx) error {
+		if version := SchemaVersion(tx); version != 1 {
+			t.Fatalf("Expected schema version 1 after failed migration, got %d", version)
+		}
+		if tx.Bucket([]byte("broken")) != nil {
+			t.Fatalf("Expected failed migration to be rolled back")
+		}
+		return nil
+	})
+}
+
+func TestRefuseNewerSchema(t *testing.T) {
+	path := "test_migration_newer.db"
+	db := openTestDB(t, path)
+	defer os.Remove(path)
+	defer db.Close()
+	if _, err := Run(db, []Migration{{1, "first", createBucket("first")}, {2, "second", createBucket("second")}}); err != nil {
+		t.Fatalf("Expected migrations to succeed, got error: %v", err)
+	}
+	if _, err := Run(db, []Migration{{1, "first", createBucket("first")}}); err == nil {
+		t.Fatalf("Expected database with newer schema to be refused")
+	}
+	if _, err := Run(db, []Migration{{2, "second", nil}, {1, "first", nil}}); err == nil {
+		t.Fatalf("Expected unordered migrations to be refused")
+	}
+}
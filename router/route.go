@@ -0,0 +1,231 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// RouteRequest asks to buy or sell Quantity of Base against Quote at a
+// rate not worse than LimitRate, 0 means no limit.
+type RouteRequest struct {
+	Base      string
+	Quote     string
+	Side      string
+	Quantity  float64
+	LimitRate float64
+}
+
+func (self RouteRequest) Validate() error {
+	if self.Side != "buy" && self.Side != "sell" {
+		return errors.New(fmt.Sprintf("Trade type of %s is not supported.", self.Side))
+	}
+	if self.Quantity <= 0 || self.LimitRate < 0 {
+		return errors.New("Quantity must be positive and limit rate must not be negative")
+	}
+	return nil
+}
+
+// Venue is what the router knows about one exchange of the pair: its book,
+// taker fee, precision and limits, and its available balance of the token
+// spent by the trade (base to sell, quote to buy).
+type Venue struct {
+	Exchange common.ExchangeID
+	Book     common.ExchangePrice
+	Fee      float64
+	Info     common.ExchangePrecisionLimit
+	Balance  float64
+}
+
+// Allocation is the part of a route traded on one exchange. Rate is the
+// limit rate of its order, the worst book level it takes. AvgRate is the
+// average rate of the levels it takes adjusted by the fee. TradeID and
+// Error are set once the allocation is executed.
+type Allocation struct {
+	Exchange common.ExchangeID
+	Quantity float64
+	Rate     float64
+	AvgRate  float64
+	TradeID  common.ActivityID
+	Error    string
+}
+
+// Route is the allocation of a request across exchanges minimizing the
+// fee adjusted cost of a buy or maximizing the proceeds of a sell.
+// Excluded lists exchanges which can't take part with the reason.
+type Route struct {
+	Request     RouteRequest
+	Allocations []Allocation
+	Allocated   float64
+	AvgRate     float64
+	Complete    bool
+	Excluded    map[common.ExchangeID]string
+	Executed    bool
+}
+
+type level struct {
+	exchange common.ExchangeID
+	quantity float64
+	rate     float64
+	adjusted float64
+}
+
+// roundDown floors quantity to precision decimals
+func roundDown(quantity float64, precision int) float64 {
+	if precision <= 0 {
+		return math.Floor(quantity)
+	}
+	scale := math.Pow(10, float64(precision))
+	// tolerate float errors right below a step
+	return math.Floor(quantity*scale+1e-9) / scale
+}
+
+// allocate takes best fee adjusted book levels of venues not excluded until
+// the request is filled, within balances and max amounts of venues.
+func allocate(request RouteRequest, venues map[common.ExchangeID]Venue, excluded map[common.ExchangeID]string) map[common.ExchangeID]*Allocation {
+	levels := []level{}
+	for id, venue := range venues {
+		if _, found := excluded[id]; found {
+			continue
+		}
+		entries, adjust := venue.Book.Asks, 1+venue.Fee
+		if request.Side == "sell" {
+			entries, adjust = venue.Book.Bids, 1-venue.Fee
+		}
+		for _, entry := range entries {
+			if request.LimitRate > 0 &&
+				((request.Side == "buy" && entry.Rate > request.LimitRate) ||
+					(request.Side == "sell" && entry.Rate < request.LimitRate)) {
+				continue
+			}
+			levels = append(levels, level{id, entry.Quantity, entry.Rate, entry.Rate * adjust})
+		}
+	}
+	sort.SliceStable(levels, func(i, j int) bool {
+		if levels[i].adjusted == levels[j].adjusted {
+			return levels[i].exchange < levels[j].exchange
+		}
+		if request.Side == "buy" {
+			return levels[i].adjusted < levels[j].adjusted
+		}
+		return levels[i].adjusted > levels[j].adjusted
+	})
+	result := map[common.ExchangeID]*Allocation{}
+	spent := map[common.ExchangeID]float64{}
+	costs := map[common.ExchangeID]float64{}
+	remaining := request.Quantity
+	for _, l := range levels {
+		if remaining <= 0 {
+			break
+		}
+		venue := venues[l.exchange]
+		allocation, found := result[l.exchange]
+		if !found {
+			allocation = &Allocation{Exchange: l.exchange}
+		}
+		qty := math.Min(l.quantity, remaining)
+		if max := venue.Info.AmountLimit.Max; max > 0 {
+			qty = math.Min(qty, max-allocation.Quantity)
+		}
+		// balance is in base token to sell and in quote token to buy
+		if request.Side == "sell" {
+			qty = math.Min(qty, venue.Balance-spent[l.exchange])
+		} else {
+			qty = math.Min(qty, (venue.Balance-spent[l.exchange])/l.adjusted)
+		}
+		if qty <= 0 {
+			continue
+		}
+		if request.Side == "sell" {
+			spent[l.exchange] += qty
+		} else {
+			spent[l.exchange] += qty * l.adjusted
+		}
+		costs[l.exchange] += qty * l.adjusted
+		allocation.Quantity += qty
+		allocation.Rate = l.rate
+		allocation.AvgRate = costs[l.exchange] / allocation.Quantity
+		result[l.exchange] = allocation
+		remaining -= qty
+	}
+	return result
+}
+
+// checkLimits rounds the allocation to the amount precision of its venue
+// and returns why it can't be placed, if any.
+func checkLimits(allocation *Allocation, venue Venue) string {
+	allocation.Quantity = roundDown(allocation.Quantity, venue.Info.Precision.Amount)
+	if allocation.Quantity <= 0 {
+		return "allocation is below amount precision"
+	}
+	if min := venue.Info.AmountLimit.Min; min > 0 && allocation.Quantity < min {
+		return fmt.Sprintf("allocation %f is below min amount %f", allocation.Quantity, min)
+	}
+	if min := venue.Info.MinNotional; min > 0 && allocation.Quantity*allocation.Rate < min {
+		return fmt.Sprintf("allocation notional %f is below min notional %f", allocation.Quantity*allocation.Rate, min)
+	}
+	return ""
+}
+
+// Allocate routes the request across venues. Allocations which break an
+// exchange limit are dropped and the rest is routed again without their
+// exchange, so each exchange gets either a tradable order or nothing.
+func Allocate(request RouteRequest, venues []Venue) Route {
+	result := Route{
+		Request:     request,
+		Allocations: []Allocation{},
+		Excluded:    map[common.ExchangeID]string{},
+	}
+	venueMap := map[common.ExchangeID]Venue{}
+	for _, venue := range venues {
+		switch {
+		case !venue.Book.Valid:
+			result.Excluded[venue.Exchange] = "book is not valid: " + venue.Book.Error
+		case venue.Balance <= 0:
+			result.Excluded[venue.Exchange] = "no available balance"
+		default:
+			venueMap[venue.Exchange] = venue
+		}
+	}
+	var allocations map[common.ExchangeID]*Allocation
+	for {
+		allocations = allocate(request, venueMap, result.Excluded)
+		dropped := false
+		for id, allocation := range allocations {
+			if reason := checkLimits(allocation, venueMap[id]); reason != "" {
+				result.Excluded[id] = reason
+				dropped = true
+			}
+		}
+		if !dropped {
+			break
+		}
+	}
+	cost := 0.0
+	for _, allocation := range allocations {
+		result.Allocations = append(result.Allocations, *allocation)
+		result.Allocated += allocation.Quantity
+		cost += allocation.Quantity * allocation.AvgRate
+	}
+	sort.Slice(result.Allocations, func(i, j int) bool {
+		return result.Allocations[i].Exchange < result.Allocations[j].Exchange
+	})
+	if result.Allocated > 0 {
+		result.AvgRate = cost / result.Allocated
+	}
+	// rounding to precisions can leave a negligible part
+	result.Complete = request.Quantity-result.Allocated <= request.Quantity*1e-6
+	return result
+}
+
+// balanceToken returns the token spent by a trade of side
+func (self RouteRequest) balanceToken() string {
+	if self.Side == "sell" {
+		return strings.ToUpper(self.Base)
+	}
+	return strings.ToUpper(self.Quote)
+}
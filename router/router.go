@@ -0,0 +1,138 @@
+package router
+
+import (
+	"log"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// Storage is the data storage the router reads books and balances from.
+type Storage interface {
+	CurrentPriceVersion(timepoint uint64) (common.Version, error)
+	GetOnePrice(common.TokenPairID, common.Version) (common.OnePrice, error)
+	CurrentAuthDataVersion(timepoint uint64) (common.Version, error)
+	GetAuthData(common.Version) (common.AuthDataSnapshot, error)
+}
+
+// Core places the orders of an executed route.
+type Core interface {
+	Trade(
+		exchange common.Exchange,
+		tradeType string,
+		base common.Token,
+		quote common.Token,
+		rate float64,
+		amount float64,
+		params common.OrderParams,
+		timestamp uint64) (id common.ActivityID, done float64, remaining float64, finished bool, err error)
+}
+
+type Router struct {
+	storage   Storage
+	core      Core
+	exchanges []common.Exchange
+}
+
+func NewRouter(storage Storage, core Core, exchanges []common.Exchange) *Router {
+	return &Router{
+		storage:   storage,
+		core:      core,
+		exchanges: exchanges,
+	}
+}
+
+// venues returns what the router knows about each exchange at timepoint:
+// the latest book of the pair, taker fee, precision and limits of the pair
+// and available balances of the latest auth data.
+func (self *Router) venues(request RouteRequest, timepoint uint64) ([]Venue, error) {
+	result := []Venue{}
+	pair, err := common.NewTokenPair(request.Base, request.Quote)
+	if err != nil {
+		return result, err
+	}
+	version, err := self.storage.CurrentPriceVersion(timepoint)
+	if err != nil {
+		return result, err
+	}
+	price, err := self.storage.GetOnePrice(pair.PairID(), version)
+	if err != nil {
+		return result, err
+	}
+	version, err = self.storage.CurrentAuthDataVersion(timepoint)
+	if err != nil {
+		return result, err
+	}
+	authData, err := self.storage.GetAuthData(version)
+	if err != nil {
+		return result, err
+	}
+	for _, exchange := range self.exchanges {
+		book, found := price[exchange.ID()]
+		if !found {
+			continue
+		}
+		venue := Venue{
+			Exchange: exchange.ID(),
+			Book:     book,
+			Fee:      exchange.GetFee().Trading["taker"],
+		}
+		if info, err := exchange.GetExchangeInfo(pair.PairID()); err == nil {
+			venue.Info = info
+		} else {
+			venue.Book.Valid = false
+			venue.Book.Error = err.Error()
+		}
+		if balance, found := authData.ExchangeBalances[exchange.ID()]; found && balance.Valid {
+			venue.Balance = balance.AvailableBalance[request.balanceToken()]
+		}
+		result = append(result, venue)
+	}
+	return result, nil
+}
+
+// Route returns the allocation of the request across exchanges at
+// timepoint without trading.
+func (self *Router) Route(request RouteRequest, timepoint uint64) (Route, error) {
+	if err := request.Validate(); err != nil {
+		return Route{}, err
+	}
+	venues, err := self.venues(request, timepoint)
+	if err != nil {
+		return Route{}, err
+	}
+	return Allocate(request, venues), nil
+}
+
+// Execute routes the request at timepoint and places a limit order of each
+// allocation. Orders which fail have Error of their allocation set, other
+// orders are not affected.
+func (self *Router) Execute(request RouteRequest, timepoint uint64) (Route, error) {
+	route, err := self.Route(request, timepoint)
+	if err != nil {
+		return route, err
+	}
+	base, err := common.GetPairToken(request.Base)
+	if err != nil {
+		return route, err
+	}
+	quote, err := common.GetPairToken(request.Quote)
+	if err != nil {
+		return route, err
+	}
+	exchanges := map[common.ExchangeID]common.Exchange{}
+	for _, exchange := range self.exchanges {
+		exchanges[exchange.ID()] = exchange
+	}
+	for i, allocation := range route.Allocations {
+		id, _, _, _, err := self.core.Trade(
+			exchanges[allocation.Exchange], request.Side, base, quote,
+			allocation.Rate, allocation.Quantity, common.OrderParams{}, common.GetTimepoint())
+		route.Allocations[i].TradeID = id
+		if err != nil {
+			route.Allocations[i].Error = err.Error()
+		}
+		log.Printf("Router: %s %f %s on %s at %f: %s", request.Side, allocation.Quantity, base.ID, allocation.Exchange, allocation.Rate, route.Allocations[i].Error)
+	}
+	route.Executed = true
+	return route, nil
+}
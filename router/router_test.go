@@ -0,0 +1,77 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+func book(entries ...common.PriceEntry) common.ExchangePrice {
+	return common.ExchangePrice{Valid: true, Bids: entries, Asks: entries}
+}
+
+func TestAllocateSellAcrossExchanges(t *testing.T) {
+	venues := []Venue{
+		{
+			Exchange: "binance",
+			Book:     book(common.PriceEntry{Quantity: 100, Rate: 0.0030}, common.PriceEntry{Quantity: 100, Rate: 0.0020}),
+			Fee:      0.001,
+			Balance:  1000,
+		},
+		{
+			// best raw rate but the fee makes it worse than binance
+			Exchange: "huobi",
+			Book:     book(common.PriceEntry{Quantity: 50, Rate: 0.00301}),
+			Fee:      0.01,
+			Balance:  30,
+		},
+		{
+			Exchange: "bittrex",
+			Book:     book(common.PriceEntry{Quantity: 100, Rate: 0.0028}),
+			Fee:      0.0025,
+			Info:     common.ExchangePrecisionLimit{MinNotional: 1},
+			Balance:  1000,
+		},
+		{Exchange: "liqui", Book: common.ExchangePrice{Error: "timeout"}, Balance: 1000},
+	}
+	route := Allocate(RouteRequest{Base: "KNC", Quote: "ETH", Side: "sell", Quantity: 150, LimitRate: 0.0025}, venues)
+	// binance takes its first level, huobi is limited by balance, bittrex
+	// gets 20 under its min notional so the rest is routed without it and
+	// the binance level under the limit rate is not taken
+	if len(route.Allocations) != 2 ||
+		route.Allocations[0].Exchange != "binance" || route.Allocations[0].Quantity != 100 ||
+		route.Allocations[1].Exchange != "huobi" || route.Allocations[1].Quantity != 30 {
+		t.Fatalf("Unexpected allocations %+v", route.Allocations)
+	}
+	if route.Complete || route.Allocated != 130 || route.Excluded["bittrex"] == "" || route.Excluded["liqui"] == "" {
+		t.Fatalf("Unexpected route %+v", route)
+	}
+}
+
+func TestAllocateBuyWithinQuoteBalanceAndPrecision(t *testing.T) {
+	venues := []Venue{
+		{
+			Exchange: "binance",
+			Book:     book(common.PriceEntry{Quantity: 10, Rate: 0.01}, common.PriceEntry{Quantity: 10, Rate: 0.02}),
+			Info:     common.ExchangePrecisionLimit{Precision: common.TokenPairPrecision{Amount: 0}},
+			Balance:  0.15,
+		},
+		{
+			Exchange: "huobi",
+			Book:     book(common.PriceEntry{Quantity: 20, Rate: 0.015}),
+			Info:     common.ExchangePrecisionLimit{Precision: common.TokenPairPrecision{Amount: 2}},
+			Balance:  1,
+		},
+	}
+	route := Allocate(RouteRequest{Base: "KNC", Quote: "ETH", Side: "buy", Quantity: 20}, venues)
+	// binance can pay for 10 at 0.01 and 2.5 more at 0.02 floored to 2,
+	// huobi is cheaper than the second binance level
+	if len(route.Allocations) != 2 ||
+		route.Allocations[0].Quantity != 10 || route.Allocations[0].Rate != 0.01 ||
+		route.Allocations[1].Quantity != 10 || route.Allocations[1].Rate != 0.015 {
+		t.Fatalf("Unexpected allocations %+v", route.Allocations)
+	}
+	if !route.Complete || route.AvgRate != 0.0125 {
+		t.Fatalf("Unexpected route %+v", route)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"log"
 	"os"
@@ -30,6 +31,8 @@ type FileSigner struct {
 	Passphrase      string `json:"passphrase"`
 	KeystoreD       string `json:"keystore_deposit_path"`
 	PassphraseD     string `json:"passphrase_deposit"`
+	KeystoreA       string `json:"keystore_alerter_path"`
+	PassphraseA     string `json:"passphrase_alerter"`
 	KNSecret        string `json:"kn_secret"`
 	KNReadOnly      string `json:"kn_readonly"`
 	KNConfiguration string `json:"kn_configuration"`
@@ -147,3 +150,22 @@ func NewFileSigner(file string) (*FileSigner, *FileSigner) {
 	depositSigner.opts = authD
 	return &signer, &depositSigner
 }
+
+// NewAlerterSigner returns a signer of the alerter keystore configured in
+// signer, which disables and enables trading on chain. The keystore is
+// optional, an error is returned when it is not configured.
+func NewAlerterSigner(signer FileSigner) (*FileSigner, error) {
+	if signer.KeystoreA == "" {
+		return nil, errors.New("Alerter keystore is not configured")
+	}
+	keyio, err := os.Open(signer.KeystoreA)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := bind.NewTransactor(keyio, signer.PassphraseA)
+	if err != nil {
+		return nil, err
+	}
+	signer.opts = auth
+	return &signer, nil
+}
@@ -150,7 +150,7 @@ func (self *Fetcher) aggregateTradeLog(trade common.TradeLog) (err error) {
 	walletFeeKey := strings.Join([]string{reserveAddr, walletAddr}, "_")
 
 	var srcAmount, destAmount, burnFee, walletFee float64
-	for _, token := range common.SupportedTokens {
+	for _, token := range common.GetSupportedTokens() {
 		if strings.ToLower(token.Address) == srcAddr {
 			srcAmount = common.BigToFloat(trade.SrcAmount, token.Decimal)
 		}
@@ -160,7 +160,7 @@ func (self *Fetcher) aggregateTradeLog(trade common.TradeLog) (err error) {
 		}
 	}
 
-	eth := common.SupportedTokens["ETH"]
+	eth := common.GetSupportedTokens()["ETH"]
 	if trade.BurnFee != nil {
 		burnFee = common.BigToFloat(trade.BurnFee, eth.Decimal)
 	}
@@ -3,6 +3,7 @@ package stat
 import (
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"strings"
 	"time"
@@ -107,6 +108,10 @@ func (self ReserveStats) GetPendingAddresses() ([]string, error) {
 	return self.storage.GetPendingAddresses()
 }
 
+func (self ReserveStats) Backup(w io.Writer) (int64, error) {
+	return self.storage.Backup(w)
+}
+
 func (self ReserveStats) Run() error {
 	return self.fetcher.Run()
 }
@@ -1,6 +1,8 @@
 package stat
 
 import (
+	"io"
+
 	"github.com/KyberNetwork/reserve-data/common"
 )
 
@@ -28,4 +30,6 @@ type Storage interface {
 	UpdateLogBlock(block uint64, timepoint uint64) error
 	StoreTradeLog(stat common.TradeLog, timepoint uint64) error
 	SetTradeStats(metric, freq string, t uint64, tradeStats common.TradeStats) error
+
+	Backup(w io.Writer) (int64, error)
 }
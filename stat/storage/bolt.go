@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/migration"
 	"github.com/boltdb/bolt"
 )
 
@@ -36,6 +37,15 @@ const (
 	PENDING_ADDRESSES string = "pending_addresses"
 )
 
+// buckets is the top level bucket layout of the latest schema version,
+// trade stats bucket has a nested bucket for each frequency of each
+// metric. Buckets are created by Migrations.
+var (
+	buckets               = []string{LOG_BUCKET, ADDRESS_ID, ID_ADDRESSES, ADDRESS_CATEGORY, TRADE_STATS_BUCKET, PENDING_ADDRESSES}
+	tradeStatsMetrics     = []string{ASSETS_VOLUME_BUCKET, BURN_FEE_BUCKET, WALLET_FEE_BUCKET, USER_VOLUME_BUCKET}
+	tradeStatsFrequencies = []string{MINUTE_BUCKET, HOUR_BUCKET, DAY_BUCKET}
+)
+
 type BoltStorage struct {
 	mu    sync.RWMutex
 	db    *bolt.DB
@@ -52,28 +62,10 @@ func NewBoltStorage(path string) (*BoltStorage, error) {
 		return nil, err
 	}
 	// init buckets
-	db.Update(func(tx *bolt.Tx) error {
-		tx.CreateBucket([]byte(LOG_BUCKET))
-		tx.CreateBucket([]byte(ADDRESS_ID))
-		tx.CreateBucket([]byte(ID_ADDRESSES))
-		tx.CreateBucket([]byte(ADDRESS_CATEGORY))
-		tx.CreateBucket([]byte(TRADE_STATS_BUCKET))
-		tx.CreateBucket([]byte(PENDING_ADDRESSES))
-
-		tradeStatsBk := tx.Bucket([]byte(TRADE_STATS_BUCKET))
-		metrics := []string{ASSETS_VOLUME_BUCKET, BURN_FEE_BUCKET, WALLET_FEE_BUCKET, USER_VOLUME_BUCKET}
-		frequencies := []string{MINUTE_BUCKET, HOUR_BUCKET, DAY_BUCKET}
-
-		for _, metric := range metrics {
-			tradeStatsBk.CreateBucket([]byte(metric))
-			metricBk := tradeStatsBk.Bucket([]byte(metric))
-			for _, freq := range frequencies {
-				metricBk.CreateBucket([]byte(freq))
-			}
-		}
-
-		return nil
-	})
+	if _, err = migration.Run(db, Migrations); err != nil {
+		db.Close()
+		return nil, err
+	}
 	storage := &BoltStorage{sync.RWMutex{}, db, 0, 0}
 	storage.db.View(func(tx *bolt.Tx) error {
 		block, index, err := storage.LoadLastLogIndex(tx)
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"github.com/KyberNetwork/reserve-data/migration"
+	"github.com/boltdb/bolt"
+)
+
+// initialBuckets are the buckets of databases created before schema
+// versioning, every schema version has them.
+var initialBuckets = []string{LOG_BUCKET, ADDRESS_ID, ID_ADDRESSES, ADDRESS_CATEGORY, TRADE_STATS_BUCKET, PENDING_ADDRESSES}
+
+// Migrations are the schema changes of the storage in order. They are
+// applied when the storage is opened or by db migrate command. Append
+// new migrations at the end and never change a released one.
+var Migrations = []migration.Migration{
+	{
+		Version:     1,
+		Description: "create initial buckets",
+		Migrate: func(tx *bolt.Tx) error {
+			for _, bucket := range initialBuckets {
+				if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+					return err
+				}
+			}
+			tradeStatsBk := tx.Bucket([]byte(TRADE_STATS_BUCKET))
+			for _, metric := range []string{ASSETS_VOLUME_BUCKET, BURN_FEE_BUCKET, WALLET_FEE_BUCKET, USER_VOLUME_BUCKET} {
+				metricBk, err := tradeStatsBk.CreateBucketIfNotExists([]byte(metric))
+				if err != nil {
+					return err
+				}
+				for _, freq := range []string{MINUTE_BUCKET, HOUR_BUCKET, DAY_BUCKET} {
+					if _, err := metricBk.CreateBucketIfNotExists([]byte(freq)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+}